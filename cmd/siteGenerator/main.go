@@ -6,17 +6,18 @@ import (
 	"sitemap-generator/cmd/siteGenerator/options"
 	"sitemap-generator/pkg/crawlers"
 	crawlersModels "sitemap-generator/pkg/crawlers/models"
-	"sitemap-generator/pkg/parsers"
+	"sitemap-generator/pkg/metrics"
 	"sitemap-generator/pkg/readers"
-	"sitemap-generator/pkg/workerPools"
-	"sitemap-generator/pkg/writers"
-	writersModels "sitemap-generator/pkg/writers/models"
 	"sitemap-generator/services"
 	"sitemap-generator/utils"
+	"sync"
 )
 
 const cmdName = "siteGenerator"
 
+// exitCodeBrokenLinks is returned when there are broken links to the site (if asked by flag)
+const exitCodeBrokenLinks = 2
+
 // Version is set during build via --ldflags parameter
 var Version = "untagged build"
 
@@ -37,46 +38,133 @@ func main() {
 		os.Exit(0)
 	}
 
-	// check and open output file
+	limiter := hostLimiter(opts)
+	m := serveMetrics(logger, opts)
+	if opts.SitesFile != "" {
+		crawlSites(logger, opts, limiter, m)
+		return
+	}
+
+	// check and open output files
 	if opts.StartUrl == "" {
 		logger.Fatal("Start URL missed. Should be a command argument: siteGenerator <start-url>")
 	}
-	file, err := os.OpenFile(opts.OutputFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	sc := newSiteCrawl(logger, opts, limiter, m)
+	defer sc.close()
+
+	progress := startProgress(logger, opts, sc.crawler.Progress)
+	summary := sc.run(true)
+	progress.Stop()
+	if summary.Err != nil {
+		// the exit skips the deferred close, so the report files are closed first
+		sc.close()
+		logger.Fatal(summary.Err.Error())
+	}
+	if opts.FailOnBrokenLinks && summary.InternalBrokenLinks {
+		logger.Error(fmt.Sprintf("Found %d broken links", summary.BrokenLinks))
+		sc.close()
+		os.Exit(exitCodeBrokenLinks)
+	}
+}
+
+// crawlSites crawls all the sites of the sites file at once and prints the summary of them;
+// the sites share the limits of concurrent requests, but their crawls are independent otherwise
+func crawlSites(logger services.Logger, opts options.Options, limiter readers.HostLimiter, m *metrics.Metrics) {
+	if opts.StartUrl != "" {
+		logger.Fatal("Start URL can not be used with the sites file, start URLs of the sites are in the file")
+	}
+	sites, err := options.Sites(opts)
 	if err != nil {
-		logger.Fatal("Can not open output file", err.Error())
+		logger.Fatal("Can not read sites file", err.Error())
 	}
 
-	// create services
-	wPool := workerPools.NewWorkerPool(logger, opts.ParallelRoutines)
-	reader := readers.NewReader(readers.ReaderOptions{
-		Timeout:      opts.Timeout,
-		MaxRetries:   opts.MaxRetries,
-		MaxRedirects: opts.MaxRedirects,
-	})
-	parser := parsers.NewParser()
-	crawler := crawlers.NewCrawler(crawlers.CrawlerOptions{
-		MaxDepth:   opts.MaxDepth,
-		Logger:     logger,
-		WorkerPool: wPool,
-		Reader:     reader,
-		Parser:     parser,
+	// all the sites are set up before the crawl, so invalid options of any site are reported at once
+	crawls := make([]*siteCrawl, len(sites))
+	for i, site := range sites {
+		siteLogger, err := services.NewLogger(os.Stderr, fmt.Sprintf("%s[%s]", cmdName, site.StartUrl), site.LogLevel)
+		if err != nil {
+			logger.Fatal("Can not initialize logger of the site", site.StartUrl, err.Error())
+		}
+		options.Validate(siteLogger, site)
+		siteLogger.Debug("Site options", utils.InJSON(site))
+		crawls[i] = newSiteCrawl(siteLogger, site, limiter, m)
+	}
+	logger.Info(fmt.Sprintf("Crawling %d sites", len(crawls)))
+
+	progress := startProgress(logger, opts, func() crawlersModels.Progress {
+		total := crawlersModels.Progress{}
+		for _, sc := range crawls {
+			total = total.Add(sc.crawler.Progress())
+		}
+		return total
 	})
 
-	// traverse the start URL recursively
-	var urls []*crawlersModels.Url
-	if urls, err = crawler.Traverse(opts.StartUrl); err != nil {
-		logger.Fatal("Error while scanning", err.Error())
+	summaries := make([]siteSummary, len(crawls))
+	var wg sync.WaitGroup
+	for i, sc := range crawls {
+		wg.Add(1)
+		go func(i int, sc *siteCrawl) {
+			defer wg.Done()
+			defer sc.close()
+
+			summaries[i] = sc.run(false)
+			if summaries[i].Err != nil {
+				sc.logger.Error("Crawl of the site is failed", summaries[i].Err.Error())
+			}
+		}(i, sc)
 	}
+	wg.Wait()
+	progress.Stop()
 
-	// build sitemap XML and write it to the output file
-	sitemap := writersModels.Sitemap{}
-	sitemap.Urls = make([]writersModels.SiteUrl, len(urls))
-	for i, u := range urls {
-		sitemap.Urls[i] = writersModels.BuildSitemapUrl(u.Location, u.LastModified)
+	if err = writeSummary(os.Stderr, summaries); err != nil {
+		logger.Error("Can not print summary", err.Error())
 	}
 
-	sw := writers.NewSitemapWriter(file)
-	if err = sw.Write(sitemap); err != nil {
-		logger.Fatal("Error while write to sitemap", err.Error())
+	failed, broken := 0, 0
+	for i, summary := range summaries {
+		if summary.Err != nil {
+			failed++
+		} else if crawls[i].opts.FailOnBrokenLinks && summary.InternalBrokenLinks {
+			broken++
+		}
+	}
+	if failed > 0 {
+		logger.Fatal(fmt.Sprintf("Crawl of %d of %d sites is failed", failed, len(summaries)))
+	}
+	if broken > 0 {
+		logger.Error(fmt.Sprintf("Found broken links on %d sites", broken))
+		os.Exit(exitCodeBrokenLinks)
+	}
+}
+
+// startProgress reports the progress of the crawl, the line is redrawn if stderr is a terminal, it's logged otherwise
+func startProgress(logger services.Logger, opts options.Options, source func() crawlersModels.Progress) crawlers.ProgressReporter {
+	if opts.ProgressInterval == 0 {
+		return nopProgressReporter{}
+	}
+	reporterOpts := crawlers.ProgressReporterOptions{
+		Interval: opts.ProgressInterval,
+		Logger:   logger,
+	}
+	if isTerminal(os.Stderr) {
+		reporterOpts.Terminal = os.Stderr
+	}
+	reporter := crawlers.NewProgressReporter(reporterOpts)
+	reporter.Start(source)
+	return reporter
+}
+
+// nopProgressReporter is used when the progress is not reported
+type nopProgressReporter struct{}
+
+func (nopProgressReporter) Start(func() crawlersModels.Progress) {}
+func (nopProgressReporter) Stop()                                {}
+
+// isTerminal tells if the file is a terminal (character device), not a file or a pipe
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
 	}
+	return info.Mode()&os.ModeCharDevice != 0
 }
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"sitemap-generator/cmd/siteGenerator/options"
+	"sitemap-generator/pkg/metrics"
+	"sitemap-generator/services"
+)
+
+// serveMetrics serves the metrics of the crawl and the pprof profiles at the metrics address until the exit;
+// the metrics are nil if the address is not set, so the components count them only for themselves
+func serveMetrics(logger services.Logger, opts options.Options) *metrics.Metrics {
+	if opts.MetricsAddr == "" {
+		return nil
+	}
+	// the address is listened before the crawl, so it fails at once if it's busy
+	listener, err := net.Listen("tcp", opts.MetricsAddr)
+	if err != nil {
+		logger.Fatal("Can not listen metrics address", err.Error())
+	}
+
+	registry := metrics.NewRegistry()
+	m := metrics.NewMetrics(registry)
+	go func() {
+		if err := http.Serve(listener, metrics.NewServeMux(registry)); err != nil {
+			logger.Error("Metrics server is stopped", err.Error())
+		}
+	}()
+	logger.Info("Serving metrics at http://" + listener.Addr().String() + "/metrics")
+	return m
+}
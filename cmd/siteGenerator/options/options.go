@@ -2,7 +2,16 @@ package options
 
 import (
 	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sitemap-generator/pkg/crawlers"
+	"sitemap-generator/pkg/readers"
+	"sitemap-generator/pkg/writers"
 	"sitemap-generator/services"
+	"strings"
 	"time"
 )
 
@@ -27,40 +36,550 @@ const (
 
 	outputFile        = "output-file"
 	outputFileDefault = "sitemap.xml"
+
+	brokenLinksFile        = "broken-links-file"
+	brokenLinksFileDefault = ""
+
+	brokenLinksFormat        = "broken-links-format"
+	brokenLinksFormatDefault = writers.ReportFormatJson
+
+	failOnBrokenLinks        = "fail-on-broken-links"
+	failOnBrokenLinksDefault = false
+
+	includeStatuses        = "include-statuses"
+	includeStatusesDefault = "2xx"
+
+	soft404Title        = "soft404-title"
+	soft404TitleDefault = ""
+
+	soft404Body        = "soft404-body"
+	soft404BodyDefault = ""
+
+	checkFailureTTL        = "check-failure-ttl"
+	checkFailureTTLDefault = time.Minute
+
+	checkCacheSize        = "check-cache-size"
+	checkCacheSizeDefault = readers.DefaultCachedChecks
+
+	fetchMode        = "fetch-mode"
+	fetchModeDefault = string(crawlers.FetchModeHead)
+
+	sniffContent        = "sniff-content"
+	sniffContentDefault = true
+
+	ignoreContentType        = "ignore-content-type"
+	ignoreContentTypeDefault = false
+
+	includeContent        = "include-content"
+	includeContentDefault = ""
+
+	maxBodySize        = "max-body-size"
+	maxBodySizeDefault = 10 << 20
+
+	bodyTimeout        = "body-timeout"
+	bodyTimeoutDefault = 0
+
+	acceptEncoding        = "accept-encoding"
+	acceptEncodingDefault = "gzip,deflate,br"
+
+	userAgent        = "user-agent"
+	userAgentDefault = readers.DefaultUserAgent
+
+	header      = "header"
+	basicAuth   = "basic-auth"
+	bearerToken = "bearer-token"
+
+	cookiesFile        = "cookies-file"
+	cookiesFileDefault = ""
+
+	proxy        = "proxy"
+	proxyDefault = ""
+
+	caFile        = "ca-file"
+	caFileDefault = ""
+
+	certFile        = "cert-file"
+	certFileDefault = ""
+
+	keyFile        = "key-file"
+	keyFileDefault = ""
+
+	insecure        = "insecure"
+	insecureDefault = false
+
+	loginUrl        = "login-url"
+	loginUrlDefault = ""
+
+	loginAction        = "login-action"
+	loginActionDefault = ""
+
+	loginField = "login-field"
+
+	loginCsrfField        = "login-csrf-field"
+	loginCsrfFieldDefault = ""
+
+	loginRequest        = "login-request"
+	loginRequestDefault = ""
+
+	sessionExpired        = "session-expired"
+	sessionExpiredDefault = ""
+
+	recordHar        = "record-har"
+	recordHarDefault = ""
+
+	replayHar        = "replay-har"
+	replayHarDefault = ""
+
+	rootDir        = "root-dir"
+	rootDirDefault = ""
+
+	crawlOrder        = "crawl-order"
+	crawlOrderDefault = string(crawlers.FrontierOrderBreadthFirst)
+
+	priorityPattern = "priority-pattern"
+
+	maxUrls        = "max-urls"
+	maxUrlsDefault = 0
+
+	maxPages        = "max-pages"
+	maxPagesDefault = 0
+
+	maxBytes        = "max-bytes"
+	maxBytesDefault = 0
+
+	maxDuration        = "max-duration"
+	maxDurationDefault = 0
+
+	maxHostUrls        = "max-host-urls"
+	maxHostUrlsDefault = 0
+
+	maxHostPages        = "max-host-pages"
+	maxHostPagesDefault = 0
+
+	maxHostBytes        = "max-host-bytes"
+	maxHostBytesDefault = 0
+
+	maxSegmentRepeats        = "max-segment-repeats"
+	maxSegmentRepeatsDefault = 3
+
+	maxUrlLength        = "max-url-length"
+	maxUrlLengthDefault = 2048
+
+	maxQueryVariants        = "max-query-variants"
+	maxQueryVariantsDefault = 100
+
+	sessionParams = "session-params"
+
+	maxNearDuplicates        = "max-near-duplicates"
+	maxNearDuplicatesDefault = 10
+
+	nearDuplicateDistance        = "near-duplicate-distance"
+	nearDuplicateDistanceDefault = 3
+
+	trapsFile        = "traps-file"
+	trapsFileDefault = ""
+
+	trapsFormat        = "traps-format"
+	trapsFormatDefault = writers.ReportFormatJson
+
+	dedup        = "dedup"
+	dedupDefault = false
+
+	dedupDistance        = "dedup-distance"
+	dedupDistanceDefault = 3
+
+	duplicatesFile        = "duplicates-file"
+	duplicatesFileDefault = ""
+
+	duplicatesFormat        = "duplicates-format"
+	duplicatesFormatDefault = writers.ReportFormatJson
+
+	sitesFile        = "sites-file"
+	sitesFileDefault = ""
+
+	maxParallel        = "max-parallel"
+	maxParallelDefault = 0
+
+	maxHostParallel        = "max-host-parallel"
+	maxHostParallelDefault = 0
+
+	statsFile        = "stats-file"
+	statsFileDefault = ""
+
+	progressInterval        = "progress-interval"
+	progressIntervalDefault = 10 * time.Second
+
+	metricsAddr        = "metrics-addr"
+	metricsAddrDefault = ""
 )
 
+// StringList is a flag which can be set several times
+type StringList []string
+
+func (sl *StringList) String() string {
+	return strings.Join(*sl, ", ")
+}
+
+func (sl *StringList) Set(v string) error {
+	*sl = append(*sl, v)
+	return nil
+}
+
 type Options struct {
-	ShowVersion      bool          `json:"showVersion"`
-	LogLevel         string        `json:"logLevel"`
-	Timeout          time.Duration `json:"timeout"`
-	MaxRetries       int           `json:"maxRetries"`
-	MaxRedirects     int           `json:"maxRedirects"`
-	ParallelRoutines int           `json:"parallelRoutines"`
-	MaxDepth         int           `json:"maxDepth"`
-	OutputFile       string        `json:"outputFile"`
-	StartUrl         string        `json:"startUrl"`
+	ShowVersion           bool          `json:"showVersion"`
+	LogLevel              string        `json:"logLevel"`
+	Timeout               time.Duration `json:"timeout"`
+	MaxRetries            int           `json:"maxRetries"`
+	MaxRedirects          int           `json:"maxRedirects"`
+	ParallelRoutines      int           `json:"parallelRoutines"`
+	MaxDepth              int           `json:"maxDepth"`
+	OutputFile            string        `json:"outputFile"`
+	BrokenLinksFile       string        `json:"brokenLinksFile"`
+	BrokenLinksFormat     string        `json:"brokenLinksFormat"`
+	FailOnBrokenLinks     bool          `json:"failOnBrokenLinks"`
+	IncludeStatuses       string        `json:"includeStatuses"`
+	Soft404Title          string        `json:"soft404Title"`
+	Soft404Body           string        `json:"soft404Body"`
+	FetchMode             string        `json:"fetchMode"`
+	CheckFailureTTL       time.Duration `json:"checkFailureTTL"`
+	CheckCacheSize        int           `json:"checkCacheSize"`
+	SniffContent          bool          `json:"sniffContent"`
+	IgnoreContentType     bool          `json:"ignoreContentType"`
+	IncludeContent        string        `json:"includeContent"`
+	MaxBodySize           int64         `json:"maxBodySize"`
+	BodyTimeout           time.Duration `json:"bodyTimeout"`
+	AcceptEncoding        string        `json:"acceptEncoding"`
+	UserAgent             string        `json:"userAgent"`
+	Headers               StringList    `json:"-"`
+	BasicAuth             StringList    `json:"-"`
+	BearerTokens          StringList    `json:"-"`
+	CookiesFile           string        `json:"cookiesFile"`
+	Proxy                 string        `json:"-"`
+	CAFile                string        `json:"caFile"`
+	CertFile              string        `json:"certFile"`
+	KeyFile               string        `json:"keyFile"`
+	Insecure              bool          `json:"insecure"`
+	LoginUrl              string        `json:"loginUrl"`
+	LoginAction           string        `json:"loginAction"`
+	LoginFields           StringList    `json:"-"`
+	LoginCsrfField        string        `json:"loginCsrfField"`
+	LoginRequest          string        `json:"loginRequest"`
+	SessionExpired        string        `json:"sessionExpired"`
+	RecordHar             string        `json:"recordHar"`
+	ReplayHar             string        `json:"replayHar"`
+	RootDir               string        `json:"rootDir"`
+	CrawlOrder            string        `json:"crawlOrder"`
+	PriorityPatterns      StringList    `json:"priorityPatterns"`
+	MaxUrls               int           `json:"maxUrls"`
+	MaxPages              int           `json:"maxPages"`
+	MaxBytes              int64         `json:"maxBytes"`
+	MaxDuration           time.Duration `json:"maxDuration"`
+	MaxHostUrls           int           `json:"maxHostUrls"`
+	MaxHostPages          int           `json:"maxHostPages"`
+	MaxHostBytes          int64         `json:"maxHostBytes"`
+	MaxSegmentRepeats     int           `json:"maxSegmentRepeats"`
+	MaxUrlLength          int           `json:"maxUrlLength"`
+	MaxQueryVariants      int           `json:"maxQueryVariants"`
+	SessionParams         string        `json:"sessionParams"`
+	MaxNearDuplicates     int           `json:"maxNearDuplicates"`
+	NearDuplicateDistance int           `json:"nearDuplicateDistance"`
+	TrapsFile             string        `json:"trapsFile"`
+	TrapsFormat           string        `json:"trapsFormat"`
+	Dedup                 bool          `json:"dedup"`
+	DedupDistance         int           `json:"dedupDistance"`
+	DuplicatesFile        string        `json:"duplicatesFile"`
+	DuplicatesFormat      string        `json:"duplicatesFormat"`
+	SitesFile             string        `json:"sitesFile"`
+	MaxParallel           int           `json:"maxParallel"`
+	MaxHostParallel       int           `json:"maxHostParallel"`
+	StatsFile             string        `json:"statsFile"`
+	ProgressInterval      time.Duration `json:"progressInterval"`
+	MetricsAddr           string        `json:"metricsAddr"`
+	StartUrl              string        `json:"startUrl"`
+
+	// flagArgs are the flags of the command line, they're applied to each site of the sites file
+	flagArgs []string
 }
 
 func ParseOptions(opts *Options) {
-	flag.BoolVar(&opts.ShowVersion, "v", false, "show version")
-	flag.StringVar(&opts.LogLevel, logLevel, logLevelDefault, "log level (error, warn, info, debug)")
-	flag.DurationVar(&opts.Timeout, timeout, timeoutDefault, "allowable timeout for each URL reading (valid duration units are 'ms', 's', 'm')")
-	flag.IntVar(&opts.MaxRetries, maxRetries, maxRetriesDefault, "max retries for each URL reading")
-	flag.IntVar(&opts.MaxRedirects, maxRedirects, maxRedirectsDefault, "max redirects when server response with redirect HTTP response")
-	flag.IntVar(&opts.ParallelRoutines, parallel, parallelDefault, "number of parallel workers to navigate through site")
-	flag.IntVar(&opts.MaxDepth, maxDepth, maxDepthDefault, "max depth of URL navigation recursion")
-	flag.StringVar(&opts.OutputFile, outputFile, outputFileDefault, "output file path")
+	defineFlags(flag.CommandLine, opts)
 	flag.Parse()
 
 	args := flag.Args()
 	if len(args) > 0 {
 		opts.StartUrl = args[0]
 	}
+	opts.flagArgs = os.Args[1 : len(os.Args)-len(args)]
+}
 
+// defineFlags binds the flags of the command line (and of the lines of the sites file) to the options
+func defineFlags(fs *flag.FlagSet, opts *Options) {
+	fs.BoolVar(&opts.ShowVersion, "v", false, "show version")
+	fs.StringVar(&opts.LogLevel, logLevel, logLevelDefault, "log level (error, warn, info, debug)")
+	fs.DurationVar(&opts.Timeout, timeout, timeoutDefault, "allowable timeout for each URL reading (valid duration units are 'ms', 's', 'm')")
+	fs.IntVar(&opts.MaxRetries, maxRetries, maxRetriesDefault, "max retries for each URL reading")
+	fs.IntVar(&opts.MaxRedirects, maxRedirects, maxRedirectsDefault, "max redirects when server response with redirect HTTP response")
+	fs.IntVar(&opts.ParallelRoutines, parallel, parallelDefault, "number of parallel workers to navigate through site")
+	fs.IntVar(&opts.MaxDepth, maxDepth, maxDepthDefault, "max depth of URL navigation recursion")
+	fs.StringVar(&opts.OutputFile, outputFile, outputFileDefault, "output file path (empty to skip the sitemap)")
+	fs.StringVar(&opts.BrokenLinksFile, brokenLinksFile, brokenLinksFileDefault, "file path of the broken links report (empty to skip the report)")
+	fs.StringVar(&opts.BrokenLinksFormat, brokenLinksFormat, brokenLinksFormatDefault, "format of the broken links report (json, csv, html)")
+	fs.BoolVar(&opts.FailOnBrokenLinks, failOnBrokenLinks, failOnBrokenLinksDefault, "exit with code 2 if there are broken links to the start URL host")
+	fs.StringVar(&opts.IncludeStatuses, includeStatuses, includeStatusesDefault, "comma separated HTTP statuses or classes of URLs to include in the sitemap (e.g. 2xx,304; 4xx and 5xx are errors)")
+	fs.StringVar(&opts.Soft404Title, soft404Title, soft404TitleDefault, "regular expression of the page title to exclude the page as a soft 404")
+	fs.StringVar(&opts.Soft404Body, soft404Body, soft404BodyDefault, "regular expression of the page content to exclude the page as a soft 404")
+	fs.StringVar(&opts.FetchMode, fetchMode, fetchModeDefault, "how URLs are requested: head (check by HEAD, read HTML by GET) or get (single GET for both)")
+	fs.DurationVar(&opts.CheckFailureTTL, checkFailureTTL, checkFailureTTLDefault, "how long failed URL check is reused for the same URL on other pages (0 to check it again each time)")
+	fs.IntVar(&opts.CheckCacheSize, checkCacheSize, checkCacheSizeDefault, "max number of URL checks kept to reuse them on other pages, the least recently used ones are dropped first; each takes about 1KB of memory")
+	fs.BoolVar(&opts.SniffContent, sniffContent, sniffContentDefault, "detect content type by its first bytes when the declared one is missing or generic")
+	fs.BoolVar(&opts.IgnoreContentType, ignoreContentType, ignoreContentTypeDefault, "don't trust declared content type and detect it by the content only")
+	fs.StringVar(&opts.IncludeContent, includeContent, includeContentDefault, "comma separated content classes of URLs to include in the sitemap (html, document, image, media, other; empty for all)")
+	fs.Int64Var(&opts.MaxBodySize, maxBodySize, maxBodySizeDefault, "max size of the page body in bytes, longer pages are truncated (0 for unlimited)")
+	fs.DurationVar(&opts.BodyTimeout, bodyTimeout, bodyTimeoutDefault, "allowable time of the page body reading, slower pages are truncated (0 to rely on the timeout only)")
+	fs.StringVar(&opts.AcceptEncoding, acceptEncoding, acceptEncodingDefault, "comma separated content encodings asked from servers (gzip, deflate, br; identity to disable compression)")
+	fs.StringVar(&opts.UserAgent, userAgent, userAgentDefault, "User-Agent header of requests")
+	fs.Var(&opts.Headers, header, "extra header of requests in \"Name: value\" form (can be set several times)")
+	fs.Var(&opts.BasicAuth, basicAuth, "basic auth credentials sent only to the host in host=username:password form (can be set several times)")
+	fs.Var(&opts.BearerTokens, bearerToken, "bearer token sent only to the host in host=token form (can be set several times)")
+	fs.StringVar(&opts.CookiesFile, cookiesFile, cookiesFileDefault, "Netscape cookies file to preload cookies from")
+	fs.StringVar(&opts.Proxy, proxy, proxyDefault, "HTTP, HTTPS or SOCKS5 proxy URL (HTTP_PROXY and HTTPS_PROXY environment variables are used if empty)")
+	fs.StringVar(&opts.CAFile, caFile, caFileDefault, "PEM bundle of CA certificates trusted in addition to the system ones")
+	fs.StringVar(&opts.CertFile, certFile, certFileDefault, "PEM client certificate")
+	fs.StringVar(&opts.KeyFile, keyFile, keyFileDefault, "PEM key of the client certificate (if it's not in the certificate file)")
+	fs.BoolVar(&opts.Insecure, insecure, insecureDefault, "don't verify server certificates")
+	fs.StringVar(&opts.LoginUrl, loginUrl, loginUrlDefault, "page with the login form to log in before the crawl")
+	fs.StringVar(&opts.LoginAction, loginAction, loginActionDefault, "URL where the login form is posted (the action of the form on the login page by default)")
+	fs.Var(&opts.LoginFields, loginField, "field of the login form in name=value form (can be set several times)")
+	fs.StringVar(&opts.LoginCsrfField, loginCsrfField, loginCsrfFieldDefault, "name of the hidden field with CSRF token which value is taken from the login page")
+	fs.StringVar(&opts.LoginRequest, loginRequest, loginRequestDefault, "file with the recorded raw HTTP request to log in (instead of the login form)")
+	fs.StringVar(&opts.SessionExpired, sessionExpired, sessionExpiredDefault, "regular expression of URL where the expired session is redirected to, to log in again")
+	fs.StringVar(&opts.RecordHar, recordHar, recordHarDefault, "HAR file to save all the requests and responses of the crawl to")
+	fs.StringVar(&opts.ReplayHar, replayHar, replayHarDefault, "HAR file to serve all the requests from instead of the network")
+	fs.StringVar(&opts.RootDir, rootDir, rootDirDefault, "local directory (path or file:// URL) served at the start URL to read the site from instead of the network")
+	fs.StringVar(&opts.CrawlOrder, crawlOrder, crawlOrderDefault, "order of reading pages: fifo, breadth-first, best-first (most linked first) or round-robin (hosts in turn)")
+	fs.Var(&opts.PriorityPatterns, priorityPattern, "weight added to the best-first score of URLs matching the pattern in regexp=weight form (can be set several times)")
+	fs.IntVar(&opts.MaxUrls, maxUrls, maxUrlsDefault, "max number of URLs collected, the crawl is stopped when it's reached (0 for unlimited)")
+	fs.IntVar(&opts.MaxPages, maxPages, maxPagesDefault, "max number of pages fetched, the crawl is stopped when it's reached (0 for unlimited)")
+	fs.Int64Var(&opts.MaxBytes, maxBytes, maxBytesDefault, "max number of bytes downloaded, the crawl is stopped when it's reached (0 for unlimited)")
+	fs.DurationVar(&opts.MaxDuration, maxDuration, maxDurationDefault, "max duration of the crawl, the crawl is stopped when it's reached (0 for unlimited)")
+	fs.IntVar(&opts.MaxHostUrls, maxHostUrls, maxHostUrlsDefault, "max number of URLs collected per host, the rest of the host is skipped (0 for unlimited)")
+	fs.IntVar(&opts.MaxHostPages, maxHostPages, maxHostPagesDefault, "max number of pages fetched per host, the rest of the host is skipped (0 for unlimited)")
+	fs.Int64Var(&opts.MaxHostBytes, maxHostBytes, maxHostBytesDefault, "max number of bytes downloaded per host, the rest of the host is skipped (0 for unlimited)")
+	fs.IntVar(&opts.MaxSegmentRepeats, maxSegmentRepeats, maxSegmentRepeatsDefault, "how many times the same path segment may occur in URL before it's skipped as a crawler trap (0 for unlimited)")
+	fs.IntVar(&opts.MaxUrlLength, maxUrlLength, maxUrlLengthDefault, "max length of URL, longer ones are skipped as crawler traps (0 for unlimited)")
+	fs.IntVar(&opts.MaxQueryVariants, maxQueryVariants, maxQueryVariantsDefault, "how many distinct query strings the same path may have, the rest are skipped as crawler traps (0 for unlimited)")
+	fs.StringVar(&opts.SessionParams, sessionParams, strings.Join(crawlers.DefaultSessionParams, ","), "comma separated names of parameters with session IDs, URLs with them are skipped as crawler traps")
+	fs.IntVar(&opts.MaxNearDuplicates, maxNearDuplicates, maxNearDuplicatesDefault, "how many near-duplicate pages the same URL shape may have, the rest are skipped as crawler traps (0 to disable)")
+	fs.IntVar(&opts.NearDuplicateDistance, nearDuplicateDistance, nearDuplicateDistanceDefault, "max number of different bits of content fingerprints (of 64) of near-duplicate pages (up to 63)")
+	fs.StringVar(&opts.TrapsFile, trapsFile, trapsFileDefault, "file path of the report of URLs skipped as crawler traps (empty to skip the report)")
+	fs.StringVar(&opts.TrapsFormat, trapsFormat, trapsFormatDefault, "format of the crawler traps report (json, csv, html)")
+	fs.BoolVar(&opts.Dedup, dedup, dedupDefault, "collect only one page of the pages with the same content (the canonical or the shortest URL), pages are written at the end of the crawl then")
+	fs.IntVar(&opts.DedupDistance, dedupDistance, dedupDistanceDefault, "max number of different bits of content fingerprints (of 64) of duplicate pages (up to 63, 0 for exact duplicates only)")
+	fs.StringVar(&opts.DuplicatesFile, duplicatesFile, duplicatesFileDefault, "file path of the report of pages excluded as duplicates (empty to skip the report)")
+	fs.StringVar(&opts.DuplicatesFormat, duplicatesFormat, duplicatesFormatDefault, "format of the duplicates report (json, csv, html)")
+	fs.StringVar(&opts.SitesFile, sitesFile, sitesFileDefault, "file with the sites to crawl at once, a site per line: its flags overriding the ones of the command line and its start URL")
+	fs.IntVar(&opts.MaxParallel, maxParallel, maxParallelDefault, "max number of requests sent at the same time by all the sites (0 for unlimited)")
+	fs.IntVar(&opts.MaxHostParallel, maxHostParallel, maxHostParallelDefault, "max number of requests sent at the same time to the same host (0 for unlimited)")
+	fs.StringVar(&opts.StatsFile, statsFile, statsFileDefault, "file path of the stats of the crawl in JSON (empty to skip the stats)")
+	fs.DurationVar(&opts.ProgressInterval, progressInterval, progressIntervalDefault, "how often the progress is logged when stderr is not a terminal, it's redrawn every second on the terminal (0 to disable)")
+	fs.StringVar(&opts.MetricsAddr, metricsAddr, metricsAddrDefault, "address to serve Prometheus metrics at /metrics and pprof profiles at /debug/pprof/ during the crawl, e.g. localhost:9090 (empty to skip)")
 }
 
 func Validate(logger services.Logger, opts Options) {
+	if opts.MaxParallel < 0 || opts.MaxHostParallel < 0 {
+		logger.Fatal("MaxParallel and MaxHostParallel should not be negative", opts)
+	}
+	if opts.ProgressInterval < 0 {
+		logger.Fatal("ProgressInterval should not be negative", opts)
+	}
 	if opts.MaxRetries <= 0 {
 		logger.Fatal("MaxRetries should be number greater than zero", opts)
 	}
+	if opts.CheckCacheSize <= 0 {
+		logger.Fatal("CheckCacheSize should be number greater than zero", opts)
+	}
+	if opts.MaxUrls < 0 || opts.MaxPages < 0 || opts.MaxBytes < 0 || opts.MaxDuration < 0 ||
+		opts.MaxHostUrls < 0 || opts.MaxHostPages < 0 || opts.MaxHostBytes < 0 {
+		logger.Fatal("Budget limits should not be negative", opts)
+	}
+	if opts.MaxSegmentRepeats < 0 || opts.MaxUrlLength < 0 || opts.MaxQueryVariants < 0 ||
+		opts.MaxNearDuplicates < 0 {
+		logger.Fatal("Crawler trap thresholds should not be negative", opts)
+	}
+	if err := crawlers.ValidateFingerprintDistance(opts.NearDuplicateDistance); err != nil {
+		logger.Fatal("NearDuplicateDistance is invalid", err.Error())
+	}
+	if opts.IgnoreContentType && !opts.SniffContent {
+		logger.Fatal("IgnoreContentType can not be used without SniffContent, the content type would be unknown", opts)
+	}
+	if opts.MaxBodySize < 0 {
+		logger.Fatal("MaxBodySize should not be negative", opts)
+	}
+	if err := readers.ValidateAcceptEncodings(AcceptEncodings(opts)); err != nil {
+		logger.Fatal("AcceptEncoding is invalid", err.Error())
+	}
+	if _, err := Headers(opts); err != nil {
+		logger.Fatal("Header is invalid", err.Error())
+	}
+	if _, err := Auth(opts); err != nil {
+		logger.Fatal("Auth is invalid", err.Error())
+	}
+	if _, err := ProxyUrl(opts); err != nil {
+		logger.Fatal("Proxy is invalid", err.Error())
+	}
+	if _, err := LoginFields(opts); err != nil {
+		logger.Fatal("LoginField is invalid", err.Error())
+	}
+	if opts.LoginRequest != "" && (opts.LoginUrl != "" || opts.LoginAction != "") {
+		logger.Fatal("LoginRequest can not be used with the login form", opts)
+	}
+	if opts.LoginCsrfField != "" && opts.LoginUrl == "" {
+		logger.Fatal("LoginCsrfField is set without LoginUrl", opts)
+	}
+	if _, err := regexp.Compile(opts.SessionExpired); err != nil {
+		logger.Fatal("SessionExpired is invalid regular expression", err.Error())
+	}
+	if opts.RootDir != "" && opts.ReplayHar != "" {
+		logger.Fatal("RootDir can not be used with ReplayHar", opts)
+	}
+	if opts.KeyFile != "" && opts.CertFile == "" {
+		logger.Fatal("KeyFile is set without CertFile", opts)
+	}
+	if err := writers.ValidateReportFormat(opts.BrokenLinksFormat); err != nil {
+		logger.Fatal("BrokenLinksFormat is invalid", err.Error())
+	}
+	if err := writers.ValidateReportFormat(opts.TrapsFormat); err != nil {
+		logger.Fatal("TrapsFormat is invalid", err.Error())
+	}
+	if err := writers.ValidateReportFormat(opts.DuplicatesFormat); err != nil {
+		logger.Fatal("DuplicatesFormat is invalid", err.Error())
+	}
+	if err := crawlers.ValidateFingerprintDistance(opts.DedupDistance); err != nil {
+		logger.Fatal("DedupDistance is invalid", err.Error())
+	}
+	if opts.DuplicatesFile != "" && !opts.Dedup {
+		logger.Fatal("DuplicatesFile is set without Dedup", opts)
+	}
+	if _, err := crawlers.ParseStatusCodes(opts.IncludeStatuses); err != nil {
+		logger.Fatal("IncludeStatuses is invalid", err.Error())
+	}
+	if _, err := crawlers.ParseContentClasses(opts.IncludeContent); err != nil {
+		logger.Fatal("IncludeContent is invalid", err.Error())
+	}
+	if _, err := regexp.Compile(opts.Soft404Title); err != nil {
+		logger.Fatal("Soft404Title is invalid regular expression", err.Error())
+	}
+	if _, err := regexp.Compile(opts.Soft404Body); err != nil {
+		logger.Fatal("Soft404Body is invalid regular expression", err.Error())
+	}
+	if _, err := crawlers.ParseFrontierOrder(opts.CrawlOrder); err != nil {
+		logger.Fatal("CrawlOrder is invalid", err.Error())
+	}
+	if _, err := PatternWeights(opts); err != nil {
+		logger.Fatal("PriorityPattern is invalid", err.Error())
+	}
+	if opts.FetchMode != string(crawlers.FetchModeHead) && opts.FetchMode != string(crawlers.FetchModeGet) {
+		logger.Fatal("FetchMode should be head or get", opts)
+	}
+	if opts.OutputFile == "" && opts.BrokenLinksFile == "" && opts.TrapsFile == "" && opts.DuplicatesFile == "" && opts.StatsFile == "" {
+		logger.Fatal("Nothing to write, OutputFile and all report files are empty", opts)
+	}
+}
+
+// AcceptEncodings splits the comma separated encodings of the options
+func AcceptEncodings(opts Options) []string {
+	encodings := make([]string, 0)
+	for _, e := range strings.Split(opts.AcceptEncoding, ",") {
+		if e = strings.ToLower(strings.TrimSpace(e)); e != "" {
+			encodings = append(encodings, e)
+		}
+	}
+	return encodings
+}
+
+// Headers parses the extra headers of the options
+func Headers(opts Options) (http.Header, error) {
+	headers := make(http.Header)
+	for _, h := range opts.Headers {
+		name, value, ok := strings.Cut(h, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("\"Name: value\" expected: %s", h)
+		}
+		headers.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	return headers, nil
+}
+
+// Auth parses basic auth credentials and bearer tokens of the options
+func Auth(opts Options) ([]readers.HostAuth, error) {
+	auth := make([]readers.HostAuth, 0)
+	for _, v := range opts.BasicAuth {
+		a, err := readers.ParseHostAuth(v, false)
+		if err != nil {
+			return nil, err
+		}
+		auth = append(auth, a)
+	}
+	for _, v := range opts.BearerTokens {
+		a, err := readers.ParseHostAuth(v, true)
+		if err != nil {
+			return nil, err
+		}
+		auth = append(auth, a)
+	}
+	return auth, nil
+}
+
+// ProxyUrl parses the proxy URL of the options, nil if it's not set
+func ProxyUrl(opts Options) (*url.URL, error) {
+	if opts.Proxy == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(opts.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+		return u, nil
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme: %s", u.Scheme)
+	}
+}
+
+// LoginFields parses the fields of the login form of the options
+func LoginFields(opts Options) (url.Values, error) {
+	fields := make(url.Values)
+	for _, f := range opts.LoginFields {
+		name, value, ok := strings.Cut(f, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("name=value expected: %s", f)
+		}
+		fields.Add(name, value)
+	}
+	return fields, nil
+}
+
+// PatternWeights parses the weights of URL patterns of the options
+func PatternWeights(opts Options) ([]crawlers.PatternWeight, error) {
+	weights := make([]crawlers.PatternWeight, 0)
+	for _, v := range opts.PriorityPatterns {
+		pw, err := crawlers.ParsePatternWeight(v)
+		if err != nil {
+			return nil, err
+		}
+		weights = append(weights, pw)
+	}
+	return weights, nil
+}
+
+// SessionParams splits the comma separated names of session parameters of the options
+func SessionParams(opts Options) []string {
+	params := make([]string, 0)
+	for _, p := range strings.Split(opts.SessionParams, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			params = append(params, p)
+		}
+	}
+	return params
 }
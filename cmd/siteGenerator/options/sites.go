@@ -0,0 +1,121 @@
+package options
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Sites reads the sites file: each line is the command line of the site, i.e. its flags and its start URL,
+// e.g. `-output-file=example.xml -max-depth=3 https://example.com/`; the flags of the whole command line
+// are applied first, so the site overrides them (or adds values of the flags which can be set several times).
+// Empty lines and lines starting with # are skipped
+func Sites(opts Options) ([]Options, error) {
+	f, err := os.Open(opts.SitesFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sites := make([]Options, 0)
+	files := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		site, err := parseSite(opts.flagArgs, line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d of %s: %s", n, opts.SitesFile, err.Error())
+		}
+
+		// sites write their own files, they must not overwrite the ones of each other
+		for _, file := range []string{site.OutputFile, site.BrokenLinksFile, site.TrapsFile, site.DuplicatesFile, site.StatsFile, site.RecordHar} {
+			if file == "" {
+				continue
+			}
+			if other, ok := files[file]; ok {
+				return nil, fmt.Errorf("line %d of %s: %s is written by %s as well", n, opts.SitesFile, file, other)
+			}
+			files[file] = site.StartUrl
+		}
+		sites = append(sites, site)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(sites) == 0 {
+		return nil, fmt.Errorf("no sites in %s", opts.SitesFile)
+	}
+	return sites, nil
+}
+
+func parseSite(flagArgs []string, line string) (Options, error) {
+	args, err := splitArgs(line)
+	if err != nil {
+		return Options{}, err
+	}
+
+	site := Options{}
+	fs := flag.NewFlagSet(line, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	defineFlags(fs, &site)
+	if err = fs.Parse(flagArgs); err != nil {
+		return Options{}, err
+	}
+	parallel, hostParallel := site.MaxParallel, site.MaxHostParallel
+	if err = fs.Parse(args); err != nil {
+		return Options{}, err
+	}
+	// the requests of all the sites are limited by the same limiter
+	if site.MaxParallel != parallel || site.MaxHostParallel != hostParallel {
+		return Options{}, fmt.Errorf("-%s and -%s apply to all the sites, they can be set on the command line only", maxParallel, maxHostParallel)
+	}
+	if fs.NArg() != 1 {
+		return Options{}, fmt.Errorf("the only start URL expected after the flags: %s", line)
+	}
+
+	site.StartUrl = fs.Arg(0)
+	site.SitesFile = ""
+	return site, nil
+}
+
+// splitArgs splits the line by spaces, except the ones in single or double quotes
+func splitArgs(line string) ([]string, error) {
+	args := make([]string, 0)
+	var arg strings.Builder
+	inArg := false
+	var quote rune
+	for _, r := range line {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			arg.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unclosed quote: %s", line)
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
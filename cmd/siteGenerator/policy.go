@@ -0,0 +1,78 @@
+package main
+
+import (
+	"regexp"
+	"sitemap-generator/cmd/siteGenerator/options"
+	"sitemap-generator/pkg/crawlers"
+	"sitemap-generator/pkg/readers"
+)
+
+// inclusionPolicy builds the policy from already validated options
+func inclusionPolicy(opts options.Options) crawlers.InclusionPolicy {
+	policyOpts := crawlers.InclusionPolicyOptions{}
+	policyOpts.StatusCodes, _ = crawlers.ParseStatusCodes(opts.IncludeStatuses)
+	policyOpts.ContentClasses, _ = crawlers.ParseContentClasses(opts.IncludeContent)
+	if opts.Soft404Title != "" {
+		policyOpts.Soft404Titles = []*regexp.Regexp{regexp.MustCompile(opts.Soft404Title)}
+	}
+	if opts.Soft404Body != "" {
+		policyOpts.Soft404Bodies = []*regexp.Regexp{regexp.MustCompile(opts.Soft404Body)}
+	}
+	return crawlers.NewInclusionPolicy(policyOpts)
+}
+
+// frontier builds the queue of the pages to read from already validated options
+func frontier(opts options.Options) crawlers.Frontier {
+	frontierOpts := crawlers.FrontierOptions{}
+	frontierOpts.Order, _ = crawlers.ParseFrontierOrder(opts.CrawlOrder)
+	frontierOpts.PatternWeights, _ = options.PatternWeights(opts)
+	return crawlers.NewFrontier(frontierOpts)
+}
+
+// budget builds the limits of the crawl from the options
+func budget(opts options.Options) crawlers.Budget {
+	return crawlers.Budget{
+		MaxUrls:     opts.MaxUrls,
+		MaxPages:    opts.MaxPages,
+		MaxBytes:    opts.MaxBytes,
+		MaxDuration: opts.MaxDuration,
+		PerHost: crawlers.HostBudget{
+			MaxUrls:  opts.MaxHostUrls,
+			MaxPages: opts.MaxHostPages,
+			MaxBytes: opts.MaxHostBytes,
+		},
+	}
+}
+
+// trapDetector builds the heuristics of crawler traps from the options
+func trapDetector(opts options.Options) crawlers.TrapDetector {
+	return crawlers.NewTrapDetector(crawlers.TrapDetectorOptions{
+		MaxSegmentRepeats:     opts.MaxSegmentRepeats,
+		MaxUrlLength:          opts.MaxUrlLength,
+		MaxQueryVariants:      opts.MaxQueryVariants,
+		SessionParams:         options.SessionParams(opts),
+		MaxNearDuplicates:     opts.MaxNearDuplicates,
+		NearDuplicateDistance: opts.NearDuplicateDistance,
+	})
+}
+
+// deduplicator builds the grouping of duplicate pages from the options, nil if it's disabled
+func deduplicator(opts options.Options) crawlers.Deduplicator {
+	if !opts.Dedup {
+		return nil
+	}
+	return crawlers.NewDeduplicator(crawlers.DeduplicatorOptions{
+		NearDuplicateDistance: opts.DedupDistance,
+	})
+}
+
+// hostLimiter builds the limit of concurrent requests of all the sites, nil if requests are unlimited
+func hostLimiter(opts options.Options) readers.HostLimiter {
+	if opts.MaxParallel == 0 && opts.MaxHostParallel == 0 {
+		return nil
+	}
+	return readers.NewHostLimiter(readers.HostLimiterOptions{
+		MaxParallel:     opts.MaxParallel,
+		MaxHostParallel: opts.MaxHostParallel,
+	})
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sitemap-generator/cmd/siteGenerator/options"
+	"sitemap-generator/pkg/metrics"
+	"sitemap-generator/pkg/readers"
+	"sitemap-generator/pkg/readers/models"
+	"sitemap-generator/services"
+)
+
+// newReader builds the reader of already validated options, the files it needs are loaded here;
+// the recorder is nil if the traffic is not recorded, requests are not limited if the limiter is nil
+func newReader(logger services.Logger, opts options.Options, limiter readers.HostLimiter, m *metrics.Metrics) (readers.Reader, readers.HarRecorder) {
+	jar, err := readers.NewCookieJar(opts.CookiesFile)
+	if err != nil {
+		logger.Fatal("Can not load cookies", err.Error())
+	}
+	tlsConfig, err := readers.NewTLSConfig(readers.TLSOptions{
+		CAFile:             opts.CAFile,
+		CertFile:           opts.CertFile,
+		KeyFile:            opts.KeyFile,
+		InsecureSkipVerify: opts.Insecure,
+	})
+	if err != nil {
+		logger.Fatal("Can not load certificates", err.Error())
+	}
+	var sessionExpired *regexp.Regexp
+	if opts.SessionExpired != "" {
+		sessionExpired = regexp.MustCompile(opts.SessionExpired)
+	}
+	authenticator, err := newAuthenticator(opts, sessionExpired)
+	if err != nil {
+		logger.Fatal("Can not set up login", err.Error())
+	}
+	headers, _ := options.Headers(opts)
+	auth, _ := options.Auth(opts)
+	proxy, _ := options.ProxyUrl(opts)
+
+	var recorder readers.HarRecorder
+	if opts.RecordHar != "" {
+		recorder = readers.NewHarRecorder()
+	}
+
+	readerOpts := readers.ReaderOptions{
+		Timeout:           opts.Timeout,
+		MaxRetries:        opts.MaxRetries,
+		MaxRedirects:      opts.MaxRedirects,
+		SniffContent:      opts.SniffContent,
+		IgnoreContentType: opts.IgnoreContentType,
+		MaxBodySize:       opts.MaxBodySize,
+		BodyTimeout:       opts.BodyTimeout,
+		AcceptEncodings:   options.AcceptEncodings(opts),
+		UserAgent:         opts.UserAgent,
+		Headers:           headers,
+		Auth:              auth,
+		CookieJar:         jar,
+		Proxy:             proxy,
+		TLSConfig:         tlsConfig,
+		Authenticator:     authenticator,
+		SessionExpired:    sessionExpired,
+		Recorder:          recorder,
+		Metrics:           m,
+	}
+
+	var reader readers.Reader
+	switch {
+	case opts.ReplayHar != "":
+		reader = readers.NewReplayReader(readHar(logger, opts.ReplayHar), readerOpts)
+	case opts.RootDir != "":
+		if reader, err = readers.NewFileReader(opts.StartUrl, opts.RootDir, readerOpts); err != nil {
+			logger.Fatal("Can not read the root directory", err.Error())
+		}
+	default:
+		reader = readers.NewReader(readerOpts)
+	}
+	if limiter != nil {
+		reader = readers.NewLimitedReader(reader, limiter)
+	}
+	return readers.NewCachedReader(reader, readers.CachedReaderOptions{
+		FailureTTL: opts.CheckFailureTTL,
+		MaxEntries: opts.CheckCacheSize,
+	}), recorder
+}
+
+func readHar(logger services.Logger, path string) models.Har {
+	f, err := os.Open(path)
+	if err != nil {
+		logger.Fatal("Can not open HAR file", err.Error())
+	}
+	defer f.Close()
+
+	har, err := readers.ReadHar(f)
+	if err != nil {
+		logger.Fatal("Can not read HAR file", err.Error())
+	}
+	return har
+}
+
+// writeHar saves the recorded traffic
+func writeHar(path string, recorder readers.HarRecorder) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if err = recorder.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// newAuthenticator builds the login of the form or the recorded request, nil if no login is needed;
+// the login fails if it leads to where the expired session is redirected to
+func newAuthenticator(opts options.Options, sessionExpired *regexp.Regexp) (readers.Authenticator, error) {
+	if opts.LoginRequest != "" {
+		raw, err := ioutil.ReadFile(opts.LoginRequest)
+		if err != nil {
+			return nil, err
+		}
+		return readers.NewReplayLogin(raw, opts.StartUrl, sessionExpired)
+	}
+	if opts.LoginUrl == "" && opts.LoginAction == "" {
+		return nil, nil
+	}
+
+	fields, _ := options.LoginFields(opts)
+	return readers.NewFormLogin(readers.FormLoginOptions{
+		LoginUrl:       opts.LoginUrl,
+		ActionUrl:      opts.LoginAction,
+		Fields:         fields,
+		CsrfField:      opts.LoginCsrfField,
+		SessionExpired: sessionExpired,
+	}), nil
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sitemap-generator/cmd/siteGenerator/options"
+	"sitemap-generator/pkg/crawlers"
+	crawlersModels "sitemap-generator/pkg/crawlers/models"
+	"sitemap-generator/pkg/metrics"
+	"sitemap-generator/pkg/parsers"
+	"sitemap-generator/pkg/readers"
+	"sitemap-generator/pkg/reports"
+	"sitemap-generator/pkg/workerPools"
+	"sitemap-generator/pkg/writers"
+	"sitemap-generator/services"
+	"sitemap-generator/utils"
+	"time"
+)
+
+// siteSummary is the outcome of the crawl of the site
+type siteSummary struct {
+	StartUrl string
+	Urls     int
+	Pages    int
+	Errors   int
+	// BrokenLinks is the number of broken links, internal ones are the links to the start URL host
+	BrokenLinks         int
+	InternalBrokenLinks bool
+	Traps               int
+	Duplicates          int
+	// Budget is the limit of the whole crawl which stopped it, empty if the site is crawled completely
+	Budget   crawlers.BudgetLimit
+	Duration time.Duration
+	Err      error
+}
+
+// siteCrawl is the crawl of the site with its own services, visited URLs and files;
+// it's set up before any site is crawled, so invalid options are reported at once
+type siteCrawl struct {
+	opts     options.Options
+	logger   services.Logger
+	recorder readers.HarRecorder
+	crawler  crawlers.Crawler
+
+	brokenLinks reports.BrokenLinksCollector
+	traps       reports.TrapsCollector
+	duplicates  reports.DuplicatesCollector
+	stats       reports.StatsCollector
+
+	file, brokenLinksFile, trapsFile, duplicatesFile, statsFile *os.File
+}
+
+// newSiteCrawl opens the files of the site with already validated options, the reader of the site
+// takes the slots of the hosts from the limiter shared by all the sites, and the metrics are shared as well
+func newSiteCrawl(logger services.Logger, opts options.Options, limiter readers.HostLimiter, m *metrics.Metrics) *siteCrawl {
+	sc := &siteCrawl{
+		opts:   opts,
+		logger: logger,
+	}
+	sc.file = openFile(logger, opts.OutputFile, "Can not open output file")
+	sc.brokenLinksFile = openFile(logger, opts.BrokenLinksFile, "Can not open broken links file")
+	sc.trapsFile = openFile(logger, opts.TrapsFile, "Can not open traps file")
+	sc.duplicatesFile = openFile(logger, opts.DuplicatesFile, "Can not open duplicates file")
+	sc.statsFile = openFile(logger, opts.StatsFile, "Can not open stats file")
+
+	// create services
+	var reader readers.Reader
+	reader, sc.recorder = newReader(logger, opts, limiter, m)
+	pages := frontier(opts)
+	wPool := workerPools.NewQueuedWorkerPool[crawlersModels.CrawlerContext](logger, workerPools.WorkerPoolOptions{WorkersCount: opts.ParallelRoutines, Metrics: m}, pages)
+	sc.brokenLinks = reports.NewBrokenLinksCollector(opts.StartUrl)
+	sc.traps = reports.NewTrapsCollector(opts.StartUrl)
+	sc.duplicates = reports.NewDuplicatesCollector(opts.StartUrl)
+	sc.stats = reports.NewStatsCollector(opts.StartUrl)
+	sc.crawler = crawlers.NewCrawler(crawlers.CrawlerOptions{
+		MaxDepth:        opts.MaxDepth,
+		Logger:          logger,
+		TaskPool:        wPool,
+		Reader:          reader,
+		Parser:          parsers.NewParser(),
+		Observers:       []crawlers.Observer{sc.brokenLinks, sc.traps, sc.duplicates, sc.stats, pages},
+		InclusionPolicy: inclusionPolicy(opts),
+		FetchMode:       crawlers.FetchMode(opts.FetchMode),
+		Budget:          budget(opts),
+		TrapDetector:    trapDetector(opts),
+		Deduplicator:    deduplicator(opts),
+		Metrics:         m,
+	})
+	return sc
+}
+
+// openFile opens the file to write, nil if the path is empty
+func openFile(logger services.Logger, path string, failure string) *os.File {
+	if path == "" {
+		return nil
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		logger.Fatal(failure, err.Error())
+	}
+	return file
+}
+
+// close closes the report files, the output file is closed by the sitemap writer
+func (sc *siteCrawl) close() {
+	for _, file := range []*os.File{sc.brokenLinksFile, sc.trapsFile, sc.duplicatesFile, sc.statsFile} {
+		if file != nil {
+			file.Close()
+		}
+	}
+}
+
+// run traverses the start URL recursively and writes found URLs to the sitemap while traversing,
+// then the reports; errors don't stop other sites, they're returned in the summary.
+// The stats of the crawl are printed to stderr if it's the only site
+func (sc *siteCrawl) run(printStats bool) (summary siteSummary) {
+	opts, logger := sc.opts, sc.logger
+	summary.StartUrl = opts.StartUrl
+	started := time.Now()
+	defer func() {
+		summary.Duration = time.Since(started)
+	}()
+
+	urls := make(chan *crawlersModels.Url, opts.ParallelRoutines)
+	written := make(chan error, 1)
+	go func() {
+		if sc.file == nil {
+			drain(urls)
+			written <- nil
+			return
+		}
+		written <- writeSitemap(sc.file, opts.StartUrl, urls)
+	}()
+
+	// URLs collected until the budget is exhausted are written anyway
+	var budgetErr *crawlers.BudgetExhaustedError
+	err := sc.crawler.TraverseStream(opts.StartUrl, urls)
+	if errors.As(err, &budgetErr) {
+		logger.Warn("Crawl is stopped because its budget is exhausted", string(budgetErr.Limit))
+		summary.Budget = budgetErr.Limit
+	} else if err != nil {
+		<-written
+		summary.Err = fmt.Errorf("error while scanning: %w", err)
+		return summary
+	}
+	if err = <-written; err != nil {
+		summary.Err = fmt.Errorf("error while write to sitemap: %w", err)
+		return summary
+	}
+	statsReport := sc.stats.Report()
+	summary.Urls = statsReport.UrlsCollected
+	summary.Pages = statsReport.PagesFetched
+	summary.Errors = statsReport.Errors.Total
+
+	if sc.recorder != nil {
+		if err = writeHar(opts.RecordHar, sc.recorder); err != nil {
+			summary.Err = fmt.Errorf("error while write recorded traffic: %w", err)
+			return summary
+		}
+	}
+
+	transferReport := statsReport.Transfer
+	logger.Info(fmt.Sprintf("Transferred %d bytes of %d pages, %d bytes decoded (compression ratio %.2f)",
+		transferReport.WireBytes, transferReport.Responses, transferReport.DecodedBytes, transferReport.Ratio()),
+		utils.InJSON(transferReport.Encodings))
+
+	// write the stats of the crawl, they're compared between runs to notice regressions
+	if printStats {
+		if err = writers.NewReportWriter(os.Stderr).WriteStats(statsReport, writers.ReportFormatText); err != nil {
+			summary.Err = fmt.Errorf("error while print stats: %w", err)
+			return summary
+		}
+	}
+	if sc.statsFile != nil {
+		if err = writers.NewReportWriter(sc.statsFile).WriteStats(statsReport, writers.ReportFormatJson); err != nil {
+			summary.Err = fmt.Errorf("error while write stats: %w", err)
+			return summary
+		}
+	}
+
+	// write report of the URLs skipped as crawler traps
+	trapsReport := sc.traps.Report()
+	summary.Traps = len(trapsReport.Urls)
+	if len(trapsReport.Urls) > 0 {
+		logger.Warn(fmt.Sprintf("Skipped %d URLs as crawler traps", len(trapsReport.Urls)), utils.InJSON(trapsReport.Counts))
+	}
+	if sc.trapsFile != nil {
+		rw := writers.NewReportWriter(sc.trapsFile)
+		if err = rw.WriteTraps(trapsReport, opts.TrapsFormat); err != nil {
+			summary.Err = fmt.Errorf("error while write traps report: %w", err)
+			return summary
+		}
+	}
+
+	// write report of the pages excluded as duplicates
+	duplicatesReport := sc.duplicates.Report()
+	for _, cluster := range duplicatesReport.Clusters {
+		summary.Duplicates += len(cluster.Duplicates)
+	}
+	if sc.duplicatesFile != nil {
+		rw := writers.NewReportWriter(sc.duplicatesFile)
+		if err = rw.WriteDuplicates(duplicatesReport, opts.DuplicatesFormat); err != nil {
+			summary.Err = fmt.Errorf("error while write duplicates report: %w", err)
+			return summary
+		}
+	}
+
+	// write report of the links which could not be read
+	report := sc.brokenLinks.Report()
+	summary.BrokenLinks = len(report.Links)
+	summary.InternalBrokenLinks = report.HasInternal()
+	if sc.brokenLinksFile != nil {
+		rw := writers.NewReportWriter(sc.brokenLinksFile)
+		if err = rw.WriteBrokenLinks(report, opts.BrokenLinksFormat); err != nil {
+			summary.Err = fmt.Errorf("error while write broken links report: %w", err)
+			return summary
+		}
+	}
+	return summary
+}
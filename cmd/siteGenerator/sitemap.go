@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	crawlersModels "sitemap-generator/pkg/crawlers/models"
+	"sitemap-generator/pkg/writers"
+	writersModels "sitemap-generator/pkg/writers/models"
+	"strings"
+)
+
+// writeSitemap writes URLs to the output file while they're being collected;
+// if they don't fit into a single sitemap, the parts are written to separate files
+// and the output file becomes a sitemap index referring to them
+func writeSitemap(file *os.File, startUrl string, urls <-chan *crawlersModels.Url) error {
+	outputFile := file.Name()
+
+	sw := writers.NewSitemapStreamWriter(writers.SitemapStreamWriterOptions{
+		Open: func(part int) (io.WriteCloser, error) {
+			if part == 1 {
+				return file, nil
+			}
+			return os.OpenFile(sitemapPartFile(outputFile, part), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		},
+	})
+
+	for u := range urls {
+		if err := sw.WriteUrl(writersModels.BuildSitemapUrl(u.Location, u.LastModified)); err != nil {
+			drain(urls)
+			// the current part is finished anyway, so its file is closed and keeps the URLs written so far
+			_ = sw.Close()
+			return err
+		}
+	}
+	if err := sw.Close(); err != nil {
+		return err
+	}
+	if sw.Parts() == 1 {
+		return nil
+	}
+
+	// the first part was written to the output file, move it to let the index take its place
+	if err := os.Rename(outputFile, sitemapPartFile(outputFile, 1)); err != nil {
+		return err
+	}
+
+	index := writersModels.SitemapIndex{}
+	for part := 1; part <= sw.Parts(); part++ {
+		loc, err := sitemapPartUrl(startUrl, sitemapPartFile(outputFile, part))
+		if err != nil {
+			return err
+		}
+		index.Sitemaps = append(index.Sitemaps, writersModels.SitemapRef{Location: loc})
+	}
+
+	indexFile, err := os.OpenFile(outputFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer indexFile.Close()
+
+	return writers.NewSitemapWriter(indexFile).WriteIndex(index)
+}
+
+// sitemapPartFile builds file name of the sitemap part, e.g. sitemap.xml -> sitemap-2.xml
+func sitemapPartFile(outputFile string, part int) string {
+	ext := filepath.Ext(outputFile)
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(outputFile, ext), part, ext)
+}
+
+// sitemapPartUrl expects sitemap files to be published in the root of the site
+func sitemapPartUrl(startUrl string, partFile string) (string, error) {
+	base, err := url.Parse(startUrl)
+	if err != nil {
+		return "", err
+	}
+	return base.ResolveReference(&url.URL{Path: "/" + filepath.Base(partFile)}).String(), nil
+}
+
+// drain reads the rest of URLs to let the crawler finish
+func drain(urls <-chan *crawlersModels.Url) {
+	for range urls {
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	crawlersModels "sitemap-generator/pkg/crawlers/models"
+	"sitemap-generator/pkg/writers"
+	"sitemap-generator/utils"
+	"strings"
+	"testing"
+)
+
+func TestWriteSitemap(t *testing.T) {
+	write := func(t *testing.T, urlsCount int) string {
+		outputFile := filepath.Join(t.TempDir(), "sitemap.xml")
+		file, err := os.Create(outputFile)
+		utils.AssertNoError(t, err)
+
+		urls := make(chan *crawlersModels.Url)
+		go func() {
+			defer close(urls)
+			for i := 0; i < urlsCount; i++ {
+				urls <- &crawlersModels.Url{Location: fmt.Sprintf("https://example.com/page-%d", i)}
+			}
+		}()
+		utils.AssertNoError(t, writeSitemap(file, "https://example.com/", urls))
+		return outputFile
+	}
+
+	t.Run("single sitemap", func(t *testing.T) {
+		outputFile := write(t, 2)
+
+		content, err := os.ReadFile(outputFile)
+		utils.AssertNoError(t, err)
+		utils.AssertTrue(t, strings.Contains(string(content), "<urlset"))
+		utils.AssertTrue(t, strings.Contains(string(content), "https://example.com/page-1</loc>"))
+		_, err = os.Stat(sitemapPartFile(outputFile, 1))
+		utils.AssertTrue(t, os.IsNotExist(err))
+	})
+
+	t.Run("sitemap rolls over to parts with the index", func(t *testing.T) {
+		outputFile := write(t, writers.MaxSitemapUrls+1)
+
+		index, err := os.ReadFile(outputFile)
+		utils.AssertNoError(t, err)
+		utils.AssertTrue(t, strings.Contains(string(index), "<sitemapindex"))
+		utils.AssertTrue(t, strings.Contains(string(index), "<loc>https://example.com/sitemap-1.xml</loc>"))
+		utils.AssertTrue(t, strings.Contains(string(index), "<loc>https://example.com/sitemap-2.xml</loc>"))
+		utils.AssertEqual(t, strings.Count(string(index), "<sitemap>"), 2)
+
+		// the first part is moved from the output file
+		first, err := os.ReadFile(filepath.Join(filepath.Dir(outputFile), "sitemap-1.xml"))
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, strings.Count(string(first), "<url>"), writers.MaxSitemapUrls)
+		utils.AssertTrue(t, strings.Contains(string(first), "https://example.com/page-0</loc>"))
+
+		second, err := os.ReadFile(filepath.Join(filepath.Dir(outputFile), "sitemap-2.xml"))
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, strings.Count(string(second), "<url>"), 1)
+		utils.AssertTrue(t, strings.Contains(string(second), fmt.Sprintf("https://example.com/page-%d</loc>", writers.MaxSitemapUrls)))
+	})
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// writeSummary prints the table of the crawled sites with the totals of all of them
+func writeSummary(w io.Writer, summaries []siteSummary) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SITE\tURLS\tPAGES\tERRORS\tBROKEN LINKS\tTRAPS\tDUPLICATES\tDURATION\tSTATUS")
+
+	total := siteSummary{StartUrl: fmt.Sprintf("total of %d sites", len(summaries))}
+	failed, stopped := 0, 0
+	for _, s := range summaries {
+		status := "ok"
+		switch {
+		case s.Err != nil:
+			status = "failed: " + s.Err.Error()
+			failed++
+		case s.Budget != "":
+			status = "stopped: " + string(s.Budget)
+			stopped++
+		}
+		writeSummaryRow(tw, s, status)
+
+		total.Urls += s.Urls
+		total.Pages += s.Pages
+		total.Errors += s.Errors
+		total.BrokenLinks += s.BrokenLinks
+		total.Traps += s.Traps
+		total.Duplicates += s.Duplicates
+		// the sites are crawled at once, so the total is the longest crawl
+		if s.Duration > total.Duration {
+			total.Duration = s.Duration
+		}
+	}
+	writeSummaryRow(tw, total, fmt.Sprintf("%d ok, %d stopped, %d failed", len(summaries)-failed-stopped, stopped, failed))
+
+	return tw.Flush()
+}
+
+func writeSummaryRow(w io.Writer, s siteSummary, status string) {
+	fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t%d\t%s\t%s\n",
+		s.StartUrl, s.Urls, s.Pages, s.Errors, s.BrokenLinks, s.Traps, s.Duplicates, s.Duration.Round(time.Millisecond), status)
+}
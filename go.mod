@@ -2,4 +2,9 @@ module sitemap-generator
 
 go 1.18
 
-require golang.org/x/net v0.0.0-20220622184535-263ec571b305
+require (
+	github.com/andybalholm/brotli v1.1.1
+	golang.org/x/net v0.0.0-20220622184535-263ec571b305
+)
+
+require golang.org/x/text v0.13.0 // indirect
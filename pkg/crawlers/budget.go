@@ -0,0 +1,186 @@
+package crawlers
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Budget limits the crawl in addition to the max depth, zero limits are unlimited
+type Budget struct {
+	// MaxUrls is max number of URLs collected
+	MaxUrls int
+	// MaxPages is max number of pages fetched (checks of the links by HEAD requests are not counted)
+	MaxPages int
+	// MaxBytes is max number of bytes downloaded, the page exceeding it is still scanned
+	MaxBytes int64
+	// MaxDuration is max wall-clock time of the crawl, pages being fetched at that moment are still scanned
+	MaxDuration time.Duration
+	// PerHost limits are applied to each host separately, only URLs of the host are skipped when its one is exhausted
+	PerHost HostBudget
+}
+
+type HostBudget struct {
+	MaxUrls  int
+	MaxPages int
+	MaxBytes int64
+}
+
+type BudgetLimit string
+
+const (
+	BudgetLimitUrls     BudgetLimit = "max-urls"
+	BudgetLimitPages    BudgetLimit = "max-pages"
+	BudgetLimitBytes    BudgetLimit = "max-bytes"
+	BudgetLimitDuration BudgetLimit = "max-duration"
+)
+
+// BudgetExhaustedError tells the crawl is stopped because the limit of the whole crawl is reached,
+// URLs collected before are valid results anyway
+type BudgetExhaustedError struct {
+	Limit BudgetLimit
+}
+
+func (be *BudgetExhaustedError) Error() string {
+	return fmt.Sprintf("budget is exhausted: %s", be.Limit)
+}
+
+type budgetUsage struct {
+	urls  int
+	pages int
+	bytes int64
+}
+
+// budgetTracker counts the usage of the budget by the workers
+type budgetTracker struct {
+	locker    sync.Mutex
+	budget    Budget
+	total     budgetUsage
+	hosts     map[string]*budgetUsage
+	exhausted BudgetLimit
+	timer     *time.Timer
+	// onExhausted is called once the first limit of the whole crawl is reached
+	onExhausted func()
+}
+
+func newBudgetTracker(budget Budget, onExhausted func()) *budgetTracker {
+	bt := &budgetTracker{
+		budget:      budget,
+		hosts:       make(map[string]*budgetUsage),
+		onExhausted: onExhausted,
+	}
+	if budget.MaxDuration > 0 {
+		bt.timer = time.AfterFunc(budget.MaxDuration, func() {
+			bt.locker.Lock()
+			bt.exhaust(BudgetLimitDuration)
+			bt.locker.Unlock()
+		})
+	}
+	return bt
+}
+
+// stop releases the timer of the duration limit
+func (bt *budgetTracker) stop() {
+	if bt.timer != nil {
+		bt.timer.Stop()
+	}
+}
+
+// reached returns the limit of the whole crawl which is reached, empty if there is no such
+func (bt *budgetTracker) reached() BudgetLimit {
+	bt.locker.Lock()
+	defer bt.locker.Unlock()
+
+	return bt.exhausted
+}
+
+// takePage counts the page to be fetched, returns the reached limit if it can't be fetched
+// and whether it's the limit of the URL host only
+func (bt *budgetTracker) takePage(location string) (limit BudgetLimit, perHost bool) {
+	bt.locker.Lock()
+	defer bt.locker.Unlock()
+
+	if bt.exhausted != "" {
+		return bt.exhausted, false
+	}
+	if bt.budget.MaxBytes > 0 && bt.total.bytes >= bt.budget.MaxBytes {
+		return bt.exhaust(BudgetLimitBytes), false
+	}
+	if bt.budget.MaxPages > 0 && bt.total.pages >= bt.budget.MaxPages {
+		return bt.exhaust(BudgetLimitPages), false
+	}
+
+	host := bt.host(location)
+	if bt.budget.PerHost.MaxBytes > 0 && host.bytes >= bt.budget.PerHost.MaxBytes {
+		return BudgetLimitBytes, true
+	}
+	if bt.budget.PerHost.MaxPages > 0 && host.pages >= bt.budget.PerHost.MaxPages {
+		return BudgetLimitPages, true
+	}
+
+	bt.total.pages++
+	host.pages++
+	return "", false
+}
+
+// addBytes counts bytes of the fetched page
+func (bt *budgetTracker) addBytes(location string, bytes int64) {
+	bt.locker.Lock()
+	defer bt.locker.Unlock()
+
+	bt.total.bytes += bytes
+	bt.host(location).bytes += bytes
+}
+
+// takeUrl counts URL to be collected, returns the reached limit if it can't be collected
+// and whether it's the limit of the URL host only
+func (bt *budgetTracker) takeUrl(location string) (limit BudgetLimit, perHost bool) {
+	bt.locker.Lock()
+	defer bt.locker.Unlock()
+
+	if bt.exhausted != "" {
+		return bt.exhausted, false
+	}
+	if bt.budget.MaxUrls > 0 && bt.total.urls >= bt.budget.MaxUrls {
+		return bt.exhaust(BudgetLimitUrls), false
+	}
+	host := bt.host(location)
+	if bt.budget.PerHost.MaxUrls > 0 && host.urls >= bt.budget.PerHost.MaxUrls {
+		return BudgetLimitUrls, true
+	}
+
+	bt.total.urls++
+	host.urls++
+	return "", false
+}
+
+// exhaust keeps the first reached limit; it's called under the lock
+func (bt *budgetTracker) exhaust(limit BudgetLimit) BudgetLimit {
+	if bt.exhausted == "" {
+		bt.exhausted = limit
+		bt.onExhausted()
+	}
+	return bt.exhausted
+}
+
+// host returns the usage of the URL host; it's called under the lock
+func (bt *budgetTracker) host(location string) *budgetUsage {
+	h := hostOf(location)
+	usage, ok := bt.hosts[h]
+	if !ok {
+		usage = &budgetUsage{}
+		bt.hosts[h] = usage
+	}
+	return usage
+}
+
+// hostOf returns the lower-cased host of URL, empty if URL is invalid
+func hostOf(location string) string {
+	u, err := url.Parse(location)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Host)
+}
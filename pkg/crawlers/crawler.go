@@ -1,103 +1,314 @@
 package crawlers
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"sitemap-generator/pkg/crawlers/models"
+	"sitemap-generator/pkg/metrics"
 	"sitemap-generator/pkg/parsers"
+	parsersModels "sitemap-generator/pkg/parsers/models"
 	"sitemap-generator/pkg/readers"
+	readersModels "sitemap-generator/pkg/readers/models"
 	"sitemap-generator/pkg/workerPools"
 	"sitemap-generator/services"
 	"sitemap-generator/utils"
+	"strings"
 	"sync"
+	"time"
 )
 
 type CrawlerOptions struct {
-	MaxDepth   int
-	Logger     services.Logger
-	Reader     readers.Reader
-	Parser     parsers.Parser
+	MaxDepth int
+	// LongRedirectChain is number of redirects above which the chain is reported as too long
+	LongRedirectChain int
+	Logger            services.Logger
+	Reader            readers.Reader
+	Parser            parsers.Parser
+	// WorkerPool is the pool of the former API, it's used only if TaskPool is not set
+	//
+	// Deprecated: use TaskPool
 	WorkerPool workerPools.WorkerPool
+	// TaskPool processes the pages in parallel, it's stopped once the budget is exhausted
+	TaskPool workerPools.TypedWorkerPool[models.CrawlerContext]
+	// Observers are notified about crawl events in addition to the logging
+	Observers []Observer
+	// InclusionPolicy decides which URLs get into the results, only 2xx ones by default
+	InclusionPolicy InclusionPolicy
+	// FetchMode is how URLs are requested, FetchModeHead by default
+	FetchMode FetchMode
+	// Budget limits the crawl in addition to MaxDepth, unlimited by default
+	Budget Budget
+	// TrapDetector skips URLs leading to crawler traps, nothing is detected by default
+	TrapDetector TrapDetector
+	// Deduplicator groups pages with the same content, so only one page of the group is collected;
+	// pages are collected at the end of the crawl then, all of them are collected as they're read by default
+	Deduplicator Deduplicator
+	// Metrics count pages, URLs and errors of the crawl, they're not exposed anywhere if it's nil
+	Metrics *metrics.Metrics
 }
 
+type FetchMode string
+
+const (
+	// FetchModeHead checks links by HEAD requests and reads HTML pages by separate GET ones
+	FetchModeHead FetchMode = "head"
+	// FetchModeGet checks links and reads HTML pages by a single GET request
+	FetchModeGet FetchMode = "get"
+)
+
+const defaultLongRedirectChain = 2
+
 type Crawler interface {
 	Traverse(startUrl string) ([]*models.Url, error)
+	TraverseStream(startUrl string, results chan<- *models.Url) error
+	// Progress can be called at any time, even while the site is traversed
+	Progress() models.Progress
 }
 
 type crawler struct {
-	maxDepth int
+	maxDepth          int
+	longRedirectChain int
+	fetchMode         FetchMode
+	budget            Budget
 
 	logger     services.Logger
 	reader     readers.Reader
 	parser     parsers.Parser
-	workerPool workerPools.WorkerPool
+	workerPool workerPools.TypedWorkerPool[models.CrawlerContext]
+	observer   *observers
+	policy     InclusionPolicy
+	traps      TrapDetector
+	dedup      Deduplicator
 
 	resultsLocker sync.Mutex
-	urls          map[string]*models.Url
+	visited       map[string]bool
+	results       chan<- *models.Url
+	tracker       *budgetTracker
+
+	// the start page is read once, it's collected when some page links to it
+	start       models.CrawlerContext
+	startPage   readersModels.Page
+	startLinked bool
 }
 
 func NewCrawler(opts CrawlerOptions) Crawler {
+	if opts.Metrics == nil {
+		opts.Metrics = metrics.NewMetrics(metrics.NewRegistry())
+	}
+	list := []Observer{NewLoggingObserver(opts.Logger, opts.MaxDepth), newMetricsObserver(opts.Metrics)}
+	list = append(list, opts.Observers...)
+
+	if opts.LongRedirectChain <= 0 {
+		opts.LongRedirectChain = defaultLongRedirectChain
+	}
+	if opts.FetchMode == "" {
+		opts.FetchMode = FetchModeHead
+	}
+	if opts.InclusionPolicy == nil {
+		opts.InclusionPolicy = NewInclusionPolicy(InclusionPolicyOptions{})
+	}
+	if opts.TrapDetector == nil {
+		opts.TrapDetector = NewTrapDetector(TrapDetectorOptions{})
+	}
+	if opts.TaskPool == nil {
+		opts.TaskPool = workerPools.AdaptWorkerPool[models.CrawlerContext](opts.WorkerPool)
+	}
+
 	return &crawler{
-		maxDepth:   opts.MaxDepth,
-		logger:     opts.Logger,
-		reader:     opts.Reader,
-		parser:     opts.Parser,
-		workerPool: opts.WorkerPool,
+		maxDepth:          opts.MaxDepth,
+		longRedirectChain: opts.LongRedirectChain,
+		fetchMode:         opts.FetchMode,
+		budget:            opts.Budget,
+		logger:            opts.Logger,
+		reader:            opts.Reader,
+		parser:            opts.Parser,
+		workerPool:        opts.TaskPool,
+		observer:          newObservers(list),
+		policy:            opts.InclusionPolicy,
+		traps:             opts.TrapDetector,
+		dedup:             opts.Deduplicator,
+	}
+}
+
+func (c *crawler) Progress() models.Progress {
+	stats := c.workerPool.Stats()
+	pages, urls, errors := c.observer.progress()
+	return models.Progress{
+		Queued:        stats.Queued,
+		BusyWorkers:   stats.Busy,
+		Workers:       stats.Workers,
+		PagesFetched:  pages,
+		UrlsCollected: urls,
+		Errors:        errors,
 	}
 }
 
+// Traverse collects all URLs of the site and returns them at the end of the crawl;
+// URLs collected until the budget is exhausted are returned along with *BudgetExhaustedError
 func (c *crawler) Traverse(startUrl string) ([]*models.Url, error) {
-	c.urls = make(map[string]*models.Url)
+	resultsChan := make(chan *models.Url)
+	results := make([]*models.Url, 0)
 
-	// a wrap to cast input to the needed type
-	var handler workerPools.WorkerHandler = func(v interface{}) error {
-		ctx, ok := v.(models.CrawlerContext)
-		if !ok {
-			return fmt.Errorf("Crawler: unknown type of input in worker handler: %T", v)
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+		for u := range resultsChan {
+			results = append(results, u)
 		}
-		return c.traverseIteration(ctx)
+	}()
+
+	err := c.TraverseStream(startUrl, resultsChan)
+	<-collected
+	var budgetErr *BudgetExhaustedError
+	if err != nil && !errors.As(err, &budgetErr) {
+		return nil, err
+	}
+	return results, err
+}
+
+// TraverseStream sends every collected URL to the results channel as soon as it's found,
+// so the caller can process them without waiting for the end of the crawl;
+// the channel is closed when the crawl is finished (or stopped with *BudgetExhaustedError)
+func (c *crawler) TraverseStream(startUrl string, results chan<- *models.Url) error {
+	defer close(results)
+
+	c.visited = make(map[string]bool)
+	c.results = results
+	c.start = models.CrawlerContext{Location: startUrl}
+	c.startLinked = false
+	c.reserve(c.start)
+
+	// the member area can be crawled only after the login
+	if err := c.reader.Authenticate(); err != nil {
+		return fmt.Errorf("Crawler: could not authenticate: %s", err.Error())
 	}
 
+	handler := func(_ context.Context, ctx models.CrawlerContext) error {
+		return c.traverseIteration(ctx)
+	}
 	if _, err := c.workerPool.Init(handler); err != nil {
-		return nil, fmt.Errorf("Crawler: could not initialize worker pool: %s", err.Error())
+		return fmt.Errorf("Crawler: could not initialize worker pool: %s", err.Error())
 	}
 	c.logger.Debug("Crawler: worker pool initialized")
 
+	// the rest of the queue is dropped once the budget is exhausted
+	c.tracker = newBudgetTracker(c.budget, c.workerPool.Stop)
+	defer c.tracker.stop()
+
+	// the failed URLs are already reported to the observers, so they're only logged here
+	logged := make(chan struct{})
+	go func() {
+		defer close(logged)
+		for err := range c.workerPool.Errors() {
+			var panicErr *workerPools.PanicError
+			if errors.As(err, &panicErr) {
+				c.logger.Error("Crawler: could not scan URL", err.Error(), string(panicErr.Stack))
+				continue
+			}
+			c.logger.Debug("Crawler: could not scan URL", err.Error())
+		}
+	}()
+
 	// analyze the start URL, collect links and put initial tasks to the queue
 	err := c.traverseIteration(models.CrawlerContext{
 		Location: startUrl,
 	})
-	if err != nil {
-		return nil, err
-	}
 
 	// wait until all links extracted or max depth is reached
 	c.workerPool.WaitFinalize()
+	<-logged
 	c.logger.Debug("Crawler: tasks completed")
 
-	// convert to necessary result type
-	results := make([]*models.Url, 0)
-	for _, u := range c.urls {
-		results = append(results, u)
+	if c.dedup != nil {
+		c.collectDeduplicated()
 	}
 
-	return results, nil
+	if limit := c.tracker.reached(); err == nil && limit != "" {
+		return &BudgetExhaustedError{Limit: limit}
+	}
+	return err
 }
 
+// traverseIteration reads the page, collects it if it's not the start one
+// and dispatches the links found on the page
 func (c *crawler) traverseIteration(ctx models.CrawlerContext) error {
 	c.logger.Debug("Crawler: starting to scan URL", ctx)
-	result, err := c.scanUrlForLinks(ctx)
-	if err != nil {
-		return err
+
+	// the rest of the queue is dropped when the crawl is stopped
+	if c.tracker.reached() != "" {
+		return nil
+	}
+	if !c.takePage(ctx) {
+		return nil
+	}
+
+	var page readersModels.Page
+	var links []parsersModels.Link
+	var err error
+	if ctx.Depth == 0 {
+		if page, links, err = c.readPage(ctx); err != nil {
+			return err
+		}
+		c.start = c.withPageInfo(ctx, page)
+		c.startPage = page
+	} else if ctx.Checked {
+		if page, links, err = c.readPage(ctx); err != nil {
+			return err
+		}
+	} else {
+		// the link is checked and read by the single request
+		if page, links, err = c.fetchPage(ctx); err != nil {
+			return err
+		}
+		var ok bool
+		if ctx, ok = c.checkedContext(ctx, page.Info); !ok {
+			return nil
+		}
+		if !c.accept(ctx) {
+			return nil
+		}
 	}
+
+	// the start URL is collected only when some page links to it
+	if ctx.Depth > 0 {
+		if reason, details := c.policy.CheckPage(page); reason != "" {
+			c.skip(ctx, reason, details)
+			return nil
+		}
+		if trap, details := c.traps.CheckPage(ctx.Location, page); trap != "" {
+			c.skipTrap(ctx, trap, details)
+			return nil
+		}
+		if c.dedup != nil {
+			if !c.takeUrl(ctx) {
+				return nil
+			}
+			c.dedup.Add(ctx, page)
+		} else if !c.collect(ctx) {
+			return nil
+		}
+
+		// the page was read only to check if it's included or a duplicate
+		if ctx.Depth >= c.maxDepth {
+			return nil
+		}
+	}
+
+	result := c.scanPageForLinks(ctx, links)
 	c.logger.Debug("Crawler: URL scanned", utils.InJSON(result))
 
-	// add to result and produce new task if needed
+	// produce new task for the links met first time
 	for _, r := range result {
-		added := c.addResult(&models.Url{
-			Location:     r.Location,
-			LastModified: r.LastModified,
-		})
-		if added {
+		if c.tracker.reached() != "" {
+			break
+		}
+		if r.Location == c.start.Location {
+			c.collectStart(r)
+		} else if c.reserve(r) {
 			c.dispatch(r)
 		}
 	}
@@ -105,72 +316,447 @@ func (c *crawler) traverseIteration(ctx models.CrawlerContext) error {
 	return nil
 }
 
-func (c *crawler) scanUrlForLinks(ctx models.CrawlerContext) ([]models.CrawlerContext, error) {
-	result := make([]models.CrawlerContext, 0)
-
+// readPage reads the page and parses its links while it's being read
+func (c *crawler) readPage(ctx models.CrawlerContext) (readersModels.Page, []parsersModels.Link, error) {
 	c.logger.Debug("Crawler: starting to read URL", ctx)
-	body, err := c.reader.ReadUrl(ctx.Location)
+	started := time.Now()
+	body := c.newPageBody(ctx)
+	page, err := c.reader.ReadUrlTo(ctx.Location, body.consume)
+	page.Body = body.content
+	c.pageFetched(ctx, page, body.size, time.Since(started))
+
+	if err != nil {
+		c.fail(models.ErrorEvent{
+			Op:    models.ErrorOpRead,
+			Url:   ctx.Location,
+			Depth: ctx.Depth,
+			Err:   err,
+		})
+	}
+	return page, body.links, err
+}
+
+// fetchPage checks the link and reads it if it's HTML page, its links are parsed while it's being read
+func (c *crawler) fetchPage(ctx models.CrawlerContext) (readersModels.Page, []parsersModels.Link, error) {
+	c.logger.Debug("Crawler: starting to fetch URL", ctx)
+	started := time.Now()
+	body := c.newPageBody(ctx)
+	page, err := c.reader.FetchUrlTo(ctx.Location, body.consume)
+	page.Body = body.content
+	c.pageFetched(ctx, page, body.size, time.Since(started))
+
 	if err != nil {
-		c.logger.Warn("Crawler: could not read URL", ctx.Location, err.Error())
-		return result, err
+		c.fail(models.ErrorEvent{
+			Op:    models.ErrorOpCheck,
+			Url:   ctx.Location,
+			From:  ctx.From,
+			Text:  ctx.Text,
+			Depth: ctx.Depth,
+			Err:   err,
+		})
 	}
-	c.logger.Debug(fmt.Sprintf("Crawler: got body (length: %d bytes)", len(body)))
+	return page, body.links, err
+}
+
+// pageBody parses links of the page while its body is being read, the body itself is kept
+// only if the page is checked by its content (soft 404, crawler traps or duplicates)
+type pageBody struct {
+	crawler *crawler
+	ctx     models.CrawlerContext
+
+	links   []parsersModels.Link
+	content []byte
+	size    int
+}
+
+func (c *crawler) newPageBody(ctx models.CrawlerContext) *pageBody {
+	return &pageBody{
+		crawler: c,
+		ctx:     ctx,
+	}
+}
+
+func (pb *pageBody) consume(page readersModels.Page, body io.Reader) {
+	counter := &countingReader{src: body}
+	var src io.Reader = counter
+	var content bytes.Buffer
+	if pb.crawler.needsContent() {
+		src = io.TeeReader(counter, &content)
+	}
+
+	// links of the pages at max depth are not scanned
+	if pb.ctx.Depth < pb.crawler.maxDepth || pb.ctx.Depth == 0 {
+		// links are relative to where the page is redirected to
+		pageUrl := pb.ctx.Location
+		if page.Url != "" {
+			pageUrl = page.Url
+		}
+		pb.crawler.logger.Debug("Crawler: starting to parse HTML")
+		pb.links = pb.crawler.parser.ParseHtmlReader(pageUrl, page.Header.Get("Content-Type"), src)
+	}
+	// the rest of the body after the end of the doc is read too, so its size is known
+	_, _ = io.Copy(ioutil.Discard, src)
+
+	pb.size = counter.read
+	if content.Len() > 0 {
+		pb.content = content.Bytes()
+	}
+}
+
+// needsContent tells if the pages are checked by their content, so their bodies are kept
+func (c *crawler) needsContent() bool {
+	return c.policy.NeedsContent() || c.traps.NeedsContent() || c.dedup != nil
+}
+
+// countingReader counts bytes read through it
+type countingReader struct {
+	src  io.Reader
+	read int
+}
+
+func (cr *countingReader) Read(p []byte) (n int, err error) {
+	n, err = cr.src.Read(p)
+	cr.read += n
+	return
+}
+
+// pageFetched counts bytes of the page in the budget and notifies the observers
+func (c *crawler) pageFetched(ctx models.CrawlerContext, page readersModels.Page, bodySize int, duration time.Duration) {
+	if page.StatusCode == 0 {
+		return
+	}
+
+	bytes := page.Transfer.WireBytes
+	if bytes == 0 {
+		bytes = int64(bodySize)
+	}
+	c.tracker.addBytes(ctx.Location, bytes)
+
+	c.observer.OnPageFetched(models.PageFetchedEvent{
+		Url:        ctx.Location,
+		Depth:      ctx.Depth,
+		StatusCode: page.StatusCode,
+		Header:     page.Header,
+		BodySize:   bodySize,
+		Duration:   duration,
+		Truncated:  page.Truncated,
+		Transfer:   page.Transfer,
+	})
+}
+
+// scanPageForLinks checks the links parsed on the page
+func (c *crawler) scanPageForLinks(ctx models.CrawlerContext, links []parsersModels.Link) []models.CrawlerContext {
+	result := make([]models.CrawlerContext, 0)
 
-	c.logger.Debug("Crawler: starting to parse HTML")
-	urls := c.parser.ParseHtmlForLinks(ctx.Location, body)
-	c.logger.Debug("Crawler: got links", urls)
+	urls := make([]string, len(links))
+	texts := make(map[string]string)
+	for i, l := range links {
+		urls[i] = l.Url
+		if texts[l.Url] == "" {
+			texts[l.Url] = l.Text
+		}
+		c.observer.OnLinkDiscovered(models.LinkDiscoveredEvent{
+			From:    ctx.Location,
+			To:      l.Url,
+			Element: l.Element,
+			Text:    l.Text,
+		})
+	}
 
 	urls = utils.StringSliceUnique(urls)
 	for _, u := range urls {
-		// such check could be duplicated by other workers if they meet this URL on pages they scan,
-		// but it's a cheap price to avoid a waiting for the end of a slow or timed-out check by ALL workers
+		// the links are not checked if they're not going to be dispatched
+		if c.tracker.reached() != "" {
+			break
+		}
+		uCtx := models.CrawlerContext{
+			Location: u,
+			Depth:    ctx.Depth + 1,
+			From:     ctx.Location,
+			Text:     texts[u],
+		}
+
+		// traps are detected by URL only, so they're not even checked
+		if c.isTrap(uCtx) {
+			continue
+		}
+
+		// the link will be checked when it's fetched, the start page is already read
+		if c.fetchMode == FetchModeGet || u == c.start.Location {
+			result = append(result, uCtx)
+			continue
+		}
+
+		// the same URL is checked for each page it's found on (before the deduplication),
+		// so the reader is expected to cache the checks (see readers.NewCachedReader)
 		c.logger.Debug("Crawler: checking if URL acceptable", u)
 		urlInfo, err := c.reader.CheckUrl(u)
 
 		if err == nil {
-			uCtx := models.CrawlerContext{
-				Location:     u,
-				LastModified: urlInfo.LastModified,
-				IsHtml:       urlInfo.IsHtml,
-				Depth:        ctx.Depth + 1,
-			}
+			uCtx = c.withUrlInfo(uCtx, urlInfo)
 			result = append(result, uCtx)
 			c.logger.Debug("Crawler: checked URL", uCtx)
 		} else {
-			c.logger.Warn("Crawler: could not read URL while checking", u, err.Error())
+			c.fail(models.ErrorEvent{
+				Op:    models.ErrorOpCheck,
+				Url:   u,
+				From:  ctx.Location,
+				Text:  texts[u],
+				Depth: ctx.Depth + 1,
+				Err:   err,
+			})
 		}
 	}
-	return result, nil
+	return result
 }
 
-// dispatch adds a task to the queue if needed
-func (c *crawler) dispatch(ctx models.CrawlerContext) {
-	if ctx.IsHtml {
-		c.logger.Debug("Crawler: URL is HTML page", ctx)
-		if ctx.Depth < c.maxDepth {
-			c.workerPool.AddTask(ctx)
-		} else {
-			c.logger.Info(fmt.Sprintf("Crawler: skip scanning, would be too deep for max depth %d", c.maxDepth), ctx)
-			return
+// withUrlInfo fills the context by the check result, replacing the link with where it's redirected to
+func (c *crawler) withUrlInfo(ctx models.CrawlerContext, info readersModels.UrlInfo) models.CrawlerContext {
+	if info.FinalUrl != "" && info.FinalUrl != ctx.Location {
+		c.observer.OnRedirect(models.RedirectEvent{
+			From:      ctx.Location,
+			To:        info.FinalUrl,
+			Redirects: info.Redirects,
+			Issues:    c.redirectIssues(ctx.Location, info.Redirects),
+		})
+		ctx.Location = info.FinalUrl
+	}
+
+	ctx.LastModified = info.LastModified
+	ctx.StatusCode = info.StatusCode
+	ctx.IsHtml = info.IsHtml
+	ctx.ContentClass = string(info.ContentClass)
+	ctx.Checked = true
+	return ctx
+}
+
+// withPageInfo fills the context of the read page
+func (c *crawler) withPageInfo(ctx models.CrawlerContext, page readersModels.Page) models.CrawlerContext {
+	ctx.LastModified = page.Info.LastModified
+	ctx.StatusCode = page.StatusCode
+	ctx.IsHtml = page.Info.IsHtml
+	ctx.ContentClass = string(page.Info.ContentClass)
+	ctx.Checked = true
+	return ctx
+}
+
+// checkedContext fills the context of the fetched link; returns *false*
+// if the link is redirected to already visited URL
+func (c *crawler) checkedContext(ctx models.CrawlerContext, info readersModels.UrlInfo) (models.CrawlerContext, bool) {
+	checked := c.withUrlInfo(ctx, info)
+	if checked.Location != ctx.Location && !c.reserve(checked) {
+		return checked, false
+	}
+	return checked, true
+}
+
+// redirectIssues detects redirects which are worth fixing on the site,
+// redirect loops are not here since they end up with an error
+func (c *crawler) redirectIssues(from string, redirects []readersModels.Redirect) []models.RedirectIssue {
+	issues := make([]models.RedirectIssue, 0)
+	if len(redirects) > c.longRedirectChain {
+		issues = append(issues, models.RedirectIssueLongChain)
+	}
+
+	previous := from
+	for _, r := range redirects {
+		if strings.HasPrefix(previous, "https://") && strings.HasPrefix(r.Location, "http://") {
+			issues = append(issues, models.RedirectIssueDowngrade)
+			break
 		}
-	} else {
-		c.logger.Debug("Crawler: not HTML page, skip it", ctx)
+		previous = r.Location
+	}
+
+	if len(issues) == 0 {
+		return nil
+	}
+	return issues
+}
+
+// dispatch adds a task to the queue if the page needs to be read, otherwise collects URL right away
+func (c *crawler) dispatch(ctx models.CrawlerContext) {
+	if ctx.Checked && !c.accept(ctx) {
 		return
 	}
+	c.logger.Debug("Crawler: URL is added to the queue", ctx)
+	c.workerPool.AddTask(ctx)
+}
+
+// accept checks if the page of the checked URL needs to be read, otherwise collects URL if it's included
+func (c *crawler) accept(ctx models.CrawlerContext) bool {
+	info := readersModels.UrlInfo{
+		StatusCode:   ctx.StatusCode,
+		ContentClass: readersModels.ContentClass(ctx.ContentClass),
+	}
+	if reason, details := c.policy.CheckUrl(info); reason != "" {
+		c.skip(ctx, reason, details)
+		return false
+	}
+	if !ctx.IsHtml {
+		c.collect(ctx)
+		return false
+	}
+	// the page at max depth is read only if its content is checked or deduplicated
+	if ctx.Depth >= c.maxDepth && !c.policy.NeedsContent() && c.dedup == nil {
+		c.collect(ctx)
+		return false
+	}
+	return true
 }
 
-// addResult collects URL if it's not yet in the list and returns *true*;
+// fail reports the error of URL; URLs which are not served offline are skipped instead,
+// since they aren't broken, they just can't be checked offline
+func (c *crawler) fail(event models.ErrorEvent) {
+	if readers.IsOffline(event.Err) {
+		c.skip(models.CrawlerContext{Location: event.Url, Depth: event.Depth}, models.SkipReasonOffline, "")
+		return
+	}
+	c.observer.OnError(event)
+}
+
+func (c *crawler) skip(ctx models.CrawlerContext, reason models.SkipReason, details string) {
+	c.observer.OnUrlSkipped(models.UrlSkippedEvent{
+		Url:     ctx.Location,
+		Depth:   ctx.Depth,
+		Reason:  reason,
+		Details: details,
+	})
+}
+
+// isTrap checks if URL leads to a crawler trap and skips it then;
+// it's reported for each page linking to it the same way as errors of the link check
+func (c *crawler) isTrap(ctx models.CrawlerContext) bool {
+	trap, details := c.traps.CheckUrl(ctx.Location)
+	if trap == "" {
+		return false
+	}
+	c.skipTrap(ctx, trap, details)
+	return true
+}
+
+func (c *crawler) skipTrap(ctx models.CrawlerContext, trap models.TrapKind, details string) {
+	c.observer.OnUrlSkipped(models.UrlSkippedEvent{
+		Url:     ctx.Location,
+		Depth:   ctx.Depth,
+		Reason:  models.SkipReasonTrap,
+		Details: details,
+		Trap:    trap,
+	})
+}
+
+// reserve marks URL as visited and returns *true* if it's not yet visited;
 // URL list is being locked while reading from and writing in
-func (c *crawler) addResult(url *models.Url) bool {
+func (c *crawler) reserve(ctx models.CrawlerContext) bool {
 	c.resultsLocker.Lock()
-	defer c.resultsLocker.Unlock()
+	visited := c.visited[ctx.Location]
+	c.visited[ctx.Location] = true
+	c.resultsLocker.Unlock()
 
-	if _, exists := c.urls[url.Location]; !exists {
-		c.urls[url.Location] = url
-		c.logger.Debug("Crawler: collected URL", utils.InJSON(url))
-		return true
-	} else {
-		c.logger.Debug("Crawler: URL already collected, skip it", utils.InJSON(url))
+	if visited {
+		c.skip(ctx, models.SkipReasonDuplicate, "")
 		return false
 	}
+	return true
+}
+
+// collectStart collects the start page when it's linked first time, the page isn't read again
+func (c *crawler) collectStart(link models.CrawlerContext) {
+	c.resultsLocker.Lock()
+	linked := c.startLinked
+	c.startLinked = true
+	c.resultsLocker.Unlock()
+
+	if linked {
+		c.skip(link, models.SkipReasonDuplicate, "")
+		return
+	}
+	if reason, details := c.policy.CheckPage(c.startPage); reason != "" {
+		c.skip(c.start, reason, details)
+		return
+	}
+	if c.dedup != nil {
+		if c.takeUrl(c.start) {
+			c.dedup.Add(c.start, c.startPage)
+		}
+		return
+	}
+	c.collect(c.start)
+}
+
+// takePage counts the page in the budget, returns *false* if it's exhausted
+func (c *crawler) takePage(ctx models.CrawlerContext) bool {
+	limit, perHost := c.tracker.takePage(ctx.Location)
+	if perHost {
+		c.skip(ctx, models.SkipReasonBudget, string(limit))
+	}
+	return limit == ""
+}
+
+// collect sends URL to the results, returns *false* if the budget is exhausted
+func (c *crawler) collect(ctx models.CrawlerContext) bool {
+	if !c.takeUrl(ctx) {
+		return false
+	}
+	c.send(ctx)
+	return true
+}
+
+// takeUrl counts URL in the budget, returns *false* if it's exhausted
+func (c *crawler) takeUrl(ctx models.CrawlerContext) bool {
+	limit, perHost := c.tracker.takeUrl(ctx.Location)
+	if perHost {
+		c.skip(ctx, models.SkipReasonBudget, string(limit))
+	}
+	return limit == ""
+}
+
+// collectDeduplicated sends the representatives of the duplicate groups to the results, the rest pages are skipped;
+// they're already counted in the budget
+func (c *crawler) collectDeduplicated() {
+	for _, cluster := range c.dedup.Clusters() {
+		c.send(cluster.Representative)
+
+		for _, d := range cluster.Duplicates {
+			details := fmt.Sprintf("near-duplicate (%d bits differ)", d.Distance)
+			if d.Exact {
+				details = "exact duplicate"
+			}
+			c.observer.OnUrlSkipped(models.UrlSkippedEvent{
+				Url:         d.Page.Location,
+				Depth:       d.Page.Depth,
+				Reason:      models.SkipReasonDuplicateContent,
+				Details:     details,
+				DuplicateOf: cluster.Representative.Location,
+			})
+		}
+	}
+}
+
+// send passes URL to the results
+func (c *crawler) send(ctx models.CrawlerContext) {
+	url := models.Url{
+		Location:     ctx.Location,
+		LastModified: ctx.LastModified,
+	}
+	c.results <- &url
+	c.observer.OnUrlCollected(models.UrlCollectedEvent{
+		Url:        url,
+		Depth:      ctx.Depth,
+		NotScanned: c.notScanned(ctx),
+	})
+}
+
+// notScanned tells why the links of the collected page are not scanned, if they're not
+func (c *crawler) notScanned(ctx models.CrawlerContext) models.NotScannedReason {
+	switch {
+	// the start page is always scanned
+	case ctx.Depth == 0:
+		return ""
+	case !ctx.IsHtml:
+		return models.NotScannedNotHtml
+	case ctx.Depth >= c.maxDepth:
+		return models.NotScannedTooDeep
+	default:
+		return ""
+	}
 }
@@ -1,7 +1,12 @@
 package crawlers_test
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"regexp"
 	"sitemap-generator/pkg/crawlers"
 	"sitemap-generator/pkg/crawlers/models"
 	"sitemap-generator/pkg/parsers"
@@ -10,7 +15,11 @@ import (
 	"sitemap-generator/pkg/workerPools"
 	"sitemap-generator/services"
 	"sitemap-generator/utils"
+	"sort"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestCrawler_Traverse(t *testing.T) {
@@ -76,6 +85,7 @@ func TestCrawler_Traverse(t *testing.T) {
 	logger, err := services.NewLogger(os.Stderr, "testing", "error")
 	utils.AssertNoError(t, err)
 
+	// the pool of the former API still can be used
 	wp := workerPools.NewWorkerPool(logger, 2)
 	parser := parsers.NewParser()
 
@@ -83,8 +93,8 @@ func TestCrawler_Traverse(t *testing.T) {
 		CheckUrl: func(url string) (readersModels.UrlInfo, error) {
 			return readersModels.UrlInfo{}, nil
 		},
-		ReadUrl: func(url string) ([]byte, error) {
-			return []byte(body), nil
+		ReadUrl: func(url string) (readersModels.Page, error) {
+			return readersModels.Page{Url: url, StatusCode: 200, Body: []byte(body)}, nil
 		},
 	})
 
@@ -100,3 +110,830 @@ func TestCrawler_Traverse(t *testing.T) {
 	utils.AssertNoError(t, err)
 	utils.AssertEqualSlices(t, urls, expectedUrls)
 }
+
+func TestCrawler_TraverseStream(t *testing.T) {
+	logger, err := services.NewLogger(os.Stderr, "testing", "error")
+	utils.AssertNoError(t, err)
+
+	pages := map[string]string{
+		"https://my-example.com/":       `<a href="/first">First</a>`,
+		"https://my-example.com/first":  `<a href="/second">Second</a>`,
+		"https://my-example.com/second": ``,
+	}
+	received := make(chan struct{})
+	reader := readers.NewReaderMock(readers.ReaderMockOptions{
+		CheckUrl: func(url string) (readersModels.UrlInfo, error) {
+			return readersModels.UrlInfo{StatusCode: 200, IsHtml: true}, nil
+		},
+		ReadUrl: func(url string) (readersModels.Page, error) {
+			// the second page is read only after the first one is received, so the crawl waits for the stream
+			if url == "https://my-example.com/second" {
+				select {
+				case <-received:
+				case <-time.After(5 * time.Second):
+					return readersModels.Page{}, fmt.Errorf("first URL is not received")
+				}
+			}
+			return readersModels.Page{Url: url, StatusCode: 200, Body: []byte(pages[url])}, nil
+		},
+	})
+
+	c := crawlers.NewCrawler(crawlers.CrawlerOptions{
+		MaxDepth: 3,
+		Logger:   logger,
+		TaskPool: workerPools.NewTypedWorkerPool[models.CrawlerContext](logger, workerPools.WorkerPoolOptions{WorkersCount: 2}),
+		Reader:   reader,
+		Parser:   parsers.NewParser(),
+	})
+
+	results := make(chan *models.Url)
+	traversed := make(chan error, 1)
+	go func() {
+		traversed <- c.TraverseStream("https://my-example.com/", results)
+	}()
+
+	first := <-results
+	utils.AssertEqual(t, first.Location, "https://my-example.com/first")
+	close(received)
+
+	urls := make([]string, 0)
+	for u := range results {
+		urls = append(urls, u.Location)
+	}
+	utils.AssertNoError(t, <-traversed)
+	utils.AssertEqualSlices(t, urls, []string{"https://my-example.com/second"})
+}
+
+type recordingObserver struct {
+	crawlers.NopObserver
+
+	fetched []string
+	// truncated are the fetched pages which bodies are truncated, with their read sizes
+	truncated map[string]int
+	links     []models.LinkDiscoveredEvent
+	collected []string
+	// notScanned are the collected URLs with the reason their links are not scanned
+	notScanned map[string]models.NotScannedReason
+	redirects  []models.RedirectEvent
+	skipped    []models.UrlSkippedEvent
+	errors     []string
+}
+
+func (ro *recordingObserver) OnPageFetched(e models.PageFetchedEvent) {
+	ro.fetched = append(ro.fetched, e.Url)
+	if e.Truncated != "" {
+		if ro.truncated == nil {
+			ro.truncated = make(map[string]int)
+		}
+		ro.truncated[e.Url] = e.BodySize
+	}
+}
+
+func (ro *recordingObserver) OnLinkDiscovered(e models.LinkDiscoveredEvent) {
+	ro.links = append(ro.links, e)
+}
+
+func (ro *recordingObserver) OnRedirect(e models.RedirectEvent) {
+	ro.redirects = append(ro.redirects, e)
+}
+
+func (ro *recordingObserver) OnUrlCollected(e models.UrlCollectedEvent) {
+	ro.collected = append(ro.collected, e.Url.Location)
+	if e.NotScanned != "" {
+		if ro.notScanned == nil {
+			ro.notScanned = make(map[string]models.NotScannedReason)
+		}
+		ro.notScanned[e.Url.Location] = e.NotScanned
+	}
+}
+
+func (ro *recordingObserver) OnUrlSkipped(e models.UrlSkippedEvent) {
+	ro.skipped = append(ro.skipped, e)
+}
+
+func (ro *recordingObserver) OnError(e models.ErrorEvent) {
+	ro.errors = append(ro.errors, e.Url)
+}
+
+func TestCrawler_Observers(t *testing.T) {
+	startUrl := "https://my-example.com/"
+	pages := map[string]string{
+		"https://my-example.com/":        `<a href="/faq.php">FAQ</a> <a href="/doc.pdf">Doc</a> <a href="/broken">Broken</a>`,
+		"https://my-example.com/faq.php": `<a href="/">Home</a> <a href="/deep.php">Deep</a>`,
+	}
+
+	logger, err := services.NewLogger(os.Stderr, "testing", "error")
+	utils.AssertNoError(t, err)
+
+	reader := readers.NewReaderMock(readers.ReaderMockOptions{
+		CheckUrl: func(url string) (readersModels.UrlInfo, error) {
+			if url == "https://my-example.com/broken" {
+				return readersModels.UrlInfo{}, fmt.Errorf("HTTP error [404]")
+			}
+			return readersModels.UrlInfo{IsHtml: !strings.HasSuffix(url, ".pdf")}, nil
+		},
+		ReadUrl: func(url string) (readersModels.Page, error) {
+			return readersModels.Page{Url: url, StatusCode: 200, Body: []byte(pages[url])}, nil
+		},
+	})
+
+	observer := &recordingObserver{}
+	c := crawlers.NewCrawler(crawlers.CrawlerOptions{
+		MaxDepth:  2,
+		Logger:    logger,
+		TaskPool:  workerPools.NewTypedWorkerPool[models.CrawlerContext](logger, workerPools.WorkerPoolOptions{WorkersCount: 2}),
+		Reader:    reader,
+		Parser:    parsers.NewParser(),
+		Observers: []crawlers.Observer{observer},
+	})
+
+	_, err = c.Traverse(startUrl)
+	utils.AssertNoError(t, err)
+
+	utils.AssertEqualSlices(t, observer.fetched, []string{
+		"https://my-example.com/",
+		"https://my-example.com/faq.php",
+	})
+	utils.AssertEqual(t, len(observer.links), 5)
+	utils.AssertEqual(t, observer.links[0], models.LinkDiscoveredEvent{
+		From:    "https://my-example.com/",
+		To:      "https://my-example.com/faq.php",
+		Element: "a",
+		Text:    "FAQ",
+	})
+	utils.AssertEqualSlices(t, observer.collected, []string{
+		"https://my-example.com/faq.php",
+		"https://my-example.com/doc.pdf",
+		"https://my-example.com/",
+		"https://my-example.com/deep.php",
+	})
+	// URLs which are collected are not skipped, even if their links are not scanned
+	utils.AssertEmpty(t, observer.skipped)
+	// the start page is collected when it's linked, but it's not read again
+	utils.AssertEqual(t, observer.notScanned, map[string]models.NotScannedReason{
+		"https://my-example.com/doc.pdf":  models.NotScannedNotHtml,
+		"https://my-example.com/deep.php": models.NotScannedTooDeep,
+	})
+	utils.AssertEqual(t, observer.errors, []string{"https://my-example.com/broken"})
+}
+
+func TestCrawler_StartPageLinked(t *testing.T) {
+	pages := map[string]string{
+		"https://my-example.com/":      `<a href="/">Home</a> <a href="/about">About</a> <a href="/broken">Broken</a>`,
+		"https://my-example.com/about": `<a href="/">Home</a>`,
+	}
+
+	logger, err := services.NewLogger(os.Stderr, "testing", "error")
+	utils.AssertNoError(t, err)
+
+	reader := readers.NewReaderMock(readers.ReaderMockOptions{
+		CheckUrl: func(url string) (readersModels.UrlInfo, error) {
+			if url == "https://my-example.com/broken" {
+				return readersModels.UrlInfo{}, fmt.Errorf("HTTP error [404]")
+			}
+			return readersModels.UrlInfo{StatusCode: 200, IsHtml: true}, nil
+		},
+		ReadUrl: func(url string) (readersModels.Page, error) {
+			return readersModels.Page{Url: url, StatusCode: 200, Body: []byte(pages[url])}, nil
+		},
+	})
+
+	observer := &recordingObserver{}
+	c := crawlers.NewCrawler(crawlers.CrawlerOptions{
+		MaxDepth:  3,
+		Logger:    logger,
+		TaskPool:  workerPools.NewTypedWorkerPool[models.CrawlerContext](logger, workerPools.WorkerPoolOptions{WorkersCount: 2}),
+		Reader:    reader,
+		Parser:    parsers.NewParser(),
+		Observers: []crawlers.Observer{observer},
+	})
+
+	// the start page is collected once it's linked, but it's read and scanned only once
+	urls, err := c.Traverse("https://my-example.com/")
+	utils.AssertNoError(t, err)
+	utils.AssertEqualSlices(t, urls, []*models.Url{
+		{Location: "https://my-example.com/"},
+		{Location: "https://my-example.com/about"},
+	})
+	utils.AssertEqualSlices(t, observer.fetched, []string{
+		"https://my-example.com/",
+		"https://my-example.com/about",
+	})
+	utils.AssertEqual(t, observer.errors, []string{"https://my-example.com/broken"})
+}
+
+func TestCrawler_Redirects(t *testing.T) {
+	startUrl := "https://my-example.com/"
+	body := `<a href="/old">Old</a> <a href="/new">New</a> <a href="/insecure">Insecure</a>`
+	redirects := map[string][]readersModels.Redirect{
+		"https://my-example.com/old": {
+			{StatusCode: 301, Location: "https://my-example.com/older"},
+			{StatusCode: 301, Location: "https://my-example.com/oldest"},
+			{StatusCode: 301, Location: "https://my-example.com/new"},
+		},
+		"https://my-example.com/insecure": {
+			{StatusCode: 302, Location: "http://my-example.com/insecure"},
+		},
+	}
+
+	logger, err := services.NewLogger(os.Stderr, "testing", "error")
+	utils.AssertNoError(t, err)
+
+	reader := readers.NewReaderMock(readers.ReaderMockOptions{
+		CheckUrl: func(url string) (readersModels.UrlInfo, error) {
+			info := readersModels.UrlInfo{FinalUrl: url, Redirects: redirects[url]}
+			if len(info.Redirects) > 0 {
+				info.FinalUrl = info.Redirects[len(info.Redirects)-1].Location
+			}
+			return info, nil
+		},
+		ReadUrl: func(url string) (readersModels.Page, error) {
+			return readersModels.Page{Url: url, StatusCode: 200, Body: []byte(body)}, nil
+		},
+	})
+
+	observer := &recordingObserver{}
+	c := crawlers.NewCrawler(crawlers.CrawlerOptions{
+		MaxDepth:  1,
+		Logger:    logger,
+		TaskPool:  workerPools.NewTypedWorkerPool[models.CrawlerContext](logger, workerPools.WorkerPoolOptions{WorkersCount: 2}),
+		Reader:    reader,
+		Parser:    parsers.NewParser(),
+		Observers: []crawlers.Observer{observer},
+	})
+
+	urls, err := c.Traverse(startUrl)
+	utils.AssertNoError(t, err)
+	utils.AssertEqualSlices(t, urls, []*models.Url{
+		{Location: "https://my-example.com/new"},
+		{Location: "http://my-example.com/insecure"},
+	})
+	utils.AssertEqualSlices(t, observer.redirects, []models.RedirectEvent{
+		{
+			From:      "https://my-example.com/old",
+			To:        "https://my-example.com/new",
+			Redirects: redirects["https://my-example.com/old"],
+			Issues:    []models.RedirectIssue{models.RedirectIssueLongChain},
+		},
+		{
+			From:      "https://my-example.com/insecure",
+			To:        "http://my-example.com/insecure",
+			Redirects: redirects["https://my-example.com/insecure"],
+			Issues:    []models.RedirectIssue{models.RedirectIssueDowngrade},
+		},
+	})
+}
+
+func TestCrawler_InclusionPolicy(t *testing.T) {
+	startUrl := "https://my-example.com/"
+	pages := map[string]string{
+		"https://my-example.com/":        `<a href="/moved">Moved</a> <a href="/gone">Gone</a> <a href="/faq.php">FAQ</a>`,
+		"https://my-example.com/faq.php": `<title>FAQ</title> <a href="/deep.php">Deep</a>`,
+		"https://my-example.com/gone":    `<title>Page not found</title>`,
+	}
+
+	logger, err := services.NewLogger(os.Stderr, "testing", "error")
+	utils.AssertNoError(t, err)
+
+	reader := readers.NewReaderMock(readers.ReaderMockOptions{
+		CheckUrl: func(url string) (readersModels.UrlInfo, error) {
+			if url == "https://my-example.com/moved" {
+				return readersModels.UrlInfo{StatusCode: 304}, nil
+			}
+			return readersModels.UrlInfo{StatusCode: 200, IsHtml: true}, nil
+		},
+		ReadUrl: func(url string) (readersModels.Page, error) {
+			return readersModels.Page{Url: url, StatusCode: 200, Body: []byte(pages[url])}, nil
+		},
+	})
+
+	observer := &recordingObserver{}
+	c := crawlers.NewCrawler(crawlers.CrawlerOptions{
+		MaxDepth:  2,
+		Logger:    logger,
+		TaskPool:  workerPools.NewTypedWorkerPool[models.CrawlerContext](logger, workerPools.WorkerPoolOptions{WorkersCount: 2}),
+		Reader:    reader,
+		Parser:    parsers.NewParser(),
+		Observers: []crawlers.Observer{observer},
+		InclusionPolicy: crawlers.NewInclusionPolicy(crawlers.InclusionPolicyOptions{
+			Soft404Titles: []*regexp.Regexp{regexp.MustCompile(`(?i)not found`)},
+		}),
+	})
+
+	urls, err := c.Traverse(startUrl)
+	utils.AssertNoError(t, err)
+	utils.AssertEqualSlices(t, urls, []*models.Url{
+		{Location: "https://my-example.com/faq.php"},
+		{Location: "https://my-example.com/deep.php"},
+	})
+	utils.AssertEqualSlices(t, observer.skipped, []models.UrlSkippedEvent{
+		{Url: "https://my-example.com/moved", Depth: 1, Reason: models.SkipReasonStatus, Details: "status 304"},
+		{Url: "https://my-example.com/gone", Depth: 1, Reason: models.SkipReasonSoft404, Details: "title matches (?i)not found"},
+	})
+	utils.AssertEqual(t, observer.notScanned, map[string]models.NotScannedReason{
+		"https://my-example.com/deep.php": models.NotScannedTooDeep,
+	})
+	// pages at max depth are read to check them for soft 404
+	utils.AssertEqualSlices(t, observer.fetched, []string{
+		"https://my-example.com/",
+		"https://my-example.com/gone",
+		"https://my-example.com/faq.php",
+		"https://my-example.com/deep.php",
+	})
+}
+
+// newTestSite serves HTML pages linking to each other and to a document, counting requests
+func newTestSite(pagesCount int, requests *int64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(requests, 1)
+
+		if strings.HasSuffix(r.URL.Path, ".pdf") {
+			w.Header().Set("Content-Type", "application/pdf")
+			return
+		}
+
+		var page int
+		if _, err := fmt.Sscanf(r.URL.Path, "/page-%d", &page); err != nil && r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		body := `<a href="/">Home</a> <a href="/doc.pdf">Doc</a>`
+		for i := 1; i <= 3; i++ {
+			body += fmt.Sprintf(` <a href="/page-%d">Page</a>`, (page+i)%pagesCount+1)
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+}
+
+func newTestReader() readers.Reader {
+	return readers.NewReader(readers.ReaderOptions{MaxRetries: 1, MaxRedirects: 3})
+}
+
+func crawlTestSite(t testing.TB, fetchMode crawlers.FetchMode, reader readers.Reader, srvUrl string) []*models.Url {
+	logger, err := services.NewLogger(os.Stderr, "testing", "error")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := crawlers.NewCrawler(crawlers.CrawlerOptions{
+		MaxDepth:  10,
+		Logger:    logger,
+		TaskPool:  workerPools.NewTypedWorkerPool[models.CrawlerContext](logger, workerPools.WorkerPoolOptions{WorkersCount: 4}),
+		Reader:    reader,
+		Parser:    parsers.NewParser(),
+		FetchMode: fetchMode,
+	})
+
+	urls, err := c.Traverse(srvUrl + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return urls
+}
+
+func TestCrawler_FetchModes(t *testing.T) {
+	pagesCount := 10
+
+	var headRequests, cachedRequests, getRequests int64
+	headSrv := newTestSite(pagesCount, &headRequests)
+	defer headSrv.Close()
+	cachedSrv := newTestSite(pagesCount, &cachedRequests)
+	defer cachedSrv.Close()
+	getSrv := newTestSite(pagesCount, &getRequests)
+	defer getSrv.Close()
+
+	headUrls := crawlTestSite(t, crawlers.FetchModeHead, newTestReader(), headSrv.URL)
+	cachedUrls := crawlTestSite(t, crawlers.FetchModeHead, readers.NewCachedReader(newTestReader(), readers.CachedReaderOptions{}), cachedSrv.URL)
+	getUrls := crawlTestSite(t, crawlers.FetchModeGet, newTestReader(), getSrv.URL)
+
+	// pages, the home page and the document
+	utils.AssertEqual(t, len(headUrls), pagesCount+2)
+	utils.AssertEqual(t, len(cachedUrls), pagesCount+2)
+	utils.AssertEqual(t, len(getUrls), pagesCount+2)
+
+	// a check and reading of each page, the start one is only read and the document is only checked
+	utils.AssertEqual(t, cachedRequests, int64(2*(pagesCount+1)))
+	utils.AssertTrue(t, headRequests > cachedRequests)
+
+	// a request per each URL, the start one is read once
+	utils.AssertEqual(t, getRequests, int64(pagesCount+2))
+}
+
+func TestCrawler_TruncatedPage(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<a href="/before">Before</a>` + strings.Repeat(" ", 2000) + `<a href="/after">After</a>`))
+	})
+	for _, path := range []string{"/before", "/after"} {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+		})
+	}
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	logger, err := services.NewLogger(os.Stderr, "testing", "error")
+	utils.AssertNoError(t, err)
+
+	for _, fetchMode := range []crawlers.FetchMode{crawlers.FetchModeHead, crawlers.FetchModeGet} {
+		t.Run(string(fetchMode), func(t *testing.T) {
+			observer := &recordingObserver{}
+			c := crawlers.NewCrawler(crawlers.CrawlerOptions{
+				MaxDepth:  2,
+				Logger:    logger,
+				TaskPool:  workerPools.NewTypedWorkerPool[models.CrawlerContext](logger, workerPools.WorkerPoolOptions{WorkersCount: 2}),
+				Reader:    readers.NewReader(readers.ReaderOptions{MaxRetries: 1, MaxBodySize: 500}),
+				Parser:    parsers.NewParser(),
+				FetchMode: fetchMode,
+				Observers: []crawlers.Observer{observer},
+			})
+
+			// links before the cutoff are scanned while the body is being read
+			urls, err := c.Traverse(srv.URL + "/")
+			utils.AssertNoError(t, err)
+			utils.AssertEqualSlices(t, urls, []*models.Url{{Location: srv.URL + "/before"}})
+			utils.AssertEqual(t, observer.truncated, map[string]int{srv.URL + "/": 500})
+		})
+	}
+}
+
+func TestCrawler_Frontier(t *testing.T) {
+	logger, err := services.NewLogger(os.Stderr, "testing", "error")
+	utils.AssertNoError(t, err)
+
+	pagesCount := 10
+	orders := []crawlers.FrontierOrder{
+		crawlers.FrontierOrderFifo, crawlers.FrontierOrderBreadthFirst, crawlers.FrontierOrderBestFirst, crawlers.FrontierOrderRoundRobin,
+	}
+	for _, order := range orders {
+		t.Run(string(order), func(t *testing.T) {
+			var requests int64
+			srv := newTestSite(pagesCount, &requests)
+			defer srv.Close()
+
+			frontier := crawlers.NewFrontier(crawlers.FrontierOptions{Order: order})
+			c := crawlers.NewCrawler(crawlers.CrawlerOptions{
+				MaxDepth:  10,
+				Logger:    logger,
+				TaskPool:  workerPools.NewQueuedWorkerPool[models.CrawlerContext](logger, workerPools.WorkerPoolOptions{WorkersCount: 3}, frontier),
+				Reader:    newTestReader(),
+				Parser:    parsers.NewParser(),
+				Observers: []crawlers.Observer{frontier},
+			})
+
+			urls, err := c.Traverse(srv.URL + "/")
+			utils.AssertNoError(t, err)
+			utils.AssertEqual(t, len(urls), pagesCount+2)
+
+			_, ok := frontier.Pop()
+			utils.AssertFalse(t, ok)
+		})
+	}
+}
+
+func TestCrawler_Budget(t *testing.T) {
+	logger, err := services.NewLogger(os.Stderr, "testing", "error")
+	utils.AssertNoError(t, err)
+
+	pagesCount := 10
+	crawl := func(budget crawlers.Budget, observer crawlers.Observer) ([]*models.Url, error) {
+		var requests int64
+		srv := newTestSite(pagesCount, &requests)
+		defer srv.Close()
+
+		c := crawlers.NewCrawler(crawlers.CrawlerOptions{
+			MaxDepth:  10,
+			Logger:    logger,
+			TaskPool:  workerPools.NewTypedWorkerPool[models.CrawlerContext](logger, workerPools.WorkerPoolOptions{WorkersCount: 3}),
+			Reader:    newTestReader(),
+			Parser:    parsers.NewParser(),
+			Observers: []crawlers.Observer{observer},
+			Budget:    budget,
+		})
+		return c.Traverse(srv.URL + "/")
+	}
+
+	t.Run("max URLs", func(t *testing.T) {
+		urls, err := crawl(crawlers.Budget{MaxUrls: 3}, crawlers.NopObserver{})
+		utils.AssertHasError(t, err, "budget is exhausted: max-urls")
+		utils.AssertEqual(t, err.(*crawlers.BudgetExhaustedError).Limit, crawlers.BudgetLimitUrls)
+		utils.AssertEqual(t, len(urls), 3)
+	})
+
+	t.Run("max pages", func(t *testing.T) {
+		observer := &recordingObserver{}
+		urls, err := crawl(crawlers.Budget{MaxPages: 4}, observer)
+		utils.AssertHasError(t, err, "budget is exhausted: max-pages")
+		utils.AssertEqual(t, len(observer.fetched), 4)
+		utils.AssertTrue(t, len(urls) < pagesCount+2)
+	})
+
+	t.Run("max pages per host", func(t *testing.T) {
+		observer := &recordingObserver{}
+		urls, err := crawl(crawlers.Budget{PerHost: crawlers.HostBudget{MaxPages: 4}}, observer)
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, len(observer.fetched), 4)
+		utils.AssertTrue(t, len(urls) < pagesCount+2)
+
+		skipped := 0
+		for _, e := range observer.skipped {
+			if e.Reason == models.SkipReasonBudget {
+				utils.AssertEqual(t, e.Details, string(crawlers.BudgetLimitPages))
+				skipped++
+			}
+		}
+		utils.AssertTrue(t, skipped > 0)
+	})
+
+	t.Run("not exhausted", func(t *testing.T) {
+		urls, err := crawl(crawlers.Budget{MaxUrls: pagesCount + 2, MaxBytes: 1 << 20, MaxDuration: time.Minute}, crawlers.NopObserver{})
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, len(urls), pagesCount+2)
+	})
+}
+
+func TestCrawler_Progress(t *testing.T) {
+	logger, err := services.NewLogger(os.Stderr, "testing", "error")
+	utils.AssertNoError(t, err)
+
+	var requests int64
+	srv := newTestSite(10, &requests)
+	defer srv.Close()
+
+	observer := &recordingObserver{}
+	c := crawlers.NewCrawler(crawlers.CrawlerOptions{
+		MaxDepth:  10,
+		Logger:    logger,
+		TaskPool:  workerPools.NewTypedWorkerPool[models.CrawlerContext](logger, workerPools.WorkerPoolOptions{WorkersCount: 3}),
+		Reader:    newTestReader(),
+		Parser:    parsers.NewParser(),
+		Observers: []crawlers.Observer{observer},
+	})
+	utils.AssertEqual(t, c.Progress(), models.Progress{Workers: 3})
+
+	urls, err := c.Traverse(srv.URL + "/")
+	utils.AssertNoError(t, err)
+	utils.AssertEqual(t, c.Progress(), models.Progress{
+		Workers:       3,
+		PagesFetched:  len(observer.fetched),
+		UrlsCollected: len(urls),
+		Errors:        len(observer.errors),
+	})
+}
+
+func TestCrawler_Traps(t *testing.T) {
+	logger, err := services.NewLogger(os.Stderr, "testing", "error")
+	utils.AssertNoError(t, err)
+
+	// each page links to the deeper one infinitely
+	reader := readers.NewReaderMock(readers.ReaderMockOptions{
+		CheckUrl: func(url string) (readersModels.UrlInfo, error) {
+			return readersModels.UrlInfo{StatusCode: 200, IsHtml: true}, nil
+		},
+		ReadUrl: func(url string) (readersModels.Page, error) {
+			return readersModels.Page{Url: url, StatusCode: 200, Body: []byte(`<a href="a/">Deeper</a> <a href="/?sid=1">Home</a>`)}, nil
+		},
+	})
+
+	observer := &recordingObserver{}
+	c := crawlers.NewCrawler(crawlers.CrawlerOptions{
+		MaxDepth:  10,
+		Logger:    logger,
+		TaskPool:  workerPools.NewTypedWorkerPool[models.CrawlerContext](logger, workerPools.WorkerPoolOptions{WorkersCount: 2}),
+		Reader:    reader,
+		Parser:    parsers.NewParser(),
+		Observers: []crawlers.Observer{observer},
+		TrapDetector: crawlers.NewTrapDetector(crawlers.TrapDetectorOptions{
+			MaxSegmentRepeats: 2,
+			SessionParams:     crawlers.DefaultSessionParams,
+		}),
+	})
+
+	urls, err := c.Traverse("https://my-example.com/")
+	utils.AssertNoError(t, err)
+	utils.AssertEqualSlices(t, urls, []*models.Url{
+		{Location: "https://my-example.com/a/"},
+		{Location: "https://my-example.com/a/a/"},
+	})
+
+	// the trap is reported for each page linking to it, first time by the start page
+	traps := make(map[string]models.UrlSkippedEvent)
+	for _, e := range observer.skipped {
+		if _, exists := traps[e.Url]; !exists && e.Reason == models.SkipReasonTrap {
+			traps[e.Url] = e
+		}
+	}
+	utils.AssertEqual(t, traps, map[string]models.UrlSkippedEvent{
+		"https://my-example.com/?sid=1": {
+			Url: "https://my-example.com/?sid=1", Depth: 1, Reason: models.SkipReasonTrap, Details: "parameter sid", Trap: models.TrapSessionId,
+		},
+		"https://my-example.com/a/a/a/": {
+			Url: "https://my-example.com/a/a/a/", Depth: 3, Reason: models.SkipReasonTrap, Details: "segment a repeats 3 times", Trap: models.TrapRepeatedSegments,
+		},
+	})
+}
+
+func TestCrawler_Deduplicator(t *testing.T) {
+	logger, err := services.NewLogger(os.Stderr, "testing", "error")
+	utils.AssertNoError(t, err)
+
+	pages := map[string]string{
+		"https://my-example.com/":               `<a href="/guide?ref=home">Guide</a> <a href="/print/guide">Print</a> <a href="/faq">FAQ</a>`,
+		"https://my-example.com/guide?ref=home": `<main>How to start</main> <a href="/guide">Guide</a>`,
+		"https://my-example.com/guide":          `<main>How to start</main> <a href="/guide">Guide</a>`,
+		"https://my-example.com/print/guide":    `<main>How to start</main> <a href="/guide">Guide</a>`,
+		"https://my-example.com/faq":            `<main>Questions and answers</main>`,
+	}
+	reader := readers.NewReaderMock(readers.ReaderMockOptions{
+		CheckUrl: func(url string) (readersModels.UrlInfo, error) {
+			return readersModels.UrlInfo{StatusCode: 200, IsHtml: true}, nil
+		},
+		ReadUrl: func(url string) (readersModels.Page, error) {
+			return readersModels.Page{Url: url, StatusCode: 200, Body: []byte(pages[url])}, nil
+		},
+	})
+
+	observer := &recordingObserver{}
+	c := crawlers.NewCrawler(crawlers.CrawlerOptions{
+		MaxDepth:     3,
+		Logger:       logger,
+		TaskPool:     workerPools.NewTypedWorkerPool[models.CrawlerContext](logger, workerPools.WorkerPoolOptions{WorkersCount: 2}),
+		Reader:       reader,
+		Parser:       parsers.NewParser(),
+		Observers:    []crawlers.Observer{observer},
+		Deduplicator: crawlers.NewDeduplicator(crawlers.DeduplicatorOptions{}),
+	})
+
+	urls, err := c.Traverse("https://my-example.com/")
+	utils.AssertNoError(t, err)
+	utils.AssertEqualSlices(t, urls, []*models.Url{
+		{Location: "https://my-example.com/guide"},
+		{Location: "https://my-example.com/faq"},
+	})
+
+	duplicates := make([]models.UrlSkippedEvent, 0)
+	for _, e := range observer.skipped {
+		if e.Reason == models.SkipReasonDuplicateContent {
+			duplicates = append(duplicates, e)
+		}
+	}
+	utils.AssertEqualSlices(t, duplicates, []models.UrlSkippedEvent{
+		{
+			Url: "https://my-example.com/guide?ref=home", Depth: 1, Reason: models.SkipReasonDuplicateContent,
+			Details: "exact duplicate", DuplicateOf: "https://my-example.com/guide",
+		},
+		{
+			Url: "https://my-example.com/print/guide", Depth: 1, Reason: models.SkipReasonDuplicateContent,
+			Details: "exact duplicate", DuplicateOf: "https://my-example.com/guide",
+		},
+	})
+}
+
+func TestCrawler_DeduplicatorAtMaxDepth(t *testing.T) {
+	logger, err := services.NewLogger(os.Stderr, "testing", "error")
+	utils.AssertNoError(t, err)
+
+	pages := map[string]string{
+		"https://my-example.com/":               `<a href="/guide?ref=home">Guide</a> <a href="/guide">Guide</a> <a href="/faq">FAQ</a>`,
+		"https://my-example.com/guide?ref=home": `<main>How to start</main>`,
+		"https://my-example.com/guide":          `<main>How to start</main>`,
+		"https://my-example.com/faq":            `<main>Questions and answers</main>`,
+	}
+	reader := readers.NewReaderMock(readers.ReaderMockOptions{
+		CheckUrl: func(url string) (readersModels.UrlInfo, error) {
+			return readersModels.UrlInfo{StatusCode: 200, IsHtml: true}, nil
+		},
+		ReadUrl: func(url string) (readersModels.Page, error) {
+			return readersModels.Page{Url: url, StatusCode: 200, Body: []byte(pages[url])}, nil
+		},
+	})
+
+	// the pages at max depth aren't scanned for links, but they're still read to be deduplicated
+	c := crawlers.NewCrawler(crawlers.CrawlerOptions{
+		MaxDepth:     1,
+		Logger:       logger,
+		TaskPool:     workerPools.NewTypedWorkerPool[models.CrawlerContext](logger, workerPools.WorkerPoolOptions{WorkersCount: 2}),
+		Reader:       reader,
+		Parser:       parsers.NewParser(),
+		Deduplicator: crawlers.NewDeduplicator(crawlers.DeduplicatorOptions{}),
+	})
+
+	urls, err := c.Traverse("https://my-example.com/")
+	utils.AssertNoError(t, err)
+	utils.AssertEqualSlices(t, urls, []*models.Url{
+		{Location: "https://my-example.com/guide"},
+		{Location: "https://my-example.com/faq"},
+	})
+}
+
+func BenchmarkCrawler_FetchModes(b *testing.B) {
+	modes := []struct {
+		name   string
+		mode   crawlers.FetchMode
+		cached bool
+	}{
+		{name: "head", mode: crawlers.FetchModeHead},
+		{name: "head-cached", mode: crawlers.FetchModeHead, cached: true},
+		{name: "get", mode: crawlers.FetchModeGet},
+	}
+	for _, m := range modes {
+		b.Run(m.name, func(b *testing.B) {
+			var requests int64
+			srv := newTestSite(50, &requests)
+			defer srv.Close()
+
+			for i := 0; i < b.N; i++ {
+				reader := newTestReader()
+				if m.cached {
+					reader = readers.NewCachedReader(reader, readers.CachedReaderOptions{})
+				}
+				crawlTestSite(b, m.mode, reader, srv.URL)
+			}
+			b.ReportMetric(float64(requests)/float64(b.N), "requests/op")
+		})
+	}
+}
+
+func TestCrawler_Replay(t *testing.T) {
+	f, err := os.Open("testdata/site.har")
+	utils.AssertNoError(t, err)
+	defer f.Close()
+	har, err := readers.ReadHar(f)
+	utils.AssertNoError(t, err)
+
+	observer := &recordingObserver{}
+	logger, _ := services.NewLogger(os.Stderr, "testing", "error")
+	c := crawlers.NewCrawler(crawlers.CrawlerOptions{
+		MaxDepth:  3,
+		Logger:    logger,
+		TaskPool:  workerPools.NewTypedWorkerPool[models.CrawlerContext](logger, workerPools.WorkerPoolOptions{WorkersCount: 2}),
+		Reader:    readers.NewReplayReader(har, readers.ReaderOptions{MaxRetries: 1, MaxRedirects: 3}),
+		Parser:    parsers.NewParser(),
+		Observers: []crawlers.Observer{observer},
+	})
+
+	urls, err := c.Traverse("https://example.com/")
+	utils.AssertNoError(t, err)
+
+	locations := make([]string, len(urls))
+	for i, u := range urls {
+		locations[i] = u.Location
+	}
+	utils.AssertEqualSlices(t, locations, []string{
+		"https://example.com/",
+		"https://example.com/about/",
+		"https://example.com/doc.pdf",
+	})
+
+	// the start page is scanned again when it's linked from other ones
+	utils.AssertEqual(t, utils.StringSliceUnique(observer.errors), []string{"https://example.com/missing"})
+}
+
+func TestCrawler_FileReader(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"index.html":       `<a href="/about/">About</a> <a href="https://other.com/">Other</a>`,
+		"about/index.html": `<a href="/">Home</a>`,
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		utils.AssertNoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		utils.AssertNoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	logger, _ := services.NewLogger(os.Stderr, "testing", "error")
+
+	for _, fetchMode := range []crawlers.FetchMode{crawlers.FetchModeHead, crawlers.FetchModeGet} {
+		t.Run(string(fetchMode), func(t *testing.T) {
+			reader, err := readers.NewFileReader("https://example.com/", root, readers.ReaderOptions{MaxRetries: 3, MaxRedirects: 3})
+			utils.AssertNoError(t, err)
+
+			observer := &recordingObserver{}
+			c := crawlers.NewCrawler(crawlers.CrawlerOptions{
+				MaxDepth:  3,
+				Logger:    logger,
+				TaskPool:  workerPools.NewTypedWorkerPool[models.CrawlerContext](logger, workerPools.WorkerPoolOptions{WorkersCount: 2}),
+				Reader:    reader,
+				Parser:    parsers.NewParser(),
+				FetchMode: fetchMode,
+				Observers: []crawlers.Observer{observer},
+			})
+
+			// links to other sites are not broken, they just can't be checked offline
+			urls, err := c.Traverse("https://example.com/")
+			utils.AssertNoError(t, err)
+			locations := make([]string, len(urls))
+			for i, u := range urls {
+				locations[i] = u.Location
+			}
+			sort.Strings(locations)
+			utils.AssertEqualSlices(t, locations, []string{"https://example.com/", "https://example.com/about/"})
+			utils.AssertEmpty(t, observer.errors)
+			utils.AssertEqual(t, len(observer.skipped), 1)
+			utils.AssertEqual(t, observer.skipped[0].Url, "https://other.com/")
+			utils.AssertEqual(t, observer.skipped[0].Reason, models.SkipReasonOffline)
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package crawlers
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sitemap-generator/pkg/crawlers/models"
+	"sitemap-generator/pkg/parsers"
+	readersModels "sitemap-generator/pkg/readers/models"
+	"sitemap-generator/utils"
+	"sort"
+	"sync"
+)
+
+// Deduplicator groups pages serving the same content (print views, tracking parameters, mirrored paths etc.),
+// so only one page of the group gets into the results
+type Deduplicator interface {
+	// Add keeps the read page to compare it with others
+	Add(ctx models.CrawlerContext, page readersModels.Page)
+	// Clusters returns all the added pages grouped with their duplicates, unique pages are groups of their own
+	Clusters() []models.DuplicateCluster
+}
+
+// MaxFingerprintDistance is the largest number of different bits of 64-bit fingerprints of similar pages
+const MaxFingerprintDistance = 63
+
+// ValidateFingerprintDistance checks if the distance of fingerprints of similar pages is from 0 to MaxFingerprintDistance
+func ValidateFingerprintDistance(distance int) error {
+	if distance < 0 || distance > MaxFingerprintDistance {
+		return fmt.Errorf("distance should be from 0 to %d bits: %d", MaxFingerprintDistance, distance)
+	}
+	return nil
+}
+
+type DeduplicatorOptions struct {
+	// NearDuplicateDistance is max number of different bits of SimHash fingerprints (of 64) of pages
+	// considered duplicates, 0 for exact duplicates only
+	NearDuplicateDistance int
+}
+
+type dedupPage struct {
+	ctx         models.CrawlerContext
+	canonical   string
+	hash        [sha256.Size]byte
+	fingerprint uint64
+	// unique pages (e.g. without text) are never compared with others
+	unique bool
+}
+
+type dedupCluster struct {
+	pages []*dedupPage
+}
+
+type deduplicator struct {
+	distance int
+	parser   parsers.Parser
+	// bands are the parts of the fingerprint, the fingerprints differing in distance bits
+	// have at least one band the same, so only clusters sharing some band are compared
+	bands int
+
+	locker   sync.Mutex
+	clusters []*dedupCluster
+	byHash   map[[sha256.Size]byte]int
+	byBand   []map[uint64][]int
+}
+
+func NewDeduplicator(opts DeduplicatorOptions) Deduplicator {
+	distance := opts.NearDuplicateDistance
+	if distance < 0 {
+		distance = 0
+	}
+	if distance > MaxFingerprintDistance {
+		distance = MaxFingerprintDistance
+	}
+
+	bands := distance + 1
+	byBand := make([]map[uint64][]int, bands)
+	for i := range byBand {
+		byBand[i] = make(map[uint64][]int)
+	}
+
+	return &deduplicator{
+		distance: distance,
+		parser:   parsers.NewParser(),
+		bands:    bands,
+		clusters: make([]*dedupCluster, 0),
+		byHash:   make(map[[sha256.Size]byte]int),
+		byBand:   byBand,
+	}
+}
+
+func (d *deduplicator) Add(ctx models.CrawlerContext, page readersModels.Page) {
+	contentType := page.Header.Get("Content-Type")
+	text := d.parser.ParseHtmlText(contentType, page.Body)
+	p := &dedupPage{
+		ctx:       ctx,
+		canonical: d.parser.ParseHtmlCanonical(ctx.Location, contentType, page.Body),
+		unique:    text == "",
+	}
+	if !p.unique {
+		p.hash = sha256.Sum256([]byte(text))
+		p.fingerprint = utils.SimHash(text)
+	}
+
+	d.locker.Lock()
+	defer d.locker.Unlock()
+
+	if p.unique {
+		d.clusters = append(d.clusters, &dedupCluster{pages: []*dedupPage{p}})
+		return
+	}
+	if i, ok := d.byHash[p.hash]; ok {
+		d.clusters[i].pages = append(d.clusters[i].pages, p)
+		return
+	}
+	if d.distance > 0 {
+		for _, i := range d.candidates(p.fingerprint) {
+			if utils.HammingDistance(d.clusters[i].pages[0].fingerprint, p.fingerprint) <= d.distance {
+				d.clusters[i].pages = append(d.clusters[i].pages, p)
+				d.byHash[p.hash] = i
+				return
+			}
+		}
+	}
+
+	i := len(d.clusters)
+	d.clusters = append(d.clusters, &dedupCluster{pages: []*dedupPage{p}})
+	d.byHash[p.hash] = i
+	if d.distance > 0 {
+		for band := 0; band < d.bands; band++ {
+			key := d.band(p.fingerprint, band)
+			d.byBand[band][key] = append(d.byBand[band][key], i)
+		}
+	}
+}
+
+// candidates returns clusters sharing some band of the fingerprint, it's called under the lock
+func (d *deduplicator) candidates(fingerprint uint64) []int {
+	seen := make(map[int]bool)
+	candidates := make([]int, 0)
+	for band := 0; band < d.bands; band++ {
+		for _, i := range d.byBand[band][d.band(fingerprint, band)] {
+			if !seen[i] {
+				seen[i] = true
+				candidates = append(candidates, i)
+			}
+		}
+	}
+	sort.Ints(candidates)
+	return candidates
+}
+
+// band returns the bits of the band, the last band takes the rest bits
+func (d *deduplicator) band(fingerprint uint64, band int) uint64 {
+	width := 64 / d.bands
+	v := fingerprint >> (band * width)
+	if band == d.bands-1 {
+		return v
+	}
+	return v & (1<<width - 1)
+}
+
+func (d *deduplicator) Clusters() []models.DuplicateCluster {
+	d.locker.Lock()
+	defer d.locker.Unlock()
+
+	result := make([]models.DuplicateCluster, 0, len(d.clusters))
+	for _, c := range d.clusters {
+		representative := representativeOf(c.pages)
+		cluster := models.DuplicateCluster{
+			Representative: representative.ctx,
+			Duplicates:     make([]models.Duplicate, 0, len(c.pages)-1),
+		}
+		for _, p := range c.pages {
+			if p == representative {
+				continue
+			}
+			cluster.Duplicates = append(cluster.Duplicates, models.Duplicate{
+				Page:     p.ctx,
+				Exact:    p.hash == representative.hash,
+				Distance: utils.HammingDistance(p.fingerprint, representative.fingerprint),
+			})
+		}
+		result = append(result, cluster)
+	}
+	return result
+}
+
+// representativeOf chooses the page declared canonical by the pages of the group, otherwise the one with the shortest URL
+func representativeOf(pages []*dedupPage) *dedupPage {
+	canonicals := make(map[string]bool)
+	for _, p := range pages {
+		if p.canonical != "" {
+			canonicals[p.canonical] = true
+		}
+	}
+
+	var best *dedupPage
+	for _, p := range pages {
+		if best == nil || isBetterRepresentative(p, best, canonicals) {
+			best = p
+		}
+	}
+	return best
+}
+
+func isBetterRepresentative(p, than *dedupPage, canonicals map[string]bool) bool {
+	if canonicals[p.ctx.Location] != canonicals[than.ctx.Location] {
+		return canonicals[p.ctx.Location]
+	}
+	if len(p.ctx.Location) != len(than.ctx.Location) {
+		return len(p.ctx.Location) < len(than.ctx.Location)
+	}
+	return p.ctx.Location < than.ctx.Location
+}
@@ -0,0 +1,73 @@
+package crawlers_test
+
+import (
+	"net/http"
+	"sitemap-generator/pkg/crawlers"
+	"sitemap-generator/pkg/crawlers/models"
+	readersModels "sitemap-generator/pkg/readers/models"
+	"sitemap-generator/utils"
+	"testing"
+)
+
+func TestDeduplicator_Clusters(t *testing.T) {
+	page := func(head, text string) readersModels.Page {
+		return readersModels.Page{
+			StatusCode: 200,
+			Header:     http.Header{"Content-Type": {"text/html"}},
+			Body:       []byte("<html><head>" + head + "</head><body><nav>Menu</nav><main>" + text + "</main></body></html>"),
+		}
+	}
+	article := "The quick brown fox jumps over the lazy dog while the farmer watches from the porch of the old house."
+	// a word replaced in the long text changes only a few bits of the fingerprint
+	edited := "The quick brown fox jumps over the lazy dog while the farmer watches from the porch of the new house."
+	other := "Prices of the subscription plans for teams and enterprises with the comparison of their features."
+
+	add := func(d crawlers.Deduplicator) {
+		canonical := `<link rel="canonical" href="https://example.com/articles/fox">`
+		d.Add(models.CrawlerContext{Location: "https://example.com/articles/fox?ref=home", Depth: 1}, page(canonical, article))
+		d.Add(models.CrawlerContext{Location: "https://example.com/pricing", Depth: 1}, page("", other))
+		d.Add(models.CrawlerContext{Location: "https://example.com/articles/fox", Depth: 2}, page(canonical, article))
+		d.Add(models.CrawlerContext{Location: "https://example.com/fox", Depth: 2}, page("", article))
+		d.Add(models.CrawlerContext{Location: "https://example.com/print/fox", Depth: 2}, page("", edited))
+		d.Add(models.CrawlerContext{Location: "https://example.com/empty", Depth: 2}, page("", ""))
+		d.Add(models.CrawlerContext{Location: "https://example.com/blank", Depth: 2}, page("", ""))
+	}
+
+	t.Run("exact duplicates", func(t *testing.T) {
+		d := crawlers.NewDeduplicator(crawlers.DeduplicatorOptions{})
+		add(d)
+
+		clusters := d.Clusters()
+		utils.AssertEqual(t, len(clusters), 5)
+		utils.AssertEqual(t, clusters[0].Representative.Location, "https://example.com/articles/fox")
+		utils.AssertEqual(t, len(clusters[0].Duplicates), 2)
+		utils.AssertEqual(t, clusters[0].Duplicates[0].Page.Location, "https://example.com/articles/fox?ref=home")
+		utils.AssertTrue(t, clusters[0].Duplicates[0].Exact)
+		utils.AssertEqual(t, clusters[0].Duplicates[1].Page.Location, "https://example.com/fox")
+
+		// pages without text are not duplicates of each other
+		utils.AssertEqual(t, clusters[3].Representative.Location, "https://example.com/empty")
+		utils.AssertEqual(t, clusters[4].Representative.Location, "https://example.com/blank")
+	})
+
+	t.Run("near duplicates", func(t *testing.T) {
+		d := crawlers.NewDeduplicator(crawlers.DeduplicatorOptions{NearDuplicateDistance: 10})
+		add(d)
+
+		clusters := d.Clusters()
+		utils.AssertEqual(t, len(clusters), 4)
+		utils.AssertEqual(t, len(clusters[0].Duplicates), 3)
+		near := clusters[0].Duplicates[2]
+		utils.AssertEqual(t, near.Page.Location, "https://example.com/print/fox")
+		utils.AssertFalse(t, near.Exact)
+		utils.AssertTrue(t, near.Distance > 0 && near.Distance <= 10)
+		utils.AssertEqual(t, clusters[1].Representative.Location, "https://example.com/pricing")
+	})
+}
+
+func TestValidateFingerprintDistance(t *testing.T) {
+	utils.AssertNoError(t, crawlers.ValidateFingerprintDistance(0))
+	utils.AssertNoError(t, crawlers.ValidateFingerprintDistance(63))
+	utils.AssertHasError(t, crawlers.ValidateFingerprintDistance(64), "distance should be from 0 to 63 bits: 64")
+	utils.AssertHasError(t, crawlers.ValidateFingerprintDistance(-1), "distance should be from 0 to 63 bits: -1")
+}
@@ -0,0 +1,282 @@
+package crawlers
+
+import (
+	"container/heap"
+	"fmt"
+	"regexp"
+	"sitemap-generator/pkg/crawlers/models"
+	"sitemap-generator/pkg/workerPools"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type FrontierOrder string
+
+const (
+	// FrontierOrderFifo reads URLs in the order they're found
+	FrontierOrderFifo FrontierOrder = "fifo"
+	// FrontierOrderBreadthFirst reads shallow URLs first
+	FrontierOrderBreadthFirst FrontierOrder = "breadth-first"
+	// FrontierOrderBestFirst reads URLs with the highest score first:
+	// number of links to the URL found so far plus weights of the patterns it matches
+	FrontierOrderBestFirst FrontierOrder = "best-first"
+	// FrontierOrderRoundRobin reads URLs of each host in turn, shallow ones first within the host
+	FrontierOrderRoundRobin FrontierOrder = "round-robin"
+)
+
+// PatternWeight is added to the score of URLs matching the pattern, negative one lowers it
+type PatternWeight struct {
+	Pattern *regexp.Regexp
+	Weight  float64
+}
+
+type FrontierOptions struct {
+	// Order is how URLs are taken, FrontierOrderBreadthFirst by default
+	Order          FrontierOrder
+	PatternWeights []PatternWeight
+}
+
+// Frontier keeps URLs waiting to be read and decides which one is read next, so a crawl which is cut short
+// keeps the most valuable pages; it's the queue of the worker pool (see workerPools.NewQueuedWorkerPool)
+// and it should be among the crawler's observers to count links to the URLs
+type Frontier interface {
+	workerPools.Queue[models.CrawlerContext]
+	Observer
+}
+
+func NewFrontier(opts FrontierOptions) Frontier {
+	switch opts.Order {
+	case FrontierOrderRoundRobin:
+		return &roundRobinFrontier{
+			hosts: make(map[string]*frontierHeap),
+			ring:  make([]string, 0),
+		}
+	case FrontierOrderFifo:
+		return newPriorityFrontier(opts, fifoLess)
+	case FrontierOrderBestFirst:
+		return newPriorityFrontier(opts, bestFirstLess)
+	default:
+		return newPriorityFrontier(opts, breadthFirstLess)
+	}
+}
+
+// ParseFrontierOrder checks the name of the order
+func ParseFrontierOrder(v string) (FrontierOrder, error) {
+	order := FrontierOrder(strings.ToLower(strings.TrimSpace(v)))
+	switch order {
+	case FrontierOrderFifo, FrontierOrderBreadthFirst, FrontierOrderBestFirst, FrontierOrderRoundRobin:
+		return order, nil
+	default:
+		return "", fmt.Errorf("invalid frontier order: %s", v)
+	}
+}
+
+// ParsePatternWeight parses the weight of URL pattern in regexp=weight form, e.g. "/blog/=-2"
+func ParsePatternWeight(v string) (PatternWeight, error) {
+	i := strings.LastIndex(v, "=")
+	if i <= 0 {
+		return PatternWeight{}, fmt.Errorf("regexp=weight expected: %s", v)
+	}
+	pattern, err := regexp.Compile(v[:i])
+	if err != nil {
+		return PatternWeight{}, fmt.Errorf("invalid pattern of %s: %s", v, err.Error())
+	}
+	weight, err := strconv.ParseFloat(strings.TrimSpace(v[i+1:]), 64)
+	if err != nil {
+		return PatternWeight{}, fmt.Errorf("invalid weight of %s: %s", v, err.Error())
+	}
+	return PatternWeight{Pattern: pattern, Weight: weight}, nil
+}
+
+type frontierEntry struct {
+	ctx   models.CrawlerContext
+	seq   uint64
+	score float64
+	index int
+}
+
+func fifoLess(a, b *frontierEntry) bool {
+	return a.seq < b.seq
+}
+
+func breadthFirstLess(a, b *frontierEntry) bool {
+	if a.ctx.Depth != b.ctx.Depth {
+		return a.ctx.Depth < b.ctx.Depth
+	}
+	return a.seq < b.seq
+}
+
+func bestFirstLess(a, b *frontierEntry) bool {
+	if a.score != b.score {
+		return a.score > b.score
+	}
+	return breadthFirstLess(a, b)
+}
+
+// frontierHeap implements heap.Interface, the first entry is the one to be taken next
+type frontierHeap struct {
+	entries []*frontierEntry
+	less    func(a, b *frontierEntry) bool
+}
+
+func (fh *frontierHeap) Len() int {
+	return len(fh.entries)
+}
+
+func (fh *frontierHeap) Less(i, j int) bool {
+	return fh.less(fh.entries[i], fh.entries[j])
+}
+
+func (fh *frontierHeap) Swap(i, j int) {
+	fh.entries[i], fh.entries[j] = fh.entries[j], fh.entries[i]
+	fh.entries[i].index = i
+	fh.entries[j].index = j
+}
+
+func (fh *frontierHeap) Push(v any) {
+	e := v.(*frontierEntry)
+	e.index = len(fh.entries)
+	fh.entries = append(fh.entries, e)
+}
+
+func (fh *frontierHeap) Pop() any {
+	last := len(fh.entries) - 1
+	e := fh.entries[last]
+	fh.entries[last] = nil
+	fh.entries = fh.entries[:last]
+	e.index = -1
+	return e
+}
+
+// priorityFrontier takes URLs by the order of their entries;
+// it's locked because links are counted by the observer while the pool takes URLs
+type priorityFrontier struct {
+	NopObserver
+
+	locker   sync.Mutex
+	bestOnly bool
+	weights  []PatternWeight
+	seq      uint64
+	entries  *frontierHeap
+	queued   map[string]*frontierEntry
+	inbound  map[string]int
+}
+
+func newPriorityFrontier(opts FrontierOptions, less func(a, b *frontierEntry) bool) *priorityFrontier {
+	return &priorityFrontier{
+		bestOnly: opts.Order == FrontierOrderBestFirst,
+		weights:  opts.PatternWeights,
+		entries:  &frontierHeap{entries: make([]*frontierEntry, 0), less: less},
+		queued:   make(map[string]*frontierEntry),
+		inbound:  make(map[string]int),
+	}
+}
+
+func (pf *priorityFrontier) Push(ctx models.CrawlerContext) {
+	pf.locker.Lock()
+	defer pf.locker.Unlock()
+
+	pf.seq++
+	e := &frontierEntry{
+		ctx:   ctx,
+		seq:   pf.seq,
+		score: pf.score(ctx.Location),
+	}
+	heap.Push(pf.entries, e)
+	pf.queued[ctx.Location] = e
+}
+
+func (pf *priorityFrontier) Pop() (ctx models.CrawlerContext, ok bool) {
+	pf.locker.Lock()
+	defer pf.locker.Unlock()
+
+	if pf.entries.Len() == 0 {
+		return ctx, false
+	}
+	e := heap.Pop(pf.entries).(*frontierEntry)
+	if pf.queued[e.ctx.Location] == e {
+		delete(pf.queued, e.ctx.Location)
+	}
+	return e.ctx, true
+}
+
+// OnLinkDiscovered raises the score of the URL, so the pages linked from many others are read earlier
+func (pf *priorityFrontier) OnLinkDiscovered(e models.LinkDiscoveredEvent) {
+	if !pf.bestOnly {
+		return
+	}
+	pf.locker.Lock()
+	defer pf.locker.Unlock()
+
+	pf.inbound[e.To]++
+	if entry, ok := pf.queued[e.To]; ok {
+		entry.score = pf.score(e.To)
+		heap.Fix(pf.entries, entry.index)
+	}
+}
+
+func (pf *priorityFrontier) score(location string) float64 {
+	if !pf.bestOnly {
+		return 0
+	}
+	score := float64(pf.inbound[location])
+	for _, pw := range pf.weights {
+		if pw.Pattern.MatchString(location) {
+			score += pw.Weight
+		}
+	}
+	return score
+}
+
+// roundRobinFrontier takes URLs of the hosts in turn, so a big host doesn't hold back the others
+type roundRobinFrontier struct {
+	NopObserver
+
+	locker sync.Mutex
+	seq    uint64
+	hosts  map[string]*frontierHeap
+	// ring is the order of the hosts having URLs, next is the position of the host to take URL from
+	ring []string
+	next int
+}
+
+func (rf *roundRobinFrontier) Push(ctx models.CrawlerContext) {
+	rf.locker.Lock()
+	defer rf.locker.Unlock()
+
+	host := hostOf(ctx.Location)
+	entries, ok := rf.hosts[host]
+	if !ok {
+		entries = &frontierHeap{entries: make([]*frontierEntry, 0), less: breadthFirstLess}
+		rf.hosts[host] = entries
+		rf.ring = append(rf.ring, host)
+	}
+
+	rf.seq++
+	heap.Push(entries, &frontierEntry{ctx: ctx, seq: rf.seq})
+}
+
+func (rf *roundRobinFrontier) Pop() (ctx models.CrawlerContext, ok bool) {
+	rf.locker.Lock()
+	defer rf.locker.Unlock()
+
+	if len(rf.ring) == 0 {
+		return ctx, false
+	}
+	if rf.next >= len(rf.ring) {
+		rf.next = 0
+	}
+	host := rf.ring[rf.next]
+	entries := rf.hosts[host]
+	ctx = heap.Pop(entries).(*frontierEntry).ctx
+
+	// the host leaves the ring until it gets new URLs
+	if entries.Len() == 0 {
+		delete(rf.hosts, host)
+		rf.ring = append(rf.ring[:rf.next], rf.ring[rf.next+1:]...)
+	} else {
+		rf.next++
+	}
+	return ctx, true
+}
@@ -0,0 +1,95 @@
+package crawlers_test
+
+import (
+	"sitemap-generator/pkg/crawlers"
+	"sitemap-generator/pkg/crawlers/models"
+	"sitemap-generator/utils"
+	"testing"
+)
+
+func TestFrontier(t *testing.T) {
+	push := func(f crawlers.Frontier) {
+		f.Push(models.CrawlerContext{Location: "https://a.com/deep", Depth: 2})
+		f.Push(models.CrawlerContext{Location: "https://a.com/x", Depth: 1})
+		f.Push(models.CrawlerContext{Location: "https://a.com/y", Depth: 1})
+		f.Push(models.CrawlerContext{Location: "https://b.com/z", Depth: 2})
+		f.Push(models.CrawlerContext{Location: "https://a.com/page?sort=asc", Depth: 1})
+	}
+	popAll := func(f crawlers.Frontier) []string {
+		locations := make([]string, 0)
+		for {
+			ctx, ok := f.Pop()
+			if !ok {
+				return locations
+			}
+			locations = append(locations, ctx.Location)
+		}
+	}
+
+	t.Run("fifo", func(t *testing.T) {
+		f := crawlers.NewFrontier(crawlers.FrontierOptions{Order: crawlers.FrontierOrderFifo})
+		push(f)
+		utils.AssertEqual(t, popAll(f), []string{
+			"https://a.com/deep", "https://a.com/x", "https://a.com/y", "https://b.com/z", "https://a.com/page?sort=asc",
+		})
+	})
+
+	t.Run("breadth-first by default", func(t *testing.T) {
+		f := crawlers.NewFrontier(crawlers.FrontierOptions{})
+		push(f)
+		utils.AssertEqual(t, popAll(f), []string{
+			"https://a.com/x", "https://a.com/y", "https://a.com/page?sort=asc", "https://a.com/deep", "https://b.com/z",
+		})
+	})
+
+	t.Run("best-first", func(t *testing.T) {
+		weight, err := crawlers.ParsePatternWeight(`\?sort==-5`)
+		utils.AssertNoError(t, err)
+		f := crawlers.NewFrontier(crawlers.FrontierOptions{
+			Order:          crawlers.FrontierOrderBestFirst,
+			PatternWeights: []crawlers.PatternWeight{weight},
+		})
+
+		// links found before and after the URL is queued are counted the same way
+		f.OnLinkDiscovered(models.LinkDiscoveredEvent{From: "https://a.com/", To: "https://b.com/z"})
+		push(f)
+		for i := 0; i < 2; i++ {
+			f.OnLinkDiscovered(models.LinkDiscoveredEvent{From: "https://a.com/", To: "https://a.com/y"})
+		}
+		utils.AssertEqual(t, popAll(f), []string{
+			"https://a.com/y", "https://b.com/z", "https://a.com/x", "https://a.com/deep", "https://a.com/page?sort=asc",
+		})
+	})
+
+	t.Run("round-robin", func(t *testing.T) {
+		f := crawlers.NewFrontier(crawlers.FrontierOptions{Order: crawlers.FrontierOrderRoundRobin})
+		push(f)
+		f.Push(models.CrawlerContext{Location: "https://b.com/w", Depth: 1})
+
+		first, ok := f.Pop()
+		utils.AssertTrue(t, ok)
+		utils.AssertEqual(t, first.Location, "https://a.com/x")
+		utils.AssertEqual(t, popAll(f), []string{
+			"https://b.com/w", "https://a.com/y", "https://b.com/z", "https://a.com/page?sort=asc", "https://a.com/deep",
+		})
+
+		// the host is back to the ring with new URLs
+		f.Push(models.CrawlerContext{Location: "https://b.com/v", Depth: 1})
+		utils.AssertEqual(t, popAll(f), []string{"https://b.com/v"})
+	})
+}
+
+func TestParsePatternWeight(t *testing.T) {
+	pw, err := crawlers.ParsePatternWeight("/products/=2.5")
+	utils.AssertNoError(t, err)
+	utils.AssertEqual(t, pw.Pattern.String(), "/products/")
+	utils.AssertEqual(t, pw.Weight, 2.5)
+
+	_, err = crawlers.ParsePatternWeight("/products/")
+	utils.AssertHasError(t, err, "regexp=weight expected: /products/")
+	_, err = crawlers.ParsePatternWeight("/products/=high")
+	utils.AssertHasError(t, err, "invalid weight of /products/=high")
+
+	_, err = crawlers.ParseFrontierOrder("depth-first")
+	utils.AssertHasError(t, err, "invalid frontier order: depth-first")
+}
@@ -0,0 +1,167 @@
+package crawlers
+
+import (
+	"fmt"
+	"regexp"
+	"sitemap-generator/pkg/crawlers/models"
+	readersModels "sitemap-generator/pkg/readers/models"
+	"strconv"
+	"strings"
+)
+
+// InclusionPolicy decides if URL should get into the sitemap;
+// empty reason means URL is included, otherwise details explain the exclusion
+type InclusionPolicy interface {
+	// CheckUrl decides by the response for the URL check
+	CheckUrl(info readersModels.UrlInfo) (reason models.SkipReason, details string)
+	// CheckPage decides by the page content when it's read
+	CheckPage(page readersModels.Page) (reason models.SkipReason, details string)
+	// NeedsContent tells if pages should be read even if they're not going to be scanned for links
+	NeedsContent() bool
+}
+
+type InclusionPolicyOptions struct {
+	// StatusCodes allowed to be included, all 2xx codes by default
+	StatusCodes []int
+	// ContentClasses allowed to be included, all of them by default
+	ContentClasses []readersModels.ContentClass
+	// Soft404Titles are patterns of the page title which tell that page is not found despite of its status
+	Soft404Titles []*regexp.Regexp
+	// Soft404Bodies are the same patterns as Soft404Titles but for the whole page
+	Soft404Bodies []*regexp.Regexp
+}
+
+type inclusionPolicy struct {
+	statusCodes    map[int]bool
+	contentClasses map[readersModels.ContentClass]bool
+	soft404Titles  []*regexp.Regexp
+	soft404Bodies  []*regexp.Regexp
+}
+
+var titleRegexp = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+func NewInclusionPolicy(opts InclusionPolicyOptions) InclusionPolicy {
+	if len(opts.StatusCodes) == 0 {
+		opts.StatusCodes, _ = ParseStatusCodes("2xx")
+	}
+
+	statusCodes := make(map[int]bool)
+	for _, code := range opts.StatusCodes {
+		statusCodes[code] = true
+	}
+
+	var contentClasses map[readersModels.ContentClass]bool
+	if len(opts.ContentClasses) > 0 {
+		contentClasses = make(map[readersModels.ContentClass]bool)
+		for _, class := range opts.ContentClasses {
+			contentClasses[class] = true
+		}
+	}
+
+	return &inclusionPolicy{
+		statusCodes:    statusCodes,
+		contentClasses: contentClasses,
+		soft404Titles:  opts.Soft404Titles,
+		soft404Bodies:  opts.Soft404Bodies,
+	}
+}
+
+// CheckUrl excludes URL with not allowed status or content class; unknown ones don't exclude anything
+func (ip *inclusionPolicy) CheckUrl(info readersModels.UrlInfo) (models.SkipReason, string) {
+	if reason, details := ip.checkStatus(info.StatusCode); reason != "" {
+		return reason, details
+	}
+	if ip.contentClasses != nil && info.ContentClass != "" && !ip.contentClasses[info.ContentClass] {
+		return models.SkipReasonContentClass, fmt.Sprintf("content class %s", info.ContentClass)
+	}
+	return "", ""
+}
+
+func (ip *inclusionPolicy) CheckPage(page readersModels.Page) (models.SkipReason, string) {
+	if reason, details := ip.checkStatus(page.StatusCode); reason != "" {
+		return reason, details
+	}
+
+	if len(ip.soft404Titles) > 0 {
+		title := ""
+		if m := titleRegexp.FindSubmatch(page.Body); m != nil {
+			title = strings.TrimSpace(string(m[1]))
+		}
+		for _, p := range ip.soft404Titles {
+			if p.MatchString(title) {
+				return models.SkipReasonSoft404, fmt.Sprintf("title matches %s", p.String())
+			}
+		}
+	}
+	for _, p := range ip.soft404Bodies {
+		if p.Match(page.Body) {
+			return models.SkipReasonSoft404, fmt.Sprintf("body matches %s", p.String())
+		}
+	}
+	return "", ""
+}
+
+func (ip *inclusionPolicy) NeedsContent() bool {
+	return len(ip.soft404Titles) > 0 || len(ip.soft404Bodies) > 0
+}
+
+func (ip *inclusionPolicy) checkStatus(statusCode int) (models.SkipReason, string) {
+	if statusCode != 0 && !ip.statusCodes[statusCode] {
+		return models.SkipReasonStatus, fmt.Sprintf("status %d", statusCode)
+	}
+	return "", ""
+}
+
+// ParseContentClasses parses comma separated list of content classes, e.g. "html,document"
+func ParseContentClasses(v string) ([]readersModels.ContentClass, error) {
+	classes := make([]readersModels.ContentClass, 0)
+	for _, item := range strings.Split(v, ",") {
+		class := readersModels.ContentClass(strings.ToLower(strings.TrimSpace(item)))
+		switch class {
+		case "":
+			continue
+		case readersModels.ContentClassHtml, readersModels.ContentClassDocument, readersModels.ContentClassImage,
+			readersModels.ContentClassMedia, readersModels.ContentClassOther:
+			classes = append(classes, class)
+		default:
+			return nil, fmt.Errorf("invalid content class: %s", item)
+		}
+	}
+	return classes, nil
+}
+
+// ParseStatusCodes parses comma separated list of status codes and classes, e.g. "2xx,304";
+// 4xx and 5xx ones are not accepted since such responses are errors, they never reach the policy
+func ParseStatusCodes(v string) ([]int, error) {
+	codes := make([]int, 0)
+	for _, item := range strings.Split(v, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item == "" {
+			continue
+		}
+
+		if len(item) == 3 && strings.HasSuffix(item, "xx") {
+			class, err := strconv.Atoi(item[:1])
+			if err != nil || class < 1 || class > 5 {
+				return nil, fmt.Errorf("invalid status class: %s", item)
+			}
+			if class >= 4 {
+				return nil, fmt.Errorf("status class can not be included, 4xx and 5xx responses are errors: %s", item)
+			}
+			for code := class * 100; code < (class+1)*100; code++ {
+				codes = append(codes, code)
+			}
+			continue
+		}
+
+		code, err := strconv.Atoi(item)
+		if err != nil || code < 100 || code > 599 {
+			return nil, fmt.Errorf("invalid status code: %s", item)
+		}
+		if code >= 400 {
+			return nil, fmt.Errorf("status code can not be included, 4xx and 5xx responses are errors: %s", item)
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
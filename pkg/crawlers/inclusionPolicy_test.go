@@ -0,0 +1,96 @@
+package crawlers_test
+
+import (
+	"regexp"
+	"sitemap-generator/pkg/crawlers"
+	"sitemap-generator/pkg/crawlers/models"
+	readersModels "sitemap-generator/pkg/readers/models"
+	"sitemap-generator/utils"
+	"testing"
+)
+
+func TestInclusionPolicy_CheckUrl(t *testing.T) {
+	t.Run("only 2xx by default", func(t *testing.T) {
+		policy := crawlers.NewInclusionPolicy(crawlers.InclusionPolicyOptions{})
+
+		reason, _ := policy.CheckUrl(readersModels.UrlInfo{StatusCode: 200})
+		utils.AssertEmpty(t, reason)
+		reason, _ = policy.CheckUrl(readersModels.UrlInfo{StatusCode: 0})
+		utils.AssertEmpty(t, reason)
+
+		reason, details := policy.CheckUrl(readersModels.UrlInfo{StatusCode: 304})
+		utils.AssertEqual(t, reason, models.SkipReasonStatus)
+		utils.AssertEqual(t, details, "status 304")
+		utils.AssertFalse(t, policy.NeedsContent())
+	})
+
+	t.Run("configured status codes", func(t *testing.T) {
+		codes, err := crawlers.ParseStatusCodes("200, 304")
+		utils.AssertNoError(t, err)
+		policy := crawlers.NewInclusionPolicy(crawlers.InclusionPolicyOptions{StatusCodes: codes})
+
+		reason, _ := policy.CheckUrl(readersModels.UrlInfo{StatusCode: 304})
+		utils.AssertEmpty(t, reason)
+		reason, _ = policy.CheckUrl(readersModels.UrlInfo{StatusCode: 203})
+		utils.AssertEqual(t, reason, models.SkipReasonStatus)
+	})
+}
+
+func TestInclusionPolicy_ContentClasses(t *testing.T) {
+	classes, err := crawlers.ParseContentClasses("html, document")
+	utils.AssertNoError(t, err)
+	policy := crawlers.NewInclusionPolicy(crawlers.InclusionPolicyOptions{ContentClasses: classes})
+
+	reason, _ := policy.CheckUrl(readersModels.UrlInfo{StatusCode: 200, ContentClass: readersModels.ContentClassDocument})
+	utils.AssertEmpty(t, reason)
+	reason, details := policy.CheckUrl(readersModels.UrlInfo{StatusCode: 200, ContentClass: readersModels.ContentClassImage})
+	utils.AssertEqual(t, reason, models.SkipReasonContentClass)
+	utils.AssertEqual(t, details, "content class image")
+
+	_, err = crawlers.ParseContentClasses("html,video")
+	utils.AssertHasError(t, err, "invalid content class")
+}
+
+func TestInclusionPolicy_CheckPage(t *testing.T) {
+	policy := crawlers.NewInclusionPolicy(crawlers.InclusionPolicyOptions{
+		Soft404Titles: []*regexp.Regexp{regexp.MustCompile(`(?i)not found`)},
+		Soft404Bodies: []*regexp.Regexp{regexp.MustCompile(`(?i)no longer available`)},
+	})
+	utils.AssertTrue(t, policy.NeedsContent())
+
+	reason, _ := policy.CheckPage(readersModels.Page{
+		StatusCode: 200,
+		Body:       []byte(`<html><head><title>Products</title></head><body>Nothing found</body></html>`),
+	})
+	utils.AssertEmpty(t, reason)
+
+	reason, details := policy.CheckPage(readersModels.Page{
+		StatusCode: 200,
+		Body:       []byte(`<html><head><title> Page Not Found </title></head></html>`),
+	})
+	utils.AssertEqual(t, reason, models.SkipReasonSoft404)
+	utils.AssertEqual(t, details, "title matches (?i)not found")
+
+	reason, _ = policy.CheckPage(readersModels.Page{
+		StatusCode: 200,
+		Body:       []byte(`<html><body>This product is no longer available</body></html>`),
+	})
+	utils.AssertEqual(t, reason, models.SkipReasonSoft404)
+}
+
+func TestParseStatusCodes(t *testing.T) {
+	codes, err := crawlers.ParseStatusCodes("2xx,304")
+	utils.AssertNoError(t, err)
+	utils.AssertEqual(t, len(codes), 101)
+	utils.AssertEqual(t, codes[0], 200)
+	utils.AssertEqual(t, codes[100], 304)
+
+	_, err = crawlers.ParseStatusCodes("6xx")
+	utils.AssertHasError(t, err, "invalid status class")
+	_, err = crawlers.ParseStatusCodes("abc")
+	utils.AssertHasError(t, err, "invalid status code")
+	_, err = crawlers.ParseStatusCodes("2xx,410")
+	utils.AssertHasError(t, err, "status code can not be included, 4xx and 5xx responses are errors: 410")
+	_, err = crawlers.ParseStatusCodes("5xx")
+	utils.AssertHasError(t, err, "status class can not be included, 4xx and 5xx responses are errors: 5xx")
+}
@@ -0,0 +1,74 @@
+package crawlers
+
+import (
+	"fmt"
+	"sitemap-generator/pkg/crawlers/models"
+	"sitemap-generator/services"
+	"sitemap-generator/utils"
+)
+
+type loggingObserver struct {
+	logger   services.Logger
+	maxDepth int
+}
+
+// NewLoggingObserver reports crawl events to the log
+func NewLoggingObserver(logger services.Logger, maxDepth int) Observer {
+	return &loggingObserver{
+		logger:   logger,
+		maxDepth: maxDepth,
+	}
+}
+
+func (lo *loggingObserver) OnPageFetched(e models.PageFetchedEvent) {
+	if e.Truncated != "" {
+		lo.logger.Warn(fmt.Sprintf("Crawler: body is truncated (%s), links are scanned only in its first %d bytes", e.Truncated, e.BodySize), e.Url)
+	}
+	lo.logger.Debug(fmt.Sprintf("Crawler: got body (length: %d bytes, on the wire: %d bytes, status: %d, duration: %s)",
+		e.BodySize, e.Transfer.WireBytes, e.StatusCode, e.Duration), e.Url)
+}
+
+func (lo *loggingObserver) OnLinkDiscovered(e models.LinkDiscoveredEvent) {
+	lo.logger.Debug("Crawler: got link", utils.InJSON(e))
+}
+
+func (lo *loggingObserver) OnRedirect(e models.RedirectEvent) {
+	if len(e.Issues) > 0 {
+		lo.logger.Warn("Crawler: problematic redirect", utils.InJSON(e))
+	} else {
+		lo.logger.Debug("Crawler: URL redirected", utils.InJSON(e))
+	}
+}
+
+func (lo *loggingObserver) OnUrlCollected(e models.UrlCollectedEvent) {
+	lo.logger.Debug("Crawler: collected URL", utils.InJSON(e.Url))
+	switch e.NotScanned {
+	case models.NotScannedTooDeep:
+		lo.logger.Info(fmt.Sprintf("Crawler: skip scanning, would be too deep for max depth %d", lo.maxDepth), utils.InJSON(e))
+	case models.NotScannedNotHtml:
+		lo.logger.Debug("Crawler: not HTML page, skip scanning", utils.InJSON(e))
+	}
+}
+
+func (lo *loggingObserver) OnUrlSkipped(e models.UrlSkippedEvent) {
+	switch e.Reason {
+	case models.SkipReasonStatus, models.SkipReasonSoft404, models.SkipReasonContentClass, models.SkipReasonDuplicateContent:
+		lo.logger.Info("Crawler: URL excluded from the results", utils.InJSON(e))
+	case models.SkipReasonTrap:
+		lo.logger.Info("Crawler: skip URL, it looks like a crawler trap", utils.InJSON(e))
+	case models.SkipReasonBudget:
+		lo.logger.Info("Crawler: skip URL, the budget of its host is exhausted", utils.InJSON(e))
+	case models.SkipReasonOffline:
+		lo.logger.Info("Crawler: skip URL, it can't be checked offline", utils.InJSON(e))
+	default:
+		lo.logger.Debug("Crawler: skip URL", utils.InJSON(e))
+	}
+}
+
+func (lo *loggingObserver) OnError(e models.ErrorEvent) {
+	if e.Op == models.ErrorOpRead {
+		lo.logger.Warn("Crawler: could not read URL", e.Url, e.Err.Error())
+	} else {
+		lo.logger.Warn("Crawler: could not read URL while checking", e.Url, e.Err.Error())
+	}
+}
@@ -0,0 +1,35 @@
+package crawlers
+
+import (
+	"sitemap-generator/pkg/crawlers/models"
+	"sitemap-generator/pkg/metrics"
+)
+
+type metricsObserver struct {
+	NopObserver
+	metrics *metrics.Metrics
+}
+
+// newMetricsObserver counts crawl events in the metrics
+func newMetricsObserver(m *metrics.Metrics) Observer {
+	return &metricsObserver{
+		metrics: m,
+	}
+}
+
+func (mo *metricsObserver) OnPageFetched(e models.PageFetchedEvent) {
+	mo.metrics.PagesFetched.Inc()
+	mo.metrics.FetchDuration.Observe(e.Duration.Seconds())
+}
+
+func (mo *metricsObserver) OnUrlCollected(models.UrlCollectedEvent) {
+	mo.metrics.UrlsCollected.Inc()
+}
+
+func (mo *metricsObserver) OnUrlSkipped(e models.UrlSkippedEvent) {
+	mo.metrics.UrlsSkipped.Inc(string(e.Reason))
+}
+
+func (mo *metricsObserver) OnError(e models.ErrorEvent) {
+	mo.metrics.Errors.Inc(string(e.Op))
+}
@@ -5,8 +5,15 @@ import "time"
 type CrawlerContext struct {
 	Location     string    `json:"location"`
 	LastModified time.Time `json:"lastModified"`
+	StatusCode   int       `json:"statusCode"`
 	IsHtml       bool      `json:"isHtml"`
+	ContentClass string    `json:"contentClass,omitempty"`
 	Depth        int       `json:"depth"`
+	// Checked means the URL info above is already known
+	Checked bool `json:"checked"`
+	// From is the page where URL was found and Text is the text of the link there
+	From string `json:"from,omitempty"`
+	Text string `json:"text,omitempty"`
 }
 
 func (cc *CrawlerContext) Id() string {
@@ -0,0 +1,16 @@
+package models
+
+// DuplicateCluster is a group of pages with the same or almost the same content
+type DuplicateCluster struct {
+	// Representative is the page which gets into the results instead of the whole group
+	Representative CrawlerContext `json:"representative"`
+	Duplicates     []Duplicate    `json:"duplicates"`
+}
+
+type Duplicate struct {
+	Page CrawlerContext `json:"page"`
+	// Exact means the main text of the page is the same as the one of the representative, otherwise it's similar
+	Exact bool `json:"exact"`
+	// Distance is the number of different bits of the content fingerprints of the page and the group
+	Distance int `json:"distance"`
+}
@@ -0,0 +1,129 @@
+package models
+
+import (
+	"net/http"
+	readersModels "sitemap-generator/pkg/readers/models"
+	"time"
+)
+
+type SkipReason string
+
+const (
+	// SkipReasonDuplicate means URL is already collected
+	SkipReasonDuplicate SkipReason = "duplicate"
+	// SkipReasonStatus means URL is excluded from the sitemap because of its HTTP status
+	SkipReasonStatus SkipReason = "status"
+	// SkipReasonSoft404 means URL is excluded from the sitemap because it looks like a "not found" page
+	SkipReasonSoft404 SkipReason = "soft-404"
+	// SkipReasonContentClass means URL is excluded from the sitemap because of its content class
+	SkipReasonContentClass SkipReason = "content-class"
+	// SkipReasonBudget means URL is not read or collected because the budget of its host is exhausted
+	SkipReasonBudget SkipReason = "budget"
+	// SkipReasonTrap means URL is not read or collected because it looks like a crawler trap
+	SkipReasonTrap SkipReason = "trap"
+	// SkipReasonDuplicateContent means URL is excluded from the sitemap because another page has the same content
+	SkipReasonDuplicateContent SkipReason = "duplicate-content"
+	// SkipReasonOffline means URL is not checked or read because it's not served offline, e.g. it's on another host
+	SkipReasonOffline SkipReason = "offline"
+)
+
+// NotScannedReason tells why the collected URL is not scanned for links, it's not a reason to skip URL
+type NotScannedReason string
+
+const (
+	// NotScannedNotHtml means URL is not HTML page, so it has no links to scan
+	NotScannedNotHtml NotScannedReason = "not-html"
+	// NotScannedTooDeep means links of the page would be deeper than max depth
+	NotScannedTooDeep NotScannedReason = "too-deep"
+)
+
+// TrapKind is the heuristic which detected the crawler trap (infinite space of URLs)
+type TrapKind string
+
+const (
+	// TrapRepeatedSegments means the same segment occurs in the path too many times, e.g. /a/a/a/a/
+	TrapRepeatedSegments TrapKind = "repeated-segments"
+	// TrapUrlLength means URL is too long
+	TrapUrlLength TrapKind = "url-length"
+	// TrapSessionId means URL contains a session ID, so each visit produces new URLs
+	TrapSessionId TrapKind = "session-id"
+	// TrapQueryVariants means the path has too many distinct query strings, e.g. faceted search
+	TrapQueryVariants TrapKind = "query-variants"
+	// TrapNearDuplicates means pages of the same URL shape have almost the same content, e.g. empty calendar pages
+	TrapNearDuplicates TrapKind = "near-duplicates"
+)
+
+type ErrorOp string
+
+const (
+	// ErrorOpRead means page could not be read to scan it for links
+	ErrorOpRead ErrorOp = "read"
+	// ErrorOpCheck means link found on the page could not be checked
+	ErrorOpCheck ErrorOp = "check"
+)
+
+type RedirectIssue string
+
+const (
+	// RedirectIssueLongChain means there are too many redirects to get the final URL
+	RedirectIssueLongChain RedirectIssue = "long-chain"
+	// RedirectIssueDowngrade means HTTPS URL is redirected to HTTP one
+	RedirectIssueDowngrade RedirectIssue = "https-downgrade"
+)
+
+type PageFetchedEvent struct {
+	Url        string        `json:"url"`
+	Depth      int           `json:"depth"`
+	StatusCode int           `json:"statusCode"`
+	Header     http.Header   `json:"header"`
+	BodySize   int           `json:"bodySize"`
+	Duration   time.Duration `json:"duration"`
+	// Truncated tells why only the beginning of the body is read (and scanned for links)
+	Truncated readersModels.Truncation `json:"truncated,omitempty"`
+	// Transfer is bytes of the body on the wire and decoded ones
+	Transfer readersModels.Transfer `json:"transfer"`
+}
+
+type LinkDiscoveredEvent struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Element string `json:"element"`
+	Text    string `json:"text"`
+}
+
+// RedirectEvent tells that link was replaced with the URL it's redirected to
+type RedirectEvent struct {
+	From      string                   `json:"from"`
+	To        string                   `json:"to"`
+	Redirects []readersModels.Redirect `json:"redirects"`
+	Issues    []RedirectIssue          `json:"issues,omitempty"`
+}
+
+type UrlCollectedEvent struct {
+	Url   Url `json:"url"`
+	Depth int `json:"depth"`
+	// NotScanned is set when the links of the page are not scanned
+	NotScanned NotScannedReason `json:"notScanned,omitempty"`
+}
+
+type UrlSkippedEvent struct {
+	Url     string     `json:"url"`
+	Depth   int        `json:"depth"`
+	Reason  SkipReason `json:"reason"`
+	Details string     `json:"details,omitempty"`
+	// Trap is set when URL is skipped as a crawler trap
+	Trap TrapKind `json:"trap,omitempty"`
+	// DuplicateOf is the page collected instead of URL with duplicate content
+	DuplicateOf string `json:"duplicateOf,omitempty"`
+}
+
+type ErrorEvent struct {
+	Op  ErrorOp `json:"op"`
+	Url string  `json:"url"`
+	// From is the page where URL was found while checking links
+	From string `json:"from,omitempty"`
+	// Text is the text of the link on the From page
+	Text  string `json:"text,omitempty"`
+	Depth int    `json:"depth"`
+	Err   error  `json:"-"`
+}
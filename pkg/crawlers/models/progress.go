@@ -0,0 +1,25 @@
+package models
+
+// Progress is the state of the crawl at the moment
+type Progress struct {
+	// Queued is the number of pages waiting for a free worker
+	Queued int `json:"queued"`
+	// BusyWorkers is the number of workers reading pages at the moment
+	BusyWorkers   int `json:"busyWorkers"`
+	Workers       int `json:"workers"`
+	PagesFetched  int `json:"pagesFetched"`
+	UrlsCollected int `json:"urlsCollected"`
+	Errors        int `json:"errors"`
+}
+
+// Add sums up the progress of several crawls
+func (p Progress) Add(other Progress) Progress {
+	return Progress{
+		Queued:        p.Queued + other.Queued,
+		BusyWorkers:   p.BusyWorkers + other.BusyWorkers,
+		Workers:       p.Workers + other.Workers,
+		PagesFetched:  p.PagesFetched + other.PagesFetched,
+		UrlsCollected: p.UrlsCollected + other.UrlsCollected,
+		Errors:        p.Errors + other.Errors,
+	}
+}
@@ -0,0 +1,96 @@
+package crawlers
+
+import (
+	"sitemap-generator/pkg/crawlers/models"
+	"sync"
+	"sync/atomic"
+)
+
+// Observer gets notified about what's happening during the crawl;
+// events are delivered one by one, so implementations don't need to be safe for concurrent use
+type Observer interface {
+	OnPageFetched(e models.PageFetchedEvent)
+	OnLinkDiscovered(e models.LinkDiscoveredEvent)
+	OnRedirect(e models.RedirectEvent)
+	OnUrlCollected(e models.UrlCollectedEvent)
+	OnUrlSkipped(e models.UrlSkippedEvent)
+	OnError(e models.ErrorEvent)
+}
+
+// NopObserver ignores all events, embed it to implement only needed ones
+type NopObserver struct{}
+
+func (NopObserver) OnPageFetched(models.PageFetchedEvent)       {}
+func (NopObserver) OnLinkDiscovered(models.LinkDiscoveredEvent) {}
+func (NopObserver) OnRedirect(models.RedirectEvent)             {}
+func (NopObserver) OnUrlCollected(models.UrlCollectedEvent)     {}
+func (NopObserver) OnUrlSkipped(models.UrlSkippedEvent)         {}
+func (NopObserver) OnError(models.ErrorEvent)                   {}
+
+// observers delivers events from the workers to all observers, each observer gets them serially;
+// every observer has its own lock, so a slow one holds back only the workers notifying it.
+// They count the main events as well, so the progress is known while the crawl goes on
+type observers struct {
+	// counters are atomic, so they're first to be aligned
+	pages  int64
+	urls   int64
+	errors int64
+
+	list []*lockedObserver
+}
+
+type lockedObserver struct {
+	locker   sync.Mutex
+	observer Observer
+}
+
+func newObservers(list []Observer) *observers {
+	locked := make([]*lockedObserver, len(list))
+	for i, ob := range list {
+		locked[i] = &lockedObserver{observer: ob}
+	}
+	return &observers{
+		list: locked,
+	}
+}
+
+// notify passes the event to each observer under its own lock
+func (o *observers) notify(event func(ob Observer)) {
+	for _, lo := range o.list {
+		lo.locker.Lock()
+		event(lo.observer)
+		lo.locker.Unlock()
+	}
+}
+
+func (o *observers) OnPageFetched(e models.PageFetchedEvent) {
+	atomic.AddInt64(&o.pages, 1)
+	o.notify(func(ob Observer) { ob.OnPageFetched(e) })
+}
+
+func (o *observers) OnLinkDiscovered(e models.LinkDiscoveredEvent) {
+	o.notify(func(ob Observer) { ob.OnLinkDiscovered(e) })
+}
+
+func (o *observers) OnRedirect(e models.RedirectEvent) {
+	o.notify(func(ob Observer) { ob.OnRedirect(e) })
+}
+
+func (o *observers) OnUrlCollected(e models.UrlCollectedEvent) {
+	atomic.AddInt64(&o.urls, 1)
+	o.notify(func(ob Observer) { ob.OnUrlCollected(e) })
+}
+
+func (o *observers) OnUrlSkipped(e models.UrlSkippedEvent) {
+	o.notify(func(ob Observer) { ob.OnUrlSkipped(e) })
+}
+
+func (o *observers) OnError(e models.ErrorEvent) {
+	atomic.AddInt64(&o.errors, 1)
+	o.notify(func(ob Observer) { ob.OnError(e) })
+}
+
+// progress returns the number of pages fetched, URLs collected and errors so far
+func (o *observers) progress() (pages, urls, errors int) {
+	return int(atomic.LoadInt64(&o.pages)), int(atomic.LoadInt64(&o.urls)), int(atomic.LoadInt64(&o.errors))
+}
@@ -0,0 +1,107 @@
+package crawlers
+
+import (
+	"fmt"
+	"io"
+	"sitemap-generator/pkg/crawlers/models"
+	"sitemap-generator/services"
+	"sync"
+	"time"
+)
+
+// terminalRefresh is how often the progress line is redrawn on the terminal
+const terminalRefresh = time.Second
+
+type ProgressReporterOptions struct {
+	// Interval of the progress logs, the terminal line is redrawn every second anyway
+	Interval time.Duration
+	// Terminal is where the progress line is redrawn, the progress is logged when it's nil
+	Terminal io.Writer
+	Logger   services.Logger
+}
+
+// ProgressReporter shows the progress of the crawl while it goes on
+type ProgressReporter interface {
+	// Start reports the progress given by the source (e.g. Crawler.Progress) until Stop
+	Start(source func() models.Progress)
+	// Stop reports the last progress and waits until the reporter is stopped
+	Stop()
+}
+
+type progressReporter struct {
+	interval time.Duration
+	terminal io.Writer
+	logger   services.Logger
+
+	source  func() models.Progress
+	stopped chan struct{}
+	done    sync.WaitGroup
+
+	// last is the progress of the previous report, the rate of pages is counted since then
+	last     models.Progress
+	lastTime time.Time
+}
+
+func NewProgressReporter(opts ProgressReporterOptions) ProgressReporter {
+	interval := opts.Interval
+	if opts.Terminal != nil {
+		interval = terminalRefresh
+	}
+	return &progressReporter{
+		interval: interval,
+		terminal: opts.Terminal,
+		logger:   opts.Logger,
+	}
+}
+
+func (pr *progressReporter) Start(source func() models.Progress) {
+	pr.source = source
+	pr.stopped = make(chan struct{})
+	pr.lastTime = time.Now()
+
+	pr.done.Add(1)
+	go func() {
+		defer pr.done.Done()
+
+		ticker := time.NewTicker(pr.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				pr.report()
+			case <-pr.stopped:
+				return
+			}
+		}
+	}()
+}
+
+func (pr *progressReporter) Stop() {
+	close(pr.stopped)
+	pr.done.Wait()
+
+	pr.report()
+	if pr.terminal != nil {
+		fmt.Fprintln(pr.terminal)
+	}
+}
+
+func (pr *progressReporter) report() {
+	now := time.Now()
+	p := pr.source()
+
+	rate := 0.0
+	if elapsed := now.Sub(pr.lastTime).Seconds(); elapsed > 0 {
+		rate = float64(p.PagesFetched-pr.last.PagesFetched) / elapsed
+	}
+	pr.last, pr.lastTime = p, now
+
+	line := fmt.Sprintf("%d pages fetched (%.1f/s), %d URLs collected, %d errors, %d pages queued, %d/%d workers busy",
+		p.PagesFetched, rate, p.UrlsCollected, p.Errors, p.Queued, p.BusyWorkers, p.Workers)
+	if pr.terminal != nil {
+		// the line is redrawn in place: carriage return and erase to the end of the line
+		fmt.Fprintf(pr.terminal, "\r\033[K%s", line)
+		return
+	}
+	pr.logger.Info("Crawler: progress: " + line)
+}
@@ -0,0 +1,55 @@
+package crawlers_test
+
+import (
+	"bytes"
+	"sitemap-generator/pkg/crawlers"
+	"sitemap-generator/pkg/crawlers/models"
+	"sitemap-generator/services"
+	"sitemap-generator/utils"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestProgressReporter(t *testing.T) {
+	var pages int64
+	source := func() models.Progress {
+		return models.Progress{
+			Queued:        7,
+			BusyWorkers:   2,
+			Workers:       5,
+			PagesFetched:  int(atomic.AddInt64(&pages, 10)),
+			UrlsCollected: 3,
+			Errors:        1,
+		}
+	}
+
+	t.Run("terminal", func(t *testing.T) {
+		atomic.StoreInt64(&pages, 0)
+		terminal := new(bytes.Buffer)
+		reporter := crawlers.NewProgressReporter(crawlers.ProgressReporterOptions{Terminal: terminal})
+		reporter.Start(source)
+		reporter.Stop()
+
+		utils.AssertTrue(t, strings.HasPrefix(terminal.String(), "\r\033[K10 pages fetched ("))
+		utils.AssertTrue(t, strings.HasSuffix(terminal.String(), "/s), 3 URLs collected, 1 errors, 7 pages queued, 2/5 workers busy\n"))
+	})
+
+	t.Run("logs", func(t *testing.T) {
+		atomic.StoreInt64(&pages, 0)
+		logs := new(bytes.Buffer)
+		logger, err := services.NewLogger(logs, "testing", "info")
+		utils.AssertNoError(t, err)
+
+		reporter := crawlers.NewProgressReporter(crawlers.ProgressReporterOptions{Interval: 20 * time.Millisecond, Logger: logger})
+		reporter.Start(source)
+		time.Sleep(50 * time.Millisecond)
+		reporter.Stop()
+
+		lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+		utils.AssertTrue(t, len(lines) >= 2)
+		utils.AssertTrue(t, strings.Contains(lines[0], "Crawler: progress: 10 pages fetched ("))
+		utils.AssertTrue(t, strings.Contains(lines[len(lines)-1], "2/5 workers busy"))
+	})
+}
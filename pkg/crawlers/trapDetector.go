@@ -0,0 +1,211 @@
+package crawlers
+
+import (
+	"fmt"
+	"net/url"
+	"sitemap-generator/pkg/crawlers/models"
+	"sitemap-generator/pkg/parsers"
+	readersModels "sitemap-generator/pkg/readers/models"
+	"sitemap-generator/utils"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultSessionParams are names of the parameters which usually hold session IDs
+var DefaultSessionParams = []string{"jsessionid", "phpsessid", "aspsessionid", "sessionid", "session_id", "sid", "cfid", "cftoken"}
+
+// TrapDetector decides if URL leads to a crawler trap (infinite space of URLs);
+// empty trap means URL is fine, otherwise details explain the detection
+type TrapDetector interface {
+	// CheckUrl decides by URL before it's checked or read, it's called for each page linking to URL
+	CheckUrl(location string) (trap models.TrapKind, details string)
+	// CheckPage decides by the content of the read page before its links are scanned
+	CheckPage(location string, page readersModels.Page) (trap models.TrapKind, details string)
+	// NeedsContent tells if CheckPage needs the body of the page
+	NeedsContent() bool
+}
+
+// TrapDetectorOptions are thresholds of the heuristics, zero ones disable them
+type TrapDetectorOptions struct {
+	// MaxSegmentRepeats is how many times the same path segment may occur in URL
+	MaxSegmentRepeats int
+	MaxUrlLength      int
+	// MaxQueryVariants is how many distinct query strings the same path may have
+	MaxQueryVariants int
+	// SessionParams are names of query and path parameters holding session IDs (case-insensitive)
+	SessionParams []string
+	// MaxNearDuplicates is how many pages of the same URL shape (numbers and query values ignored)
+	// may be near-duplicates of other pages of the shape, the rest URLs of the shape are skipped then
+	MaxNearDuplicates int
+	// NearDuplicateDistance is max number of different bits of SimHash fingerprints of near-duplicate pages
+	NearDuplicateDistance int
+}
+
+// maxShapeFingerprints limits the fingerprints compared with the page, so the check doesn't slow down the crawl
+const maxShapeFingerprints = 100
+
+type trapDetector struct {
+	opts          TrapDetectorOptions
+	sessionParams map[string]bool
+	parser        parsers.Parser
+
+	locker         sync.Mutex
+	queryVariants  map[string]map[string]bool
+	fingerprints   map[string][]uint64
+	nearDuplicates map[string]int
+}
+
+func NewTrapDetector(opts TrapDetectorOptions) TrapDetector {
+	sessionParams := make(map[string]bool)
+	for _, p := range opts.SessionParams {
+		sessionParams[strings.ToLower(p)] = true
+	}
+
+	return &trapDetector{
+		opts:           opts,
+		sessionParams:  sessionParams,
+		parser:         parsers.NewParser(),
+		queryVariants:  make(map[string]map[string]bool),
+		fingerprints:   make(map[string][]uint64),
+		nearDuplicates: make(map[string]int),
+	}
+}
+
+func (td *trapDetector) NeedsContent() bool {
+	return td.opts.MaxNearDuplicates > 0
+}
+
+func (td *trapDetector) CheckUrl(location string) (models.TrapKind, string) {
+	if td.opts.MaxUrlLength > 0 && len(location) > td.opts.MaxUrlLength {
+		return models.TrapUrlLength, fmt.Sprintf("%d characters", len(location))
+	}
+
+	u, err := url.Parse(location)
+	if err != nil {
+		return "", ""
+	}
+
+	if name := td.sessionParam(u); name != "" {
+		return models.TrapSessionId, fmt.Sprintf("parameter %s", name)
+	}
+
+	if td.opts.MaxSegmentRepeats > 0 {
+		repeats := make(map[string]int)
+		for _, segment := range strings.Split(u.EscapedPath(), "/") {
+			if segment == "" {
+				continue
+			}
+			if repeats[segment]++; repeats[segment] > td.opts.MaxSegmentRepeats {
+				return models.TrapRepeatedSegments, fmt.Sprintf("segment %s repeats %d times", segment, repeats[segment])
+			}
+		}
+	}
+
+	td.locker.Lock()
+	defer td.locker.Unlock()
+
+	if td.opts.MaxNearDuplicates > 0 {
+		shape := urlShape(u)
+		if td.nearDuplicates[shape] > td.opts.MaxNearDuplicates {
+			return models.TrapNearDuplicates, fmt.Sprintf("pages of %s are near-duplicates", shape)
+		}
+	}
+
+	if td.opts.MaxQueryVariants > 0 && u.RawQuery != "" {
+		path := u.Host + u.EscapedPath()
+		variants, ok := td.queryVariants[path]
+		if !ok {
+			variants = make(map[string]bool)
+			td.queryVariants[path] = variants
+		}
+		query := u.Query().Encode()
+		if !variants[query] && len(variants) >= td.opts.MaxQueryVariants {
+			return models.TrapQueryVariants, fmt.Sprintf("more than %d query strings of %s", td.opts.MaxQueryVariants, path)
+		}
+		variants[query] = true
+	}
+	return "", ""
+}
+
+// CheckPage compares the fingerprint of the page text with the ones of the same URL shape;
+// the page becomes a trap when there are already too many near-duplicates of the shape
+func (td *trapDetector) CheckPage(location string, page readersModels.Page) (models.TrapKind, string) {
+	if td.opts.MaxNearDuplicates <= 0 || len(page.Body) == 0 {
+		return "", ""
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		return "", ""
+	}
+	text := td.parser.ParseHtmlText(page.Header.Get("Content-Type"), page.Body)
+	if text == "" {
+		return "", ""
+	}
+	fingerprint := utils.SimHash(text)
+	shape := urlShape(u)
+
+	td.locker.Lock()
+	defer td.locker.Unlock()
+
+	fingerprints := td.fingerprints[shape]
+	for _, f := range fingerprints {
+		if utils.HammingDistance(f, fingerprint) <= td.opts.NearDuplicateDistance {
+			td.nearDuplicates[shape]++
+			break
+		}
+	}
+	if len(fingerprints) < maxShapeFingerprints {
+		td.fingerprints[shape] = append(fingerprints, fingerprint)
+	}
+
+	if td.nearDuplicates[shape] > td.opts.MaxNearDuplicates {
+		return models.TrapNearDuplicates, fmt.Sprintf("pages of %s are near-duplicates", shape)
+	}
+	return "", ""
+}
+
+// sessionParam returns the name of the session parameter of URL, empty if there is no such
+func (td *trapDetector) sessionParam(u *url.URL) string {
+	if len(td.sessionParams) == 0 {
+		return ""
+	}
+	for name := range u.Query() {
+		if td.sessionParams[strings.ToLower(name)] {
+			return name
+		}
+	}
+	// path parameters, e.g. /cart;jsessionid=123
+	for _, segment := range strings.Split(u.Path, "/") {
+		for _, param := range strings.Split(segment, ";")[1:] {
+			name, _, _ := strings.Cut(param, "=")
+			if td.sessionParams[strings.ToLower(name)] {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// urlShape is URL without the parts which usually vary in generated URLs: numbers in the path and query values,
+// e.g. example.com/calendar/*/*?view for https://example.com/calendar/2024/05?view=month
+func urlShape(u *url.URL) string {
+	segments := strings.Split(u.EscapedPath(), "/")
+	for i, segment := range segments {
+		if strings.ContainsAny(segment, "0123456789") {
+			segments[i] = "*"
+		}
+	}
+
+	keys := make([]string, 0)
+	for k := range u.Query() {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	shape := strings.ToLower(u.Host) + strings.Join(segments, "/")
+	if len(keys) > 0 {
+		shape += "?" + strings.Join(keys, "&")
+	}
+	return shape
+}
@@ -0,0 +1,80 @@
+package crawlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"sitemap-generator/pkg/crawlers"
+	"sitemap-generator/pkg/crawlers/models"
+	readersModels "sitemap-generator/pkg/readers/models"
+	"sitemap-generator/utils"
+	"testing"
+)
+
+func TestTrapDetector_CheckUrl(t *testing.T) {
+	detector := crawlers.NewTrapDetector(crawlers.TrapDetectorOptions{
+		MaxSegmentRepeats: 2,
+		MaxUrlLength:      60,
+		MaxQueryVariants:  2,
+		SessionParams:     crawlers.DefaultSessionParams,
+	})
+
+	cases := []struct {
+		url  string
+		trap models.TrapKind
+	}{
+		{"https://example.com/a/b/a/", ""},
+		{"https://example.com/a/b/a/a/", models.TrapRepeatedSegments},
+		{"https://example.com/" + fmt.Sprintf("%060d", 0), models.TrapUrlLength},
+		{"https://example.com/cart?PHPSESSID=42", models.TrapSessionId},
+		{"https://example.com/cart;jsessionid=42", models.TrapSessionId},
+		{"https://example.com/search?q=1&color=red", ""},
+		{"https://example.com/search?color=red&q=1", ""},
+		{"https://example.com/search?q=2", ""},
+		{"https://example.com/search?q=3", models.TrapQueryVariants},
+		{"https://example.com/search?q=1&color=red", ""},
+		{"https://example.com/other?q=3", ""},
+	}
+	for _, c := range cases {
+		trap, _ := detector.CheckUrl(c.url)
+		utils.AssertEqual(t, trap, c.trap)
+	}
+
+	// nothing is detected by default
+	trap, _ := crawlers.NewTrapDetector(crawlers.TrapDetectorOptions{}).CheckUrl("https://example.com/a/a/a/a/?sid=1")
+	utils.AssertEmpty(t, trap)
+}
+
+func TestTrapDetector_CheckPage(t *testing.T) {
+	detector := crawlers.NewTrapDetector(crawlers.TrapDetectorOptions{
+		MaxNearDuplicates:     2,
+		NearDuplicateDistance: 3,
+	})
+	page := func(text string) readersModels.Page {
+		return readersModels.Page{
+			StatusCode: 200,
+			Header:     http.Header{"Content-Type": {"text/html"}},
+			Body:       []byte("<html><body><nav>2024</nav><main>" + text + "</main></body></html>"),
+		}
+	}
+	emptyMonth := page("Calendar of events. There are no events this month, please check other months.")
+
+	for month := 1; month <= 3; month++ {
+		trap, _ := detector.CheckPage(fmt.Sprintf("https://example.com/calendar/2024/%d", month), emptyMonth)
+		utils.AssertEmpty(t, trap)
+	}
+	// pages of other shapes and different pages of the same shape are fine
+	trap, _ := detector.CheckPage("https://example.com/events/", emptyMonth)
+	utils.AssertEmpty(t, trap)
+	trap, _ = detector.CheckPage("https://example.com/calendar/2024/4", page("Concert of the city orchestra at the central park on Sunday evening."))
+	utils.AssertEmpty(t, trap)
+
+	trap, details := detector.CheckPage("https://example.com/calendar/2024/5", emptyMonth)
+	utils.AssertEqual(t, trap, models.TrapNearDuplicates)
+	utils.AssertEqual(t, details, "pages of example.com/calendar/*/* are near-duplicates")
+
+	// the rest URLs of the shape are not read
+	trap, _ = detector.CheckUrl("https://example.com/calendar/2025/1")
+	utils.AssertEqual(t, trap, models.TrapNearDuplicates)
+	trap, _ = detector.CheckUrl("https://example.com/calendar/")
+	utils.AssertEmpty(t, trap)
+}
@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/pprof"
+)
+
+const namespace = "sitemap_generator_"
+
+// Metrics are the instruments of the crawl, they're shared by the readers, worker pools and crawlers of all the sites
+type Metrics struct {
+	// Requests is the number of HTTP requests by method and status code ("error" when there is no response)
+	Requests Counter
+	// RequestDuration is the time until the response headers by method, in seconds
+	RequestDuration Histogram
+	// Retries is the number of requests made again after the failure
+	Retries Counter
+	// Workers is the number of workers of the running pools
+	Workers Gauge
+	// BusyWorkers is the number of workers processing tasks
+	BusyWorkers Gauge
+	// QueuedTasks is the number of tasks waiting for a free worker
+	QueuedTasks Gauge
+	// TaskPanics is the number of panics recovered in the workers
+	TaskPanics Counter
+	// PagesFetched is the number of pages read to scan them for links
+	PagesFetched Counter
+	// FetchDuration is the time of the page reading with its body, in seconds
+	FetchDuration Histogram
+	// UrlsCollected is the number of URLs sent to the sitemap
+	UrlsCollected Counter
+	// UrlsSkipped is the number of skipped URLs by reason
+	UrlsSkipped Counter
+	// Errors is the number of URLs which could not be read or checked by operation
+	Errors Counter
+}
+
+// NewMetrics registers the instruments of the crawl in the registry
+func NewMetrics(registry Registry) *Metrics {
+	return &Metrics{
+		Requests:        registry.Counter(namespace+"requests_total", "HTTP requests by method and status code.", "method", "status"),
+		RequestDuration: registry.Histogram(namespace+"request_duration_seconds", "Time until HTTP response headers.", nil, "method"),
+		Retries:         registry.Counter(namespace+"retries_total", "HTTP requests made again after the failure."),
+		Workers:         registry.Gauge(namespace+"workers", "Workers of the running pools."),
+		BusyWorkers:     registry.Gauge(namespace+"busy_workers", "Workers processing tasks."),
+		QueuedTasks:     registry.Gauge(namespace+"queued_tasks", "Tasks waiting for a free worker."),
+		TaskPanics:      registry.Counter(namespace+"task_panics_total", "Panics recovered in the workers."),
+		PagesFetched:    registry.Counter(namespace+"pages_fetched_total", "Pages read to scan them for links."),
+		FetchDuration:   registry.Histogram(namespace+"fetch_duration_seconds", "Time of the page reading with its body.", nil),
+		UrlsCollected:   registry.Counter(namespace+"urls_collected_total", "URLs sent to the sitemap."),
+		UrlsSkipped:     registry.Counter(namespace+"urls_skipped_total", "Skipped URLs by reason.", "reason"),
+		Errors:          registry.Counter(namespace+"errors_total", "URLs which could not be read or checked by operation.", "op"),
+	}
+}
+
+// Handler serves the metrics of the registry in Prometheus text format
+func Handler(registry Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = registry.Write(w)
+	})
+}
+
+// NewServeMux serves the metrics at /metrics and the profiles of net/http/pprof at /debug/pprof/
+func NewServeMux(registry Registry) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler(registry))
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
@@ -0,0 +1,250 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+)
+
+// DefaultBuckets are upper bounds of the histogram buckets in seconds, they suit the latency of requests
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Counter only goes up, values of the labels are given in the order of their names
+type Counter interface {
+	Inc(labelValues ...string)
+	Add(v float64, labelValues ...string)
+}
+
+// Gauge goes up and down
+type Gauge interface {
+	Set(v float64, labelValues ...string)
+	Add(v float64, labelValues ...string)
+}
+
+// Histogram counts observed values in the buckets
+type Histogram interface {
+	Observe(v float64, labelValues ...string)
+}
+
+// Registry keeps the metrics to expose them in Prometheus text format; it's safe for concurrent use
+type Registry interface {
+	Counter(name, help string, labelNames ...string) Counter
+	Gauge(name, help string, labelNames ...string) Gauge
+	// Histogram uses DefaultBuckets if buckets are empty
+	Histogram(name, help string, buckets []float64, labelNames ...string) Histogram
+	// Write writes all the metrics sorted by name
+	Write(w io.Writer) error
+}
+
+type series struct {
+	labelValues []string
+	value       float64
+	// buckets, sum and count are of the histogram, the buckets are not cumulative
+	buckets []uint64
+	sum     float64
+	count   uint64
+}
+
+type family struct {
+	registry   *registry
+	name       string
+	help       string
+	kind       string
+	labelNames []string
+	buckets    []float64
+	series     map[string]*series
+}
+
+type registry struct {
+	locker   sync.Mutex
+	families map[string]*family
+}
+
+func NewRegistry() Registry {
+	return &registry{
+		families: make(map[string]*family),
+	}
+}
+
+func (r *registry) Counter(name, help string, labelNames ...string) Counter {
+	return r.register(name, help, typeCounter, nil, labelNames)
+}
+
+func (r *registry) Gauge(name, help string, labelNames ...string) Gauge {
+	return r.register(name, help, typeGauge, nil, labelNames)
+}
+
+func (r *registry) Histogram(name, help string, buckets []float64, labelNames ...string) Histogram {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	sorted := append([]float64{}, buckets...)
+	sort.Float64s(sorted)
+	return r.register(name, help, typeHistogram, sorted, labelNames)
+}
+
+// register returns the family of the name, the one registered before is shared
+func (r *registry) register(name, help, kind string, buckets []float64, labelNames []string) *family {
+	r.locker.Lock()
+	defer r.locker.Unlock()
+
+	if f, ok := r.families[name]; ok {
+		return f
+	}
+	f := &family{
+		registry:   r,
+		name:       name,
+		help:       help,
+		kind:       kind,
+		labelNames: labelNames,
+		buckets:    buckets,
+		series:     make(map[string]*series),
+	}
+	r.families[name] = f
+	return f
+}
+
+func (f *family) Inc(labelValues ...string) {
+	f.Add(1, labelValues...)
+}
+
+func (f *family) Add(v float64, labelValues ...string) {
+	f.registry.locker.Lock()
+	defer f.registry.locker.Unlock()
+
+	f.get(labelValues).value += v
+}
+
+func (f *family) Set(v float64, labelValues ...string) {
+	f.registry.locker.Lock()
+	defer f.registry.locker.Unlock()
+
+	f.get(labelValues).value = v
+}
+
+func (f *family) Observe(v float64, labelValues ...string) {
+	f.registry.locker.Lock()
+	defer f.registry.locker.Unlock()
+
+	s := f.get(labelValues)
+	for i, bound := range f.buckets {
+		if v <= bound {
+			s.buckets[i]++
+			break
+		}
+	}
+	s.sum += v
+	s.count++
+}
+
+// get returns the series of the label values, missing values are empty; it's called under the lock
+func (f *family) get(labelValues []string) *series {
+	values := make([]string, len(f.labelNames))
+	copy(values, labelValues)
+	key := strings.Join(values, "\xff")
+
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labelValues: values}
+		if f.kind == typeHistogram {
+			s.buckets = make([]uint64, len(f.buckets))
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+func (r *registry) Write(w io.Writer) error {
+	r.locker.Lock()
+	defer r.locker.Unlock()
+
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	bw := bufio.NewWriter(w)
+	for _, name := range names {
+		r.families[name].write(bw)
+	}
+	return bw.Flush()
+}
+
+// write writes the family in Prometheus text format, series are sorted by the label values
+func (f *family) write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.kind)
+
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := f.series[key]
+		if f.kind != typeHistogram {
+			fmt.Fprintf(w, "%s%s %s\n", f.name, f.labels(s.labelValues, "", 0), formatValue(s.value))
+			continue
+		}
+
+		var cumulative uint64
+		for i, bound := range f.buckets {
+			cumulative += s.buckets[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, f.labels(s.labelValues, "le", bound), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, f.labels(s.labelValues, "le", math.Inf(1)), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", f.name, f.labels(s.labelValues, "", 0), formatValue(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", f.name, f.labels(s.labelValues, "", 0), s.count)
+	}
+}
+
+// labels formats the labels of the series, the bucket label is added if its name is given
+func (f *family) labels(values []string, bucketLabel string, bound float64) string {
+	pairs := make([]string, 0, len(values)+1)
+	for i, name := range f.labelNames {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, name, escapeLabel(values[i])))
+	}
+	if bucketLabel != "" {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, bucketLabel, formatValue(bound)))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func escapeHelp(v string) string {
+	return helpEscaper.Replace(v)
+}
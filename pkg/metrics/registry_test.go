@@ -0,0 +1,94 @@
+package metrics_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sitemap-generator/pkg/metrics"
+	"sitemap-generator/utils"
+	"strings"
+	"testing"
+)
+
+func TestRegistry_Write(t *testing.T) {
+	registry := metrics.NewRegistry()
+	requests := registry.Counter("requests_total", "HTTP requests.", "method", "status")
+	queued := registry.Gauge("queued_tasks", "Queued tasks.")
+	duration := registry.Histogram("duration_seconds", "Duration.", []float64{1, 0.1})
+
+	requests.Inc("GET", "200")
+	requests.Add(2, "GET", "200")
+	requests.Inc("HEAD", "error")
+	requests.Inc(`"quoted"`, "200")
+	queued.Add(5)
+	queued.Add(-2)
+	duration.Observe(0.05)
+	duration.Observe(0.5)
+	duration.Observe(3)
+
+	out := new(strings.Builder)
+	utils.AssertNoError(t, registry.Write(out))
+	utils.AssertEqual(t, out.String(), `# HELP duration_seconds Duration.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{le="0.1"} 1
+duration_seconds_bucket{le="1"} 2
+duration_seconds_bucket{le="+Inf"} 3
+duration_seconds_sum 3.55
+duration_seconds_count 3
+# HELP queued_tasks Queued tasks.
+# TYPE queued_tasks gauge
+queued_tasks 3
+# HELP requests_total HTTP requests.
+# TYPE requests_total counter
+requests_total{method="\"quoted\"",status="200"} 1
+requests_total{method="GET",status="200"} 3
+requests_total{method="HEAD",status="error"} 1
+`)
+}
+
+func TestRegistry_SharedFamily(t *testing.T) {
+	registry := metrics.NewRegistry()
+	first := metrics.NewMetrics(registry)
+	second := metrics.NewMetrics(registry)
+
+	first.UrlsCollected.Inc()
+	second.UrlsCollected.Inc()
+
+	out := new(strings.Builder)
+	utils.AssertNoError(t, registry.Write(out))
+	utils.AssertTrue(t, strings.Contains(out.String(), "\nsitemap_generator_urls_collected_total 2\n"))
+}
+
+func TestNewServeMux(t *testing.T) {
+	registry := metrics.NewRegistry()
+	m := metrics.NewMetrics(registry)
+	m.Requests.Inc("GET", "404")
+	m.BusyWorkers.Set(4)
+
+	server := httptest.NewServer(metrics.NewServeMux(registry))
+	defer server.Close()
+
+	t.Run("metrics", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/metrics")
+		utils.AssertNoError(t, err)
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertNoError(t, err)
+
+		utils.AssertEqual(t, resp.StatusCode, http.StatusOK)
+		utils.AssertTrue(t, strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain; version=0.0.4"))
+		utils.AssertTrue(t, strings.Contains(string(body), "\nsitemap_generator_requests_total{method=\"GET\",status=\"404\"} 1\n"))
+		utils.AssertTrue(t, strings.Contains(string(body), "\nsitemap_generator_busy_workers 4\n"))
+	})
+
+	t.Run("pprof", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/debug/pprof/goroutine?debug=1")
+		utils.AssertNoError(t, err)
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertNoError(t, err)
+
+		utils.AssertEqual(t, resp.StatusCode, http.StatusOK)
+		utils.AssertTrue(t, strings.HasPrefix(string(body), "goroutine profile:"))
+	})
+}
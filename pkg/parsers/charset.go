@@ -0,0 +1,42 @@
+package parsers
+
+import (
+	"bufio"
+	"golang.org/x/net/html/charset"
+	"io"
+	"unicode/utf8"
+)
+
+// prescanLength is how many bytes are looked through to detect the encoding, the same as the charset package does
+const prescanLength = 1024
+
+// utf8Reader converts HTML doc to UTF-8 detecting its encoding by the BOM,
+// the charset of the content type and <meta> tags (in this order)
+func utf8Reader(r io.Reader, contentType string) io.Reader {
+	buffered := bufio.NewReaderSize(r, prescanLength)
+	prescan, _ := buffered.Peek(prescanLength)
+
+	encoding, name, certain := charset.DetermineEncoding(prescan, contentType)
+
+	// nothing is declared, so the guess is a legacy encoding, but most of such pages are UTF-8 indeed
+	if !certain && validUtf8Beginning(prescan) {
+		return buffered
+	}
+	if name == "utf-8" {
+		return buffered
+	}
+	return encoding.NewDecoder().Reader(buffered)
+}
+
+// validUtf8Beginning checks the beginning of the text which may end in the middle of a character
+func validUtf8Beginning(b []byte) bool {
+	for i := 1; i < utf8.UTFMax && i <= len(b); i++ {
+		if utf8.RuneStart(b[len(b)-i]) {
+			if !utf8.FullRune(b[len(b)-i:]) {
+				b = b[:len(b)-i]
+			}
+			break
+		}
+	}
+	return utf8.Valid(b)
+}
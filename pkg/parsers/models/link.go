@@ -0,0 +1,9 @@
+package models
+
+type Link struct {
+	Url string
+	// Element is the name of the HTML tag the link was extracted from
+	Element string
+	// Text is the text content of the element, e.g. anchor text
+	Text string
+}
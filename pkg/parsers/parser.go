@@ -3,11 +3,18 @@ package parsers
 import (
 	"bytes"
 	"golang.org/x/net/html"
+	"io"
 	"net/url"
+	"sitemap-generator/pkg/parsers/models"
+	"strings"
 )
 
 type Parser interface {
 	ParseHtmlForLinks(bodyUrl string, body []byte) []string
+	ParseHtml(bodyUrl string, contentType string, body []byte) []models.Link
+	ParseHtmlReader(bodyUrl string, contentType string, body io.Reader) []models.Link
+	ParseHtmlText(contentType string, body []byte) string
+	ParseHtmlCanonical(bodyUrl string, contentType string, body []byte) string
 }
 
 type parser struct {
@@ -19,16 +26,55 @@ func NewParser() Parser {
 
 // ParseHtmlForLinks parses HTML doc to find all <A> tags and extract URL (taking into account the <base> tag)
 func (p *parser) ParseHtmlForLinks(bodyUrl string, body []byte) []string {
-	urls := make([]string, 0)
-	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	links := p.ParseHtml(bodyUrl, "", body)
+
+	urls := make([]string, len(links))
+	for i, l := range links {
+		urls[i] = l.Url
+	}
+	return urls
+}
+
+// ParseHtml does the same as ParseHtmlForLinks but returns details of each link;
+// the doc is decoded to UTF-8 by the charset of the content type or the doc itself
+func (p *parser) ParseHtml(bodyUrl string, contentType string, body []byte) []models.Link {
+	return p.ParseHtmlReader(bodyUrl, contentType, bytes.NewReader(body))
+}
+
+// ParseHtmlReader does the same as ParseHtml but consumes the doc while it's being read;
+// if reading fails (e.g. the doc is truncated) the links found before are returned
+func (p *parser) ParseHtmlReader(bodyUrl string, contentType string, body io.Reader) []models.Link {
+	links := make([]models.Link, 0)
+	tokenizer := html.NewTokenizer(utf8Reader(body, contentType))
 
 	base := parseUrlWithoutFragment(bodyUrl)
+
+	// index of the link which text is being collected until its tag is closed
+	textOf := -1
+	var text strings.Builder
+
 	for {
 		next := tokenizer.Next()
 
 		// error or end of the HTML doc
 		if next == html.ErrorToken {
-			return urls
+			if textOf >= 0 {
+				links[textOf].Text = collapseSpaces(text.String())
+			}
+			return links
+		}
+
+		// text of the link
+		if next == html.TextToken && textOf >= 0 {
+			text.Write(tokenizer.Text())
+			continue
+		}
+		if next == html.EndTagToken && textOf >= 0 {
+			if name, _ := tokenizer.TagName(); string(name) == links[textOf].Element {
+				links[textOf].Text = collapseSpaces(text.String())
+				textOf = -1
+			}
+			continue
 		}
 
 		// HTML tag appeared
@@ -49,7 +95,14 @@ func (p *parser) ParseHtmlForLinks(bodyUrl string, body []byte) []string {
 							if a.Scheme == "" {
 								a.Scheme = "http"
 							}
-							urls = append(urls, a.String())
+							links = append(links, models.Link{
+								Url:     a.String(),
+								Element: token.Data,
+							})
+							if next == html.StartTagToken {
+								textOf = len(links) - 1
+								text.Reset()
+							}
 						}
 					}
 				}
@@ -58,6 +111,86 @@ func (p *parser) ParseHtmlForLinks(bodyUrl string, body []byte) []string {
 	}
 }
 
+// ParseHtmlText extracts the main text of the HTML doc: the visible text without scripts, styles
+// and page chrome (navigation, header, footer, sidebars), so pages of the same site differ by their content only
+func (p *parser) ParseHtmlText(contentType string, body []byte) string {
+	tokenizer := html.NewTokenizer(utf8Reader(bytes.NewReader(body), contentType))
+
+	var text strings.Builder
+	// depth of the elements which content is skipped
+	skipped := 0
+	for {
+		next := tokenizer.Next()
+		switch next {
+		case html.ErrorToken:
+			return collapseSpaces(text.String())
+		case html.TextToken:
+			if skipped == 0 {
+				text.Write(tokenizer.Text())
+				text.WriteByte(' ')
+			}
+		case html.StartTagToken:
+			if name, _ := tokenizer.TagName(); skippedTextElements[string(name)] {
+				skipped++
+			}
+		case html.EndTagToken:
+			if name, _ := tokenizer.TagName(); skippedTextElements[string(name)] && skipped > 0 {
+				skipped--
+			}
+		}
+	}
+}
+
+// ParseHtmlCanonical returns absolute URL of <link rel="canonical"> of the HTML doc, empty if it's not declared
+func (p *parser) ParseHtmlCanonical(bodyUrl string, contentType string, body []byte) string {
+	tokenizer := html.NewTokenizer(utf8Reader(bytes.NewReader(body), contentType))
+	base := parseUrlWithoutFragment(bodyUrl)
+
+	for {
+		next := tokenizer.Next()
+		if next == html.ErrorToken {
+			return ""
+		}
+		if next != html.StartTagToken && next != html.SelfClosingTagToken {
+			continue
+		}
+
+		token := tokenizer.Token()
+		switch token.Data {
+		case "base":
+			if href := tokenAttrByKey(token, "href"); href != "" {
+				base = parseUrlWithoutFragment(href)
+			}
+		case "link":
+			if !strings.EqualFold(tokenAttrByKey(token, "rel"), "canonical") {
+				continue
+			}
+			canonical := parseUrlWithoutFragment(tokenAttrByKey(token, "href"))
+			if canonical == nil {
+				return ""
+			}
+			if !canonical.IsAbs() && base != nil {
+				canonical = base.ResolveReference(canonical)
+			}
+			return canonical.String()
+		case "body":
+			// the link is declared in the head only
+			return ""
+		}
+	}
+}
+
+var skippedTextElements = map[string]bool{
+	"script":   true,
+	"style":    true,
+	"noscript": true,
+	"template": true,
+	"nav":      true,
+	"header":   true,
+	"footer":   true,
+	"aside":    true,
+}
+
 func parseUrlWithoutFragment(v string) *url.URL {
 	if u, err := url.Parse(v); err == nil {
 		u.Fragment = ""
@@ -67,6 +200,10 @@ func parseUrlWithoutFragment(v string) *url.URL {
 	}
 }
 
+func collapseSpaces(v string) string {
+	return strings.Join(strings.Fields(v), " ")
+}
+
 func tokenAttrByKey(token html.Token, key string) string {
 	for _, a := range token.Attr {
 		if a.Key == key {
@@ -1,9 +1,14 @@
 package parsers_test
 
 import (
+	"errors"
+	"io"
 	"sitemap-generator/pkg/parsers"
+	"sitemap-generator/pkg/parsers/models"
 	"sitemap-generator/utils"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestParser_ParseHtmlForLinks(t *testing.T) {
@@ -109,3 +114,111 @@ func TestParser_ParseHtmlForLinks(t *testing.T) {
 		utils.AssertEqual(t, links, expected)
 	})
 }
+
+func TestParser_ParseHtml(t *testing.T) {
+	body := `<html>
+<body>
+    <ul>
+        <li><a href="faq.php">FAQ</a></li>
+        <li><a href="https://www.w3schools.com/protocol.php">
+            <b>Protocol</b> details
+        </a></li>
+        <li><a href="terms.php"><img src="terms.png"/></a></li>
+    </ul>
+</body>
+</html>`
+
+	expected := []models.Link{
+		{
+			Url:     "https://example.com/faq.php",
+			Element: "a",
+			Text:    "FAQ",
+		},
+		{
+			Url:     "https://www.w3schools.com/protocol.php",
+			Element: "a",
+			Text:    "Protocol details",
+		},
+		{
+			Url:     "https://example.com/terms.php",
+			Element: "a",
+		},
+	}
+
+	links := parsers.NewParser().ParseHtml("https://example.com/home", "", []byte(body))
+	utils.AssertEqual(t, links, expected)
+}
+
+func TestParser_ParseHtml_Charset(t *testing.T) {
+	parser := parsers.NewParser()
+	expected := []models.Link{
+		{
+			Url:     "https://example.com/%D0%BD%D0%BE%D0%B2%D0%BE%D1%81%D1%82%D0%B8",
+			Element: "a",
+			Text:    "Новости",
+		},
+	}
+
+	t.Run("content type charset", func(t *testing.T) {
+		// "новости" and "Новости" in Windows-1251
+		body := "<a href=\"/\xed\xee\xe2\xee\xf1\xf2\xe8\">\xcd\xee\xe2\xee\xf1\xf2\xe8</a>"
+		links := parser.ParseHtml("https://example.com/", "text/html; charset=windows-1251", []byte(body))
+		utils.AssertEqual(t, links, expected)
+	})
+
+	t.Run("meta charset", func(t *testing.T) {
+		body := "<html><head><meta charset=\"windows-1251\"></head>" +
+			"<body><a href=\"/\xed\xee\xe2\xee\xf1\xf2\xe8\">\xcd\xee\xe2\xee\xf1\xf2\xe8</a></body></html>"
+		links := parser.ParseHtml("https://example.com/", "text/html", []byte(body))
+		utils.AssertEqual(t, links, expected)
+	})
+
+	t.Run("undeclared UTF-8", func(t *testing.T) {
+		body := `<a href="/новости">Новости</a>`
+		links := parser.ParseHtml("https://example.com/", "", []byte(body))
+		utils.AssertEqual(t, links, expected)
+	})
+}
+
+func TestParser_ParseHtmlReader(t *testing.T) {
+	// the doc is cut off in the middle of the second link
+	body := io.MultiReader(
+		strings.NewReader(`<html><body><a href="/faq.php">FAQ</a><a href="/terms`),
+		iotest.ErrReader(errors.New("connection reset")),
+	)
+
+	expected := []models.Link{
+		{
+			Url:     "https://example.com/faq.php",
+			Element: "a",
+			Text:    "FAQ",
+		},
+	}
+
+	links := parsers.NewParser().ParseHtmlReader("https://example.com/home", "text/html", body)
+	utils.AssertEqual(t, links, expected)
+}
+
+func TestParser_ParseHtmlText(t *testing.T) {
+	body := `<html><head><title>Report</title><style>p { color: red; }</style></head>
+<body>
+  <header><nav><a href="/">Home</a> <a href="/blog">Blog</a></nav></header>
+  <main><h1>Annual   report</h1><p>Revenue <b>grew</b> by 5%.</p><script>track("view")</script></main>
+  <aside>Popular posts</aside>
+  <footer>&copy; Example</footer>
+</body></html>`
+
+	text := parsers.NewParser().ParseHtmlText("text/html; charset=utf-8", []byte(body))
+	utils.AssertEqual(t, text, "Report Annual report Revenue grew by 5%.")
+}
+
+func TestParser_ParseHtmlCanonical(t *testing.T) {
+	p := parsers.NewParser()
+
+	body := `<html><head><base href="https://example.com/docs/"><link rel="Canonical" href="guide#intro"></head><body></body></html>`
+	utils.AssertEqual(t, p.ParseHtmlCanonical("https://example.com/print/guide", "text/html", []byte(body)), "https://example.com/docs/guide")
+
+	// the link in the body is ignored
+	body = `<html><head></head><body><link rel="canonical" href="/other"></body></html>`
+	utils.AssertEmpty(t, p.ParseHtmlCanonical("https://example.com/page", "text/html", []byte(body)))
+}
@@ -0,0 +1,125 @@
+package readers
+
+import (
+	"container/list"
+	"sitemap-generator/pkg/readers/models"
+	"sitemap-generator/utils"
+	"sync"
+	"time"
+)
+
+// DefaultCachedChecks is how many checks are cached by default, it's about 100MB of memory
+const DefaultCachedChecks = 100000
+
+type CachedReaderOptions struct {
+	// TTL of the successful check, zero means it's cached until it's evicted
+	TTL time.Duration
+	// FailureTTL of the failed check, zero means it's not cached at all
+	FailureTTL time.Duration
+	// MaxEntries is how many checks are cached, the least recently used ones are evicted first;
+	// DefaultCachedChecks if it's zero
+	MaxEntries int
+}
+
+type cachedReader struct {
+	Reader
+
+	ttl        time.Duration
+	failureTTL time.Duration
+	maxEntries int
+
+	locker  sync.Mutex
+	entries map[string]*checkEntry
+	// recent is the list of the entries, the most recently used one is at the front
+	recent *list.List
+}
+
+// checkEntry is a result of the check shared by all who asked for it
+type checkEntry struct {
+	key     string
+	element *list.Element
+	done    chan struct{}
+	info    models.UrlInfo
+	err     error
+	expires time.Time
+}
+
+// NewCachedReader caches results of the URL checks of the given reader;
+// concurrent checks of the same URL wait for the single request instead of making their own ones
+func NewCachedReader(reader Reader, opts CachedReaderOptions) Reader {
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = DefaultCachedChecks
+	}
+	return &cachedReader{
+		Reader:     reader,
+		ttl:        opts.TTL,
+		failureTTL: opts.FailureTTL,
+		maxEntries: opts.MaxEntries,
+		entries:    make(map[string]*checkEntry),
+		recent:     list.New(),
+	}
+}
+
+func (cr *cachedReader) CheckUrl(url string) (info models.UrlInfo, err error) {
+	key := utils.NormalizeUrl(url)
+
+	cr.locker.Lock()
+	entry, exists := cr.entries[key]
+	if exists && cr.isExpired(entry) {
+		cr.remove(entry)
+		exists = false
+	}
+	if exists {
+		cr.recent.MoveToFront(entry.element)
+	} else {
+		entry = &checkEntry{key: key, done: make(chan struct{})}
+		entry.element = cr.recent.PushFront(entry)
+		cr.entries[key] = entry
+		// the evicted check in progress is still shared by the ones already waiting for it
+		if cr.recent.Len() > cr.maxEntries {
+			cr.remove(cr.recent.Back().Value.(*checkEntry))
+		}
+	}
+	cr.locker.Unlock()
+
+	// somebody else is already checking it, wait for the result
+	if exists {
+		<-entry.done
+		return entry.info, entry.err
+	}
+
+	entry.info, entry.err = cr.Reader.CheckUrl(url)
+
+	cr.locker.Lock()
+	if entry.err != nil {
+		if cr.failureTTL <= 0 {
+			cr.remove(entry)
+		} else {
+			entry.expires = time.Now().Add(cr.failureTTL)
+		}
+	} else if cr.ttl > 0 {
+		entry.expires = time.Now().Add(cr.ttl)
+	}
+	cr.locker.Unlock()
+
+	close(entry.done)
+	return entry.info, entry.err
+}
+
+// remove drops the entry from the cache, unless it's already replaced by another one
+func (cr *cachedReader) remove(entry *checkEntry) {
+	if cr.entries[entry.key] == entry {
+		delete(cr.entries, entry.key)
+	}
+	cr.recent.Remove(entry.element)
+}
+
+// isExpired tells if completed entry should be checked again, entries in progress never expire
+func (cr *cachedReader) isExpired(entry *checkEntry) bool {
+	select {
+	case <-entry.done:
+		return !entry.expires.IsZero() && time.Now().After(entry.expires)
+	default:
+		return false
+	}
+}
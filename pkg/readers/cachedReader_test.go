@@ -0,0 +1,112 @@
+package readers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sitemap-generator/pkg/readers"
+	"sitemap-generator/utils"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCachedReader_CheckUrl(t *testing.T) {
+	t.Run("concurrent checks share the single request", func(t *testing.T) {
+		var requests int64
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt64(&requests, 1)
+			time.Sleep(50 * time.Millisecond)
+			w.Header().Set("Content-Type", "text/html")
+		}))
+		defer srv.Close()
+
+		reader := readers.NewCachedReader(readers.NewReader(readers.ReaderOptions{MaxRetries: 1}), readers.CachedReaderOptions{})
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				info, err := reader.CheckUrl(srv.URL + "/page")
+				utils.AssertNoError(t, err)
+				utils.AssertTrue(t, info.IsHtml)
+			}()
+		}
+		wg.Wait()
+		utils.AssertEqual(t, atomic.LoadInt64(&requests), int64(1))
+
+		// completed result is reused for the same normalized URL
+		_, err := reader.CheckUrl(srv.URL + "/page#top")
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, atomic.LoadInt64(&requests), int64(1))
+
+		_, err = reader.CheckUrl(srv.URL + "/other")
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, atomic.LoadInt64(&requests), int64(2))
+	})
+
+	t.Run("failures are cached with TTL", func(t *testing.T) {
+		var requests int64
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt64(&requests, 1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer srv.Close()
+
+		reader := readers.NewCachedReader(readers.NewReader(readers.ReaderOptions{MaxRetries: 1}), readers.CachedReaderOptions{
+			FailureTTL: 100 * time.Millisecond,
+		})
+
+		_, err := reader.CheckUrl(srv.URL)
+		utils.AssertEqual(t, readers.ErrorStatusCode(err), http.StatusServiceUnavailable)
+		_, err = reader.CheckUrl(srv.URL)
+		utils.AssertEqual(t, readers.ErrorStatusCode(err), http.StatusServiceUnavailable)
+		utils.AssertEqual(t, atomic.LoadInt64(&requests), int64(1))
+
+		time.Sleep(150 * time.Millisecond)
+		_, err = reader.CheckUrl(srv.URL)
+		utils.AssertHasError(t, err, "503")
+		utils.AssertEqual(t, atomic.LoadInt64(&requests), int64(2))
+	})
+
+	t.Run("least recently used checks are evicted", func(t *testing.T) {
+		var requests int64
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt64(&requests, 1)
+			w.Header().Set("Content-Type", "text/html")
+		}))
+		defer srv.Close()
+
+		reader := readers.NewCachedReader(readers.NewReader(readers.ReaderOptions{MaxRetries: 1}), readers.CachedReaderOptions{
+			MaxEntries: 2,
+		})
+
+		for _, path := range []string{"/a", "/b", "/a", "/c"} {
+			_, err := reader.CheckUrl(srv.URL + path)
+			utils.AssertNoError(t, err)
+		}
+		utils.AssertEqual(t, atomic.LoadInt64(&requests), int64(3))
+
+		// /a is used after /b, so /b is evicted by /c
+		_, _ = reader.CheckUrl(srv.URL + "/a")
+		utils.AssertEqual(t, atomic.LoadInt64(&requests), int64(3))
+		_, _ = reader.CheckUrl(srv.URL + "/b")
+		utils.AssertEqual(t, atomic.LoadInt64(&requests), int64(4))
+	})
+
+	t.Run("failures are not cached without TTL", func(t *testing.T) {
+		var requests int64
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt64(&requests, 1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer srv.Close()
+
+		reader := readers.NewCachedReader(readers.NewReader(readers.ReaderOptions{MaxRetries: 1}), readers.CachedReaderOptions{})
+
+		_, _ = reader.CheckUrl(srv.URL)
+		_, _ = reader.CheckUrl(srv.URL)
+		utils.AssertEqual(t, atomic.LoadInt64(&requests), int64(2))
+	})
+}
@@ -0,0 +1,135 @@
+package readers
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// DefaultUserAgent is sent when no other user agent is configured
+const DefaultUserAgent = "sitemap-generator"
+
+// HostAuth is credentials sent only to the host, so they don't leak on redirects to other sites
+type HostAuth struct {
+	Host string
+	// Username and Password are for the basic auth
+	Username string
+	Password string
+	// Token is for the bearer auth, it's used instead of the basic one if set
+	Token string
+}
+
+func (ha HostAuth) header() string {
+	if ha.Token != "" {
+		return "Bearer " + ha.Token
+	}
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(ha.Username+":"+ha.Password))
+}
+
+// ParseHostAuth parses basic auth credentials in "host=username:password" form
+// or bearer token in "host=token" one if isToken is set
+func ParseHostAuth(v string, isToken bool) (HostAuth, error) {
+	host, credentials, ok := strings.Cut(v, "=")
+	if !ok || host == "" || credentials == "" {
+		return HostAuth{}, fmt.Errorf("invalid auth, host=credentials expected: %s", v)
+	}
+	auth := HostAuth{Host: strings.ToLower(host)}
+	if isToken {
+		auth.Token = credentials
+		return auth, nil
+	}
+	if auth.Username, auth.Password, ok = strings.Cut(credentials, ":"); !ok {
+		return HostAuth{}, fmt.Errorf("invalid basic auth, host=username:password expected: %s", v)
+	}
+	return auth, nil
+}
+
+type TLSOptions struct {
+	// CAFile is PEM bundle of certificates trusted in addition to the system ones
+	CAFile string
+	// CertFile and KeyFile are PEM client certificate and its key
+	CertFile string
+	KeyFile  string
+	// InsecureSkipVerify disables verification of server certificates
+	InsecureSkipVerify bool
+}
+
+// NewTLSConfig builds config of the options, nil means the default one is fine
+func NewTLSConfig(opts TLSOptions) (*tls.Config, error) {
+	if opts.CAFile == "" && opts.CertFile == "" && !opts.InsecureSkipVerify {
+		return nil, nil
+	}
+
+	config := &tls.Config{
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+	}
+
+	if opts.CAFile != "" {
+		pem, err := ioutil.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read CA file: %s", err.Error())
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", opts.CAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if opts.CertFile != "" {
+		keyFile := opts.KeyFile
+		if keyFile == "" {
+			// the key is often in the same file as the certificate
+			keyFile = opts.CertFile
+		}
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate: %s", err.Error())
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// headersTransport adds the configured headers and auth to every request
+type headersTransport struct {
+	userAgent string
+	headers   http.Header
+	auth      map[string]HostAuth
+	base      http.RoundTripper
+}
+
+func newHeadersTransport(base http.RoundTripper, userAgent string, headers http.Header, auth []HostAuth) http.RoundTripper {
+	authByHost := make(map[string]HostAuth)
+	for _, a := range auth {
+		authByHost[strings.ToLower(a.Host)] = a
+	}
+	return &headersTransport{
+		userAgent: userAgent,
+		headers:   headers,
+		auth:      authByHost,
+		base:      base,
+	}
+}
+
+func (ht *headersTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// the request must not be modified by the transport
+	req = req.Clone(req.Context())
+	for key, values := range ht.headers {
+		req.Header[key] = values
+	}
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", ht.userAgent)
+	}
+	if auth, ok := ht.auth[strings.ToLower(req.URL.Hostname())]; ok {
+		req.Header.Set("Authorization", auth.header())
+	}
+	return ht.base.RoundTrip(req)
+}
@@ -0,0 +1,167 @@
+package readers_test
+
+import (
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sitemap-generator/pkg/readers"
+	"sitemap-generator/utils"
+	"strings"
+	"testing"
+)
+
+func TestReader_Headers(t *testing.T) {
+	var requests []*http.Request
+	record := func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+	}
+	other := httptest.NewServer(http.HandlerFunc(record))
+	defer other.Close()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		record(w, r)
+		if r.URL.Path == "/away" {
+			// the same server by other host name
+			http.Redirect(w, r, strings.Replace(other.URL, "127.0.0.1", "localhost", 1), http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+
+	reader := readers.NewReader(readers.ReaderOptions{
+		MaxRetries:   1,
+		MaxRedirects: 3,
+		UserAgent:    "test-agent",
+		Headers:      http.Header{"X-Staging": {"yes"}},
+		Auth: []readers.HostAuth{
+			{Host: "127.0.0.1", Username: "user", Password: "secret"},
+		},
+	})
+
+	t.Run("headers and auth are sent", func(t *testing.T) {
+		requests = nil
+		_, err := reader.ReadUrl(srv.URL + "/")
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, len(requests), 1)
+		utils.AssertEqual(t, requests[0].UserAgent(), "test-agent")
+		utils.AssertEqual(t, requests[0].Header.Get("X-Staging"), "yes")
+		username, password, ok := requests[0].BasicAuth()
+		utils.AssertTrue(t, ok)
+		utils.AssertEqual(t, username, "user")
+		utils.AssertEqual(t, password, "secret")
+	})
+
+	t.Run("auth is not sent to other hosts", func(t *testing.T) {
+		requests = nil
+		_, err := reader.ReadUrl(srv.URL + "/away")
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, len(requests), 2)
+		utils.AssertEqual(t, requests[1].Header.Get("X-Staging"), "yes")
+		utils.AssertEmpty(t, requests[1].Header.Get("Authorization"))
+	})
+
+	t.Run("default user agent", func(t *testing.T) {
+		requests = nil
+		_, err := readers.NewReader(readers.ReaderOptions{MaxRetries: 1}).ReadUrl(srv.URL + "/")
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, requests[0].UserAgent(), readers.DefaultUserAgent)
+	})
+}
+
+func TestParseHostAuth(t *testing.T) {
+	auth, err := readers.ParseHostAuth("Example.com=user:pass:word", false)
+	utils.AssertNoError(t, err)
+	utils.AssertEqual(t, auth, readers.HostAuth{Host: "example.com", Username: "user", Password: "pass:word"})
+
+	auth, err = readers.ParseHostAuth("example.com=abc", true)
+	utils.AssertNoError(t, err)
+	utils.AssertEqual(t, auth, readers.HostAuth{Host: "example.com", Token: "abc"})
+
+	_, err = readers.ParseHostAuth("example.com=user", false)
+	utils.AssertHasError(t, err, "invalid basic auth")
+	_, err = readers.ParseHostAuth("example.com", true)
+	utils.AssertHasError(t, err, "invalid auth")
+}
+
+func TestNewCookieJar(t *testing.T) {
+	var cookies []*http.Cookie
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookies = r.Cookies()
+	}))
+	defer srv.Close()
+
+	file := filepath.Join(t.TempDir(), "cookies.txt")
+	content := "# Netscape HTTP Cookie File\n\n" +
+		"127.0.0.1\tFALSE\t/\tFALSE\t0\tsession\tabc\n" +
+		"#HttpOnly_127.0.0.1\tFALSE\t/members\tFALSE\t0\tmember\tyes\n" +
+		"127.0.0.1\tFALSE\t/\tFALSE\t1\texpired\tyes\n"
+	utils.AssertNoError(t, os.WriteFile(file, []byte(content), 0644))
+
+	jar, err := readers.NewCookieJar(file)
+	utils.AssertNoError(t, err)
+	reader := readers.NewReader(readers.ReaderOptions{MaxRetries: 1, CookieJar: jar})
+
+	_, err = reader.ReadUrl(srv.URL + "/")
+	utils.AssertNoError(t, err)
+	utils.AssertEqual(t, fmt.Sprint(cookies), "[session=abc]")
+
+	_, err = reader.ReadUrl(srv.URL + "/members/")
+	utils.AssertNoError(t, err)
+	utils.AssertEqual(t, fmt.Sprint(cookies), "[member=yes session=abc]")
+
+	utils.AssertNoError(t, os.WriteFile(file, []byte("127.0.0.1\tFALSE\t/\n"), 0644))
+	_, err = readers.NewCookieJar(file)
+	utils.AssertHasError(t, err, "invalid cookie at line 1")
+}
+
+func TestReader_Proxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+	}))
+	defer proxy.Close()
+
+	proxyUrl, _ := url.Parse(proxy.URL)
+	reader := readers.NewReader(readers.ReaderOptions{MaxRetries: 1, Proxy: proxyUrl})
+
+	_, err := reader.ReadUrl("http://example.com/page")
+	utils.AssertNoError(t, err)
+	utils.AssertEqual(t, proxied, "http://example.com/page")
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	t.Run("untrusted certificate", func(t *testing.T) {
+		_, err := readers.NewReader(readers.ReaderOptions{MaxRetries: 1}).ReadUrl(srv.URL)
+		utils.AssertHasError(t, err, "certificate")
+	})
+
+	t.Run("insecure", func(t *testing.T) {
+		config, err := readers.NewTLSConfig(readers.TLSOptions{InsecureSkipVerify: true})
+		utils.AssertNoError(t, err)
+		_, err = readers.NewReader(readers.ReaderOptions{MaxRetries: 1, TLSConfig: config}).ReadUrl(srv.URL)
+		utils.AssertNoError(t, err)
+	})
+
+	t.Run("CA bundle", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "ca.pem")
+		ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+		utils.AssertNoError(t, os.WriteFile(file, ca, 0644))
+
+		config, err := readers.NewTLSConfig(readers.TLSOptions{CAFile: file})
+		utils.AssertNoError(t, err)
+		_, err = readers.NewReader(readers.ReaderOptions{MaxRetries: 1, TLSConfig: config}).ReadUrl(srv.URL)
+		utils.AssertNoError(t, err)
+	})
+
+	t.Run("no certificates", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "ca.pem")
+		utils.AssertNoError(t, os.WriteFile(file, []byte("nothing"), 0644))
+		_, err := readers.NewTLSConfig(readers.TLSOptions{CAFile: file})
+		utils.AssertHasError(t, err, "no certificates found")
+	})
+}
@@ -0,0 +1,175 @@
+package readers
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"github.com/andybalholm/brotli"
+	"io"
+	"net/http"
+	"sitemap-generator/pkg/readers/models"
+	"strings"
+)
+
+// DefaultAcceptEncodings are the content encodings the reader can decode
+var DefaultAcceptEncodings = []string{"gzip", "deflate", "br"}
+
+// ValidateAcceptEncodings checks if the reader can decode all the encodings
+func ValidateAcceptEncodings(encodings []string) error {
+	for _, e := range encodings {
+		switch e {
+		case "gzip", "deflate", "br", "identity":
+		default:
+			return fmt.Errorf("unsupported content encoding: %s", e)
+		}
+	}
+	return nil
+}
+
+// compressionTransport asks for the compressed content and decodes it,
+// counting bytes of the body on the wire and decoded ones
+type compressionTransport struct {
+	acceptEncoding string
+	base           http.RoundTripper
+}
+
+func newCompressionTransport(base http.RoundTripper, encodings []string) http.RoundTripper {
+	return &compressionTransport{
+		acceptEncoding: strings.Join(encodings, ", "),
+		base:           base,
+	}
+}
+
+func (ct *compressionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if ct.acceptEncoding != "" && req.Header.Get("Accept-Encoding") == "" {
+		// the request must not be modified by the transport
+		req = req.Clone(req.Context())
+		req.Header.Set("Accept-Encoding", ct.acceptEncoding)
+	}
+
+	resp, err := ct.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	if encoding == "identity" {
+		encoding = ""
+	}
+	body := &transferBody{
+		encoding: encoding,
+		wire:     &countingReader{r: resp.Body},
+		closer:   resp.Body,
+	}
+	switch encoding {
+	case "":
+	case "gzip", "x-gzip", "deflate", "br":
+		// the decoded body has other length and encoding
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Del("Content-Length")
+		resp.ContentLength = -1
+		resp.Uncompressed = true
+	default:
+		// unknown encoding is left as is
+		body.encoding = ""
+	}
+	resp.Body = body
+	if holder, ok := req.Context().Value(transferKey{}).(*transferHolder); ok {
+		holder.body = body
+	}
+	return resp, nil
+}
+
+// transferBody decodes the body lazily, so bodies of HEAD requests and empty ones are not decoded at all
+type transferBody struct {
+	encoding string
+	wire     *countingReader
+	closer   io.Closer
+	decoder  io.Reader
+	decoded  int64
+	err      error
+}
+
+func (tb *transferBody) Read(p []byte) (int, error) {
+	if tb.err != nil {
+		return 0, tb.err
+	}
+	if tb.decoder == nil {
+		if tb.decoder, tb.err = newDecoder(tb.encoding, tb.wire); tb.err != nil {
+			return 0, tb.err
+		}
+	}
+	n, err := tb.decoder.Read(p)
+	tb.decoded += int64(n)
+	return n, err
+}
+
+func (tb *transferBody) Close() error {
+	return tb.closer.Close()
+}
+
+func (tb *transferBody) transfer() models.Transfer {
+	return models.Transfer{
+		Encoding:     tb.encoding,
+		WireBytes:    tb.wire.n,
+		DecodedBytes: tb.decoded,
+	}
+}
+
+func newDecoder(encoding string, r io.Reader) (io.Reader, error) {
+	switch encoding {
+	case "gzip", "x-gzip":
+		return gzip.NewReader(r)
+	case "deflate":
+		// deflate should be zlib wrapped, but some servers send raw deflate data
+		buffered := bufio.NewReader(r)
+		if header, err := buffered.Peek(2); err == nil && isZlibHeader(header) {
+			return zlib.NewReader(buffered)
+		}
+		return flate.NewReader(buffered), nil
+	case "br":
+		return brotli.NewReader(r), nil
+	default:
+		return r, nil
+	}
+}
+
+func isZlibHeader(h []byte) bool {
+	return h[0]&0x0f == 8 && (uint16(h[0])<<8|uint16(h[1]))%31 == 0
+}
+
+// transferKey is the key of *transferHolder in the request context
+type transferKey struct{}
+
+// transferHolder keeps the body of the last response to the request (i.e. after redirects);
+// the body is found by the request, since the client may wrap it (e.g. when the client timeout is set)
+type transferHolder struct {
+	body *transferBody
+}
+
+// withTransfer makes bytes transferred for the response to the request known to transferOf
+func withTransfer(req *http.Request) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), transferKey{}, &transferHolder{}))
+}
+
+// transferOf tells bytes transferred for the response body which is already read
+func transferOf(resp *http.Response) models.Transfer {
+	if holder, ok := resp.Request.Context().Value(transferKey{}).(*transferHolder); ok && holder.body != nil {
+		return holder.body.transfer()
+	}
+	return models.Transfer{}
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
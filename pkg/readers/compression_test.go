@@ -0,0 +1,90 @@
+package readers_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"github.com/andybalholm/brotli"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sitemap-generator/pkg/readers"
+	"sitemap-generator/utils"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReader_ReadUrl_Compression(t *testing.T) {
+	content := "<html><body>" + strings.Repeat("<p>Hello, world!</p>", 100) + "</body></html>"
+
+	encoders := map[string]func(w io.Writer) io.WriteCloser{
+		"gzip":    func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+		"deflate": func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) },
+		"br":      func(w io.Writer) io.WriteCloser { return brotli.NewWriter(w) },
+	}
+
+	var acceptEncoding string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acceptEncoding = r.Header.Get("Accept-Encoding")
+		w.Header().Set("Content-Type", "text/html")
+
+		encoding := r.URL.Query().Get("encoding")
+		encoder, ok := encoders[encoding]
+		if !ok {
+			_, _ = w.Write([]byte(content))
+			return
+		}
+		w.Header().Set("Content-Encoding", encoding)
+		buffer := new(bytes.Buffer)
+		ew := encoder(buffer)
+		_, _ = ew.Write([]byte(content))
+		_ = ew.Close()
+		_, _ = w.Write(buffer.Bytes())
+	}))
+	defer srv.Close()
+
+	reader := readers.NewReader(readers.ReaderOptions{MaxRetries: 1})
+	for encoding := range encoders {
+		t.Run(encoding, func(t *testing.T) {
+			page, err := reader.ReadUrl(srv.URL + "/?encoding=" + encoding)
+			utils.AssertNoError(t, err)
+			utils.AssertEqual(t, acceptEncoding, "gzip, deflate, br")
+			utils.AssertEqual(t, string(page.Body), content)
+			utils.AssertEqual(t, page.Transfer.Encoding, encoding)
+			utils.AssertEqual(t, page.Transfer.DecodedBytes, int64(len(content)))
+			utils.AssertTrue(t, page.Transfer.WireBytes < page.Transfer.DecodedBytes)
+			utils.AssertEmpty(t, page.Header.Get("Content-Encoding"))
+		})
+	}
+
+	t.Run("client timeout", func(t *testing.T) {
+		// the client wraps the body when its timeout is set, but the transfer is still counted
+		timeoutReader := readers.NewReader(readers.ReaderOptions{MaxRetries: 1, Timeout: 5 * time.Second})
+
+		page, err := timeoutReader.ReadUrl(srv.URL + "/?encoding=gzip")
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, string(page.Body), content)
+		utils.AssertEqual(t, page.Transfer.Encoding, "gzip")
+		utils.AssertEqual(t, page.Transfer.DecodedBytes, int64(len(content)))
+		utils.AssertTrue(t, page.Transfer.WireBytes > 0 && page.Transfer.WireBytes < page.Transfer.DecodedBytes)
+
+		page, err = timeoutReader.FetchUrl(srv.URL + "/")
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, page.Transfer.WireBytes, int64(len(content)))
+		utils.AssertEqual(t, page.Transfer.DecodedBytes, int64(len(content)))
+	})
+
+	t.Run("identity", func(t *testing.T) {
+		page, err := readers.NewReader(readers.ReaderOptions{
+			MaxRetries:      1,
+			AcceptEncodings: []string{"identity"},
+		}).FetchUrl(srv.URL + "/")
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, acceptEncoding, "identity")
+		utils.AssertEqual(t, string(page.Body), content)
+		utils.AssertEmpty(t, page.Transfer.Encoding)
+		utils.AssertEqual(t, page.Transfer.WireBytes, int64(len(content)))
+		utils.AssertEqual(t, page.Transfer.DecodedBytes, int64(len(content)))
+	})
+}
@@ -0,0 +1,86 @@
+package readers
+
+import (
+	"mime"
+	"net/http"
+	"sitemap-generator/pkg/readers/models"
+	"strings"
+)
+
+// sniffLength is how many bytes of the content are enough to detect its type
+const sniffLength = 512
+
+// documentTypes are not HTML but still worth to be in the sitemap
+var documentTypes = map[string]bool{
+	"application/pdf":               true,
+	"application/msword":            true,
+	"application/rtf":               true,
+	"application/epub+zip":          true,
+	"application/vnd.ms-excel":      true,
+	"application/vnd.ms-powerpoint": true,
+	"text/plain":                    true,
+	"text/csv":                      true,
+}
+
+// documentTypePrefixes are families of office documents
+var documentTypePrefixes = []string{
+	"application/vnd.openxmlformats-officedocument.",
+	"application/vnd.oasis.opendocument.",
+}
+
+// ClassifyContentType tells which class the media type belongs to
+func ClassifyContentType(contentType string) models.ContentClass {
+	contentType = mediaType(contentType)
+
+	switch {
+	case contentType == "text/html" || contentType == "application/xhtml+xml":
+		return models.ContentClassHtml
+	case documentTypes[contentType]:
+		return models.ContentClassDocument
+	case strings.HasPrefix(contentType, "image/"):
+		return models.ContentClassImage
+	case strings.HasPrefix(contentType, "video/") || strings.HasPrefix(contentType, "audio/"):
+		return models.ContentClassMedia
+	}
+
+	for _, prefix := range documentTypePrefixes {
+		if strings.HasPrefix(contentType, prefix) {
+			return models.ContentClassDocument
+		}
+	}
+	return models.ContentClassOther
+}
+
+// classifyContent detects type of the content by its declared type and its first bytes
+// (if they're given and sniffing is on, they may be read for other reasons)
+func (r *reader) classifyContent(declared string, sniffed []byte) (string, models.ContentClass) {
+	contentType := mediaType(declared)
+	if r.ignoreContentType {
+		contentType = ""
+	}
+	if r.sniffContent && isGenericContentType(contentType) && sniffed != nil {
+		contentType = mediaType(http.DetectContentType(sniffed))
+	}
+	return contentType, ClassifyContentType(contentType)
+}
+
+// needsSniffing tells if the declared type is not enough to classify the content
+func (r *reader) needsSniffing(declared string) bool {
+	return r.sniffContent && (r.ignoreContentType || isGenericContentType(mediaType(declared)))
+}
+
+// isGenericContentType tells if the type could be declared by the misconfigured server for any content
+func isGenericContentType(contentType string) bool {
+	return contentType == "" || contentType == "text/plain" || contentType == "application/octet-stream"
+}
+
+// mediaType returns lower case type without parameters
+func mediaType(contentType string) string {
+	if contentType == "" {
+		return ""
+	}
+	if parsed, _, err := mime.ParseMediaType(contentType); err == nil {
+		return parsed
+	}
+	return strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+}
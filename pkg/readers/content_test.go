@@ -0,0 +1,100 @@
+package readers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sitemap-generator/pkg/readers"
+	"sitemap-generator/pkg/readers/models"
+	"sitemap-generator/utils"
+	"testing"
+)
+
+func TestClassifyContentType(t *testing.T) {
+	cases := map[string]models.ContentClass{
+		"text/html":                               models.ContentClassHtml,
+		"TEXT/HTML; charset=UTF-8":                models.ContentClassHtml,
+		"application/xhtml+xml":                   models.ContentClassHtml,
+		"application/pdf":                         models.ContentClassDocument,
+		"application/vnd.oasis.opendocument.text": models.ContentClassDocument,
+		"image/png":                               models.ContentClassImage,
+		"video/mp4":                               models.ContentClassMedia,
+		"application/json":                        models.ContentClassOther,
+		"":                                        models.ContentClassOther,
+	}
+	for contentType, expected := range cases {
+		utils.AssertEqual(t, readers.ClassifyContentType(contentType), expected)
+	}
+}
+
+func TestReader_CheckUrl_ContentSniffing(t *testing.T) {
+	html := []byte("<!DOCTYPE html><html><body><a href=\"/\">Home</a></body></html>")
+	pdf := []byte("%PDF-1.4 some document")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/plain-html", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write(html)
+	})
+	mux.HandleFunc("/untyped-pdf", func(w http.ResponseWriter, r *http.Request) {
+		w.Header()["Content-Type"] = nil
+		_, _ = w.Write(pdf)
+	})
+	mux.HandleFunc("/xhtml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "Application/XHTML+XML; charset=utf-8")
+		_, _ = w.Write(pdf)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	t.Run("declared type only", func(t *testing.T) {
+		reader := readers.NewReader(readers.ReaderOptions{MaxRetries: 1})
+
+		info, err := reader.CheckUrl(srv.URL + "/plain-html")
+		utils.AssertNoError(t, err)
+		utils.AssertFalse(t, info.IsHtml)
+		utils.AssertEqual(t, info.ContentClass, models.ContentClassDocument)
+
+		info, err = reader.CheckUrl(srv.URL + "/xhtml")
+		utils.AssertNoError(t, err)
+		utils.AssertTrue(t, info.IsHtml)
+		utils.AssertEqual(t, info.ContentType, "application/xhtml+xml")
+
+		// the read content is not sniffed either
+		page, err := reader.ReadUrl(srv.URL + "/plain-html")
+		utils.AssertNoError(t, err)
+		utils.AssertFalse(t, page.Info.IsHtml)
+		utils.AssertEqual(t, page.Info.ContentType, "text/plain")
+	})
+
+	t.Run("sniffing of generic types", func(t *testing.T) {
+		reader := readers.NewReader(readers.ReaderOptions{MaxRetries: 1, SniffContent: true})
+
+		info, err := reader.CheckUrl(srv.URL + "/plain-html")
+		utils.AssertNoError(t, err)
+		utils.AssertTrue(t, info.IsHtml)
+
+		info, err = reader.CheckUrl(srv.URL + "/untyped-pdf")
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, info.ContentType, "application/pdf")
+		utils.AssertEqual(t, info.ContentClass, models.ContentClassDocument)
+
+		// specific declared type is trusted
+		info, err = reader.CheckUrl(srv.URL + "/xhtml")
+		utils.AssertNoError(t, err)
+		utils.AssertTrue(t, info.IsHtml)
+
+		page, err := reader.FetchUrl(srv.URL + "/plain-html")
+		utils.AssertNoError(t, err)
+		utils.AssertTrue(t, page.Info.IsHtml)
+		utils.AssertEqual(t, string(page.Body), string(html))
+	})
+
+	t.Run("declared type is ignored", func(t *testing.T) {
+		reader := readers.NewReader(readers.ReaderOptions{MaxRetries: 1, SniffContent: true, IgnoreContentType: true})
+
+		info, err := reader.CheckUrl(srv.URL + "/xhtml")
+		utils.AssertNoError(t, err)
+		utils.AssertFalse(t, info.IsHtml)
+		utils.AssertEqual(t, info.ContentClass, models.ContentClassDocument)
+	})
+}
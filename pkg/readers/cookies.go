@@ -0,0 +1,95 @@
+package readers
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// httpOnlyPrefix marks http only cookies in the Netscape cookies file (as curl writes them)
+const httpOnlyPrefix = "#HttpOnly_"
+
+// NewCookieJar creates cookie jar preloaded from the Netscape cookies file (as curl or browser extensions export them),
+// the jar is empty if the file isn't set
+func NewCookieJar(netscapeFile string) (http.CookieJar, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	if netscapeFile == "" {
+		return jar, nil
+	}
+
+	f, err := os.Open(netscapeFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not open cookies file: %s", err.Error())
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		u, cookie, err := parseNetscapeCookie(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("invalid cookie at line %d of %s: %s", line, netscapeFile, err.Error())
+		}
+		if cookie != nil {
+			jar.SetCookies(u, []*http.Cookie{cookie})
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read cookies file: %s", err.Error())
+	}
+	return jar, nil
+}
+
+// parseNetscapeCookie parses the line of tab separated fields:
+// domain, include subdomains, path, secure, expiration time, name and value;
+// nil cookie is returned for comments and empty lines
+func parseNetscapeCookie(line string) (*url.URL, *http.Cookie, error) {
+	httpOnly := strings.HasPrefix(line, httpOnlyPrefix)
+	if httpOnly {
+		line = line[len(httpOnlyPrefix):]
+	}
+	if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+		return nil, nil, nil
+	}
+
+	fields := strings.Split(line, "\t")
+	if len(fields) != 7 {
+		return nil, nil, fmt.Errorf("7 tab separated fields expected, got %d", len(fields))
+	}
+	expires, err := strconv.ParseInt(fields[4], 10, 64)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid expiration time: %s", fields[4])
+	}
+
+	host := strings.TrimPrefix(fields[0], ".")
+	secure := strings.EqualFold(fields[3], "TRUE")
+	cookie := &http.Cookie{
+		Name:     fields[5],
+		Value:    fields[6],
+		Path:     fields[2],
+		Secure:   secure,
+		HttpOnly: httpOnly,
+	}
+	// host only cookie has no domain
+	if strings.EqualFold(fields[1], "TRUE") {
+		cookie.Domain = host
+	}
+	// zero means session cookie
+	if expires > 0 {
+		cookie.Expires = time.Unix(expires, 0)
+	}
+
+	scheme := "http"
+	if secure {
+		scheme = "https"
+	}
+	return &url.URL{Scheme: scheme, Host: host, Path: cookie.Path}, cookie, nil
+}
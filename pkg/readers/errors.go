@@ -0,0 +1,150 @@
+package readers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sitemap-generator/pkg/readers/models"
+)
+
+const (
+	ErrorKindHttp             = "http"
+	ErrorKindTimeout          = "timeout"
+	ErrorKindTooManyRedirects = "too-many-redirects"
+	ErrorKindRedirectLoop     = "redirect-loop"
+	ErrorKindConnection       = "connection"
+	ErrorKindAuth             = "auth"
+	ErrorKindOffline          = "offline"
+)
+
+// HttpError means server responded with an error status
+type HttpError struct {
+	StatusCode int
+	Status     string
+	Redirects  []models.Redirect
+}
+
+func (e *HttpError) Error() string {
+	return fmt.Sprintf("HTTP error [%d] %s", e.StatusCode, e.Status)
+}
+
+// TransportError means response was not received
+type TransportError struct {
+	Err       error
+	Redirects []models.Redirect
+}
+
+func (e *TransportError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+// AuthError means the session is expired and the login didn't help,
+// so the response is the login page instead of the requested one
+type AuthError struct {
+	Err error
+}
+
+func (e *AuthError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *AuthError) Unwrap() error {
+	return e.Err
+}
+
+// OfflineError means URL is not served by the offline reader, e.g. it's outside of the root directory,
+// so it's not retried and the crawler skips it instead of reporting it as broken
+type OfflineError struct {
+	Err error
+}
+
+func (e *OfflineError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *OfflineError) Unwrap() error {
+	return e.Err
+}
+
+// IsOffline tells if URL could not be read because it's not served offline
+func IsOffline(err error) bool {
+	var offlineErr *OfflineError
+	return errors.As(err, &offlineErr)
+}
+
+// ErrorKind classifies the reading error to one of ErrorKind* values
+func ErrorKind(err error) string {
+	var httpErr *HttpError
+	var authErr *AuthError
+	switch {
+	case errors.As(err, &httpErr):
+		return ErrorKindHttp
+	case errors.As(err, &authErr):
+		return ErrorKindAuth
+	case IsOffline(err):
+		return ErrorKindOffline
+	case IsTimeout(err):
+		return ErrorKindTimeout
+	case IsRedirectLoop(err):
+		return ErrorKindRedirectLoop
+	case IsTooManyRedirects(err):
+		return ErrorKindTooManyRedirects
+	default:
+		return ErrorKindConnection
+	}
+}
+
+// ErrorStatusCode returns HTTP status of the response if the error is caused by it
+func ErrorStatusCode(err error) int {
+	var httpErr *HttpError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode
+	}
+	return 0
+}
+
+// ErrorRedirects returns redirects which were followed before the error
+func ErrorRedirects(err error) []models.Redirect {
+	var httpErr *HttpError
+	if errors.As(err, &httpErr) {
+		return httpErr.Redirects
+	}
+	var transportErr *TransportError
+	if errors.As(err, &transportErr) {
+		return transportErr.Redirects
+	}
+	return nil
+}
+
+// redirectChain restores the redirects followed by the client to get the response
+func redirectChain(resp *http.Response) []models.Redirect {
+	if resp == nil {
+		return nil
+	}
+
+	chain := make([]models.Redirect, 0)
+	for req := resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		chain = append([]models.Redirect{{
+			StatusCode: req.Response.StatusCode,
+			Location:   req.URL.String(),
+		}}, chain...)
+	}
+
+	// the last response is the redirect itself when the client stopped following them
+	if resp.StatusCode >= 300 && resp.StatusCode < 400 && resp.Header.Get("Location") != "" {
+		if location, err := resp.Location(); err == nil {
+			chain = append(chain, models.Redirect{
+				StatusCode: resp.StatusCode,
+				Location:   location.String(),
+			})
+		}
+	}
+	if len(chain) == 0 {
+		return nil
+	}
+	return chain
+}
@@ -0,0 +1,79 @@
+package readers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// NewFileReader serves the site from the local directory (e.g. generated by a static site generator)
+// as if it's deployed at the base URL: directory URLs are resolved to their index.html,
+// content type is inferred from the file extension and modification time is taken from the file;
+// URLs outside of the base one can't be read, they fail with OfflineError
+func NewFileReader(baseUrl string, rootDir string, opts ReaderOptions) (Reader, error) {
+	base, err := url.Parse(baseUrl)
+	if err != nil || base.Host == "" {
+		return nil, fmt.Errorf("invalid base URL: %s", baseUrl)
+	}
+
+	// the directory can be set as file:// URL too
+	if strings.HasPrefix(rootDir, "file://") {
+		u, err := url.Parse(rootDir)
+		if err != nil {
+			return nil, fmt.Errorf("invalid root directory: %s", rootDir)
+		}
+		rootDir = filepath.FromSlash(u.Path)
+	}
+	if stat, err := os.Stat(rootDir); err != nil || !stat.IsDir() {
+		return nil, fmt.Errorf("root directory %s doesn't exist", rootDir)
+	}
+
+	return newReader(opts, &fileTransport{
+		base: base,
+		root: http.NewFileTransport(http.Dir(rootDir)),
+	}), nil
+}
+
+// fileTransport maps URLs under the base one to the files
+type fileTransport struct {
+	base *url.URL
+	root http.RoundTripper
+}
+
+func (ft *fileTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	path, ok := ft.relativePath(req.URL)
+	if !ok {
+		return nil, &OfflineError{Err: fmt.Errorf("%s is outside of the root directory of %s", req.URL.String(), ft.base.String())}
+	}
+
+	fileReq := req.Clone(req.Context())
+	fileReq.URL = &url.URL{Path: path, RawQuery: req.URL.RawQuery}
+	resp, err := ft.root.RoundTrip(fileReq)
+	if err != nil {
+		return resp, err
+	}
+
+	// redirects are relative to the public URL
+	resp.Request = req
+	return resp, nil
+}
+
+// relativePath tells the path of the URL in the root directory
+func (ft *fileTransport) relativePath(u *url.URL) (string, bool) {
+	if !strings.EqualFold(u.Scheme, ft.base.Scheme) || !strings.EqualFold(u.Host, ft.base.Host) {
+		return "", false
+	}
+
+	basePath := strings.TrimSuffix(ft.base.Path, "/")
+	if u.Path != basePath && !strings.HasPrefix(u.Path, basePath+"/") {
+		return "", false
+	}
+	path := strings.TrimPrefix(u.Path, basePath)
+	if path == "" {
+		path = "/"
+	}
+	return path, true
+}
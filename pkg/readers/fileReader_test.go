@@ -0,0 +1,87 @@
+package readers_test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"sitemap-generator/pkg/readers"
+	"sitemap-generator/pkg/readers/models"
+	"sitemap-generator/utils"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileReader(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"index.html":             `<a href="/posts/">Posts</a>`,
+		"posts/index.html":       `<a href="/posts/first/">First</a>`,
+		"posts/first/index.html": `<a href="/docs/guide.pdf">Guide</a>`,
+		"docs/guide.pdf":         "%PDF-1.4",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		utils.AssertNoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		utils.AssertNoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	modified := time.Date(2022, 7, 1, 9, 0, 0, 0, time.UTC)
+	utils.AssertNoError(t, os.Chtimes(filepath.Join(root, "posts", "index.html"), modified, modified))
+
+	reader, err := readers.NewFileReader("https://example.com/", root, readers.ReaderOptions{MaxRetries: 1, MaxRedirects: 3})
+	utils.AssertNoError(t, err)
+
+	t.Run("directory index", func(t *testing.T) {
+		page, err := reader.ReadUrl("https://example.com/posts/")
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, string(page.Body), files["posts/index.html"])
+		utils.AssertTrue(t, page.Info.IsHtml)
+		utils.AssertTrue(t, page.Info.LastModified.Equal(modified))
+	})
+
+	t.Run("directory without slash is redirected", func(t *testing.T) {
+		info, err := reader.CheckUrl("https://example.com/posts/first")
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, info.FinalUrl, "https://example.com/posts/first/")
+		utils.AssertEqual(t, info.Redirects[0].StatusCode, http.StatusMovedPermanently)
+	})
+
+	t.Run("content type by extension", func(t *testing.T) {
+		info, err := reader.CheckUrl("https://example.com/docs/guide.pdf")
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, info.ContentClass, models.ContentClassDocument)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := reader.CheckUrl("https://example.com/missing.html")
+		utils.AssertEqual(t, readers.ErrorStatusCode(err), http.StatusNotFound)
+	})
+
+	t.Run("other site", func(t *testing.T) {
+		// the request is not retried, since it fails the same way each time
+		reader, err := readers.NewFileReader("https://example.com/", root, readers.ReaderOptions{MaxRetries: 3, MaxRedirects: 3})
+		utils.AssertNoError(t, err)
+
+		_, err = reader.CheckUrl("https://other.com/")
+		utils.AssertHasError(t, err, "outside of the root directory")
+		utils.AssertFalse(t, strings.Contains(err.Error(), "Maximum retries exceeded"))
+		utils.AssertTrue(t, readers.IsOffline(err))
+		utils.AssertEqual(t, readers.ErrorKind(err), readers.ErrorKindOffline)
+	})
+
+	t.Run("base path and file URL of the directory", func(t *testing.T) {
+		reader, err := readers.NewFileReader("https://example.com/blog/", "file://"+filepath.ToSlash(root), readers.ReaderOptions{MaxRetries: 1})
+		utils.AssertNoError(t, err)
+		page, err := reader.ReadUrl("https://example.com/blog/posts/")
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, string(page.Body), files["posts/index.html"])
+
+		_, err = reader.CheckUrl("https://example.com/posts/")
+		utils.AssertHasError(t, err, "outside of the root directory")
+	})
+
+	t.Run("missing root directory", func(t *testing.T) {
+		_, err := readers.NewFileReader("https://example.com/", filepath.Join(root, "missing"), readers.ReaderOptions{})
+		utils.AssertHasError(t, err, "doesn't exist")
+	})
+}
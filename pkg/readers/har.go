@@ -0,0 +1,263 @@
+package readers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sitemap-generator/pkg/readers/models"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const harVersion = "1.2"
+
+// HarRecorder keeps every request of the reader and its response to save them as HAR
+type HarRecorder interface {
+	// Har returns the requests recorded so far
+	Har() models.Har
+	// Write saves the recorded requests as HAR JSON
+	Write(dest io.Writer) error
+}
+
+type harRecorder struct {
+	locker  sync.Mutex
+	entries []models.HarEntry
+}
+
+// NewHarRecorder creates the recorder to set in ReaderOptions.Recorder
+func NewHarRecorder() HarRecorder {
+	return &harRecorder{
+		entries: make([]models.HarEntry, 0),
+	}
+}
+
+func (hr *harRecorder) Har() models.Har {
+	hr.locker.Lock()
+	defer hr.locker.Unlock()
+
+	entries := make([]models.HarEntry, len(hr.entries))
+	copy(entries, hr.entries)
+	return models.Har{
+		Log: models.HarLog{
+			Version: harVersion,
+			Creator: models.HarCreator{Name: DefaultUserAgent, Version: harVersion},
+			Entries: entries,
+		},
+	}
+}
+
+func (hr *harRecorder) Write(dest io.Writer) error {
+	encoder := json.NewEncoder(dest)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(hr.Har())
+}
+
+func (hr *harRecorder) add(entry models.HarEntry) {
+	hr.locker.Lock()
+	defer hr.locker.Unlock()
+	hr.entries = append(hr.entries, entry)
+}
+
+// recordingTransport records the response when its body is closed, so only the read part of the body is recorded
+type recordingTransport struct {
+	recorder *harRecorder
+	base     http.RoundTripper
+}
+
+func newRecordingTransport(base http.RoundTripper, recorder HarRecorder) http.RoundTripper {
+	hr, ok := recorder.(*harRecorder)
+	if !ok {
+		return base
+	}
+	return &recordingTransport{recorder: hr, base: base}
+}
+
+func (rt *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	started := time.Now()
+	resp, err := rt.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	resp.Body = &recordingBody{
+		ReadCloser: resp.Body,
+		onClose: func(body []byte) {
+			rt.recorder.add(harEntry(req, resp, body, started))
+		},
+	}
+	return resp, nil
+}
+
+type recordingBody struct {
+	io.ReadCloser
+	buffer  bytes.Buffer
+	once    sync.Once
+	onClose func(body []byte)
+}
+
+func (rb *recordingBody) Read(p []byte) (int, error) {
+	n, err := rb.ReadCloser.Read(p)
+	rb.buffer.Write(p[:n])
+	return n, err
+}
+
+func (rb *recordingBody) Close() error {
+	err := rb.ReadCloser.Close()
+	rb.once.Do(func() {
+		rb.onClose(rb.buffer.Bytes())
+	})
+	return err
+}
+
+func harEntry(req *http.Request, resp *http.Response, body []byte, started time.Time) models.HarEntry {
+	content := models.HarContent{
+		Size:     len(body),
+		MimeType: resp.Header.Get("Content-Type"),
+	}
+	if utf8.Valid(body) {
+		content.Text = string(body)
+	} else {
+		content.Text = base64.StdEncoding.EncodeToString(body)
+		content.Encoding = "base64"
+	}
+
+	elapsed := float64(time.Since(started)) / float64(time.Millisecond)
+	return models.HarEntry{
+		StartedDateTime: started.Format(time.RFC3339Nano),
+		Time:            elapsed,
+		Request: models.HarRequest{
+			Method:      req.Method,
+			Url:         req.URL.String(),
+			HttpVersion: req.Proto,
+			Headers:     harHeaders(req.Header),
+			QueryString: make([]models.HarNameValue, 0),
+			Cookies:     make([]models.HarNameValue, 0),
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Response: models.HarResponse{
+			Status:      resp.StatusCode,
+			StatusText:  strings.TrimSpace(strings.TrimPrefix(resp.Status, fmt.Sprint(resp.StatusCode))),
+			HttpVersion: resp.Proto,
+			Headers:     harHeaders(resp.Header),
+			Cookies:     make([]models.HarNameValue, 0),
+			Content:     content,
+			RedirectUrl: resp.Header.Get("Location"),
+			HeadersSize: -1,
+			BodySize:    len(body),
+		},
+		Timings: models.HarTimings{Wait: elapsed},
+	}
+}
+
+func harHeaders(header http.Header) []models.HarNameValue {
+	list := make([]models.HarNameValue, 0, len(header))
+	for name, values := range header {
+		for _, v := range values {
+			list = append(list, models.HarNameValue{Name: name, Value: v})
+		}
+	}
+	return list
+}
+
+// ReadHar loads HAR JSON, e.g. saved by HarRecorder or exported by the browser
+func ReadHar(src io.Reader) (har models.Har, err error) {
+	if err = json.NewDecoder(src).Decode(&har); err != nil {
+		return har, fmt.Errorf("invalid HAR: %s", err.Error())
+	}
+	return har, nil
+}
+
+// NewReplayReader serves all the requests from the recorded HAR without network access;
+// options affecting the network (proxy, TLS, compression) are ignored
+func NewReplayReader(har models.Har, opts ReaderOptions) Reader {
+	return newReader(opts, newReplayTransport(har))
+}
+
+// replayTransport serves the responses of the same requests in the recorded order,
+// the last one is repeated when they are over; HEAD requests can be served by GET responses
+type replayTransport struct {
+	locker  sync.Mutex
+	entries map[string][]models.HarEntry
+	served  map[string]int
+}
+
+func newReplayTransport(har models.Har) http.RoundTripper {
+	entries := make(map[string][]models.HarEntry)
+	for _, e := range har.Log.Entries {
+		key := replayKey(e.Request.Method, e.Request.Url)
+		entries[key] = append(entries[key], e)
+	}
+	return &replayTransport{
+		entries: entries,
+		served:  make(map[string]int),
+	}
+}
+
+func replayKey(method string, url string) string {
+	return strings.ToUpper(method) + " " + url
+}
+
+func (rt *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	entry, ok := rt.next(req.Method, req.URL.String())
+	if !ok && req.Method == http.MethodHead {
+		entry, ok = rt.next(http.MethodGet, req.URL.String())
+	}
+	if !ok {
+		return nil, fmt.Errorf("replay: %s %s is not recorded", req.Method, req.URL.String())
+	}
+
+	body := []byte(entry.Response.Content.Text)
+	if entry.Response.Content.Encoding == "base64" {
+		var err error
+		if body, err = base64.StdEncoding.DecodeString(entry.Response.Content.Text); err != nil {
+			return nil, fmt.Errorf("replay: invalid content of %s %s: %s", req.Method, req.URL.String(), err.Error())
+		}
+	}
+	if req.Method == http.MethodHead {
+		body = nil
+	}
+
+	header := make(http.Header)
+	for _, h := range entry.Response.Headers {
+		header.Add(h.Name, h.Value)
+	}
+	// the content is recorded decoded
+	header.Del("Content-Encoding")
+	header.Del("Content-Length")
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", entry.Response.Status, entry.Response.StatusText),
+		StatusCode:    entry.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+func (rt *replayTransport) next(method string, url string) (models.HarEntry, bool) {
+	rt.locker.Lock()
+	defer rt.locker.Unlock()
+
+	key := replayKey(method, url)
+	list := rt.entries[key]
+	if len(list) == 0 {
+		return models.HarEntry{}, false
+	}
+	i := rt.served[key]
+	if i >= len(list) {
+		i = len(list) - 1
+	}
+	rt.served[key]++
+	return list[i], true
+}
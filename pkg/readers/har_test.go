@@ -0,0 +1,71 @@
+package readers_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"sitemap-generator/pkg/readers"
+	"sitemap-generator/utils"
+	"testing"
+)
+
+func TestHarRecorder(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<a href="/old">Old</a>`))
+	})
+	mux.Handle("/old", http.RedirectHandler("/new.png", http.StatusMovedPermanently))
+	mux.HandleFunc("/new.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte{0x89, 'P', 'N', 'G', 0xff})
+	})
+	srv := httptest.NewServer(mux)
+
+	recorder := readers.NewHarRecorder()
+	reader := readers.NewReader(readers.ReaderOptions{MaxRetries: 1, MaxRedirects: 3, Recorder: recorder})
+
+	page, err := reader.ReadUrl(srv.URL + "/")
+	utils.AssertNoError(t, err)
+	info, err := reader.CheckUrl(srv.URL + "/old")
+	utils.AssertNoError(t, err)
+	image, err := reader.ReadUrl(srv.URL + "/new.png")
+	utils.AssertNoError(t, err)
+
+	// GET of the page, HEAD of the redirect and its target and GET of the image
+	har := recorder.Har()
+	utils.AssertEqual(t, len(har.Log.Entries), 4)
+	utils.AssertEqual(t, har.Log.Entries[1].Response.Status, http.StatusMovedPermanently)
+	utils.AssertEqual(t, har.Log.Entries[3].Response.Content.Encoding, "base64")
+
+	buffer := new(bytes.Buffer)
+	utils.AssertNoError(t, recorder.Write(buffer))
+	srv.Close()
+
+	t.Run("replay", func(t *testing.T) {
+		har, err := readers.ReadHar(buffer)
+		utils.AssertNoError(t, err)
+		replay := readers.NewReplayReader(har, readers.ReaderOptions{MaxRetries: 1, MaxRedirects: 3})
+
+		replayedPage, err := replay.ReadUrl(srv.URL + "/")
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, string(replayedPage.Body), string(page.Body))
+		utils.AssertTrue(t, replayedPage.Info.IsHtml)
+
+		replayedInfo, err := replay.CheckUrl(srv.URL + "/old")
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, replayedInfo.FinalUrl, info.FinalUrl)
+		utils.AssertEqual(t, replayedInfo.Redirects, info.Redirects)
+		utils.AssertEqual(t, replayedInfo.ContentClass, info.ContentClass)
+
+		replayedImage, err := replay.ReadUrl(srv.URL + "/new.png")
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, replayedImage.Body, image.Body)
+	})
+
+	t.Run("not recorded", func(t *testing.T) {
+		har, _ := readers.ReadHar(bytes.NewReader([]byte(`{"log": {"entries": []}}`)))
+		_, err := readers.NewReplayReader(har, readers.ReaderOptions{MaxRetries: 1}).CheckUrl(srv.URL + "/")
+		utils.AssertHasError(t, err, "is not recorded")
+	})
+}
@@ -0,0 +1,197 @@
+package readers
+
+import (
+	"net/url"
+	"sitemap-generator/pkg/readers/models"
+	"strings"
+	"sync"
+)
+
+type HostLimiterOptions struct {
+	// MaxParallel is max number of requests sent at the same time to all the hosts, 0 for unlimited
+	MaxParallel int
+	// MaxHostParallel is max number of requests sent at the same time to the same host, 0 for unlimited
+	MaxHostParallel int
+}
+
+// HostLimiter limits concurrent requests, it's shared by the readers of all the sites crawled at once;
+// when the limit is reached, the waiting hosts get free slots in turn, so a big site doesn't hold back small ones
+type HostLimiter interface {
+	// Acquire waits for the slot of the host
+	Acquire(host string)
+	// Release frees the slot of the host taken by Acquire
+	Release(host string)
+}
+
+type hostSlots struct {
+	running int
+	waiters []chan struct{}
+}
+
+type hostLimiter struct {
+	maxParallel     int
+	maxHostParallel int
+
+	locker  sync.Mutex
+	running int
+	hosts   map[string]*hostSlots
+	// ring is the order of the hosts having waiters, next is the position of the host to get the free slot
+	ring []string
+	next int
+}
+
+func NewHostLimiter(opts HostLimiterOptions) HostLimiter {
+	return &hostLimiter{
+		maxParallel:     opts.MaxParallel,
+		maxHostParallel: opts.MaxHostParallel,
+		hosts:           make(map[string]*hostSlots),
+		ring:            make([]string, 0),
+	}
+}
+
+func (hl *hostLimiter) Acquire(host string) {
+	hl.locker.Lock()
+	slots, ok := hl.hosts[host]
+	if !ok {
+		slots = &hostSlots{}
+		hl.hosts[host] = slots
+	}
+	// the host which already waits keeps the order of its requests
+	if len(slots.waiters) == 0 && hl.isFree(slots) {
+		hl.running++
+		slots.running++
+		hl.locker.Unlock()
+		return
+	}
+
+	wait := make(chan struct{})
+	if len(slots.waiters) == 0 {
+		hl.ring = append(hl.ring, host)
+	}
+	slots.waiters = append(slots.waiters, wait)
+	hl.locker.Unlock()
+
+	<-wait
+}
+
+func (hl *hostLimiter) Release(host string) {
+	hl.locker.Lock()
+	defer hl.locker.Unlock()
+
+	slots, ok := hl.hosts[host]
+	if !ok {
+		return
+	}
+	hl.running--
+	slots.running--
+	hl.dispatch()
+
+	if slots.running == 0 && len(slots.waiters) == 0 {
+		delete(hl.hosts, host)
+	}
+}
+
+// dispatch gives free slots to the waiting hosts in turn; it's called under the lock
+func (hl *hostLimiter) dispatch() {
+	skipped := 0
+	for len(hl.ring) > 0 && skipped < len(hl.ring) {
+		if hl.maxParallel > 0 && hl.running >= hl.maxParallel {
+			return
+		}
+		if hl.next >= len(hl.ring) {
+			hl.next = 0
+		}
+		slots := hl.hosts[hl.ring[hl.next]]
+		if !hl.isFree(slots) {
+			hl.next++
+			skipped++
+			continue
+		}
+
+		wait := slots.waiters[0]
+		slots.waiters = slots.waiters[1:]
+		hl.running++
+		slots.running++
+		close(wait)
+
+		// the host leaves the ring until it waits again
+		if len(slots.waiters) == 0 {
+			hl.ring = append(hl.ring[:hl.next], hl.ring[hl.next+1:]...)
+		} else {
+			hl.next++
+		}
+		skipped = 0
+	}
+}
+
+// isFree tells if the host can send one more request; it's called under the lock
+func (hl *hostLimiter) isFree(slots *hostSlots) bool {
+	if hl.maxParallel > 0 && hl.running >= hl.maxParallel {
+		return false
+	}
+	return hl.maxHostParallel <= 0 || slots.running < hl.maxHostParallel
+}
+
+type limitedReader struct {
+	Reader
+
+	limiter HostLimiter
+}
+
+// NewLimitedReader makes requests of the reader wait for the slots of their hosts;
+// login is not limited, since it's made while other requests of the host wait for it
+func NewLimitedReader(reader Reader, limiter HostLimiter) Reader {
+	return &limitedReader{
+		Reader:  reader,
+		limiter: limiter,
+	}
+}
+
+func (lr *limitedReader) CheckUrl(url string) (info models.UrlInfo, err error) {
+	host := limitedHost(url)
+	lr.limiter.Acquire(host)
+	defer lr.limiter.Release(host)
+
+	return lr.Reader.CheckUrl(url)
+}
+
+func (lr *limitedReader) ReadUrl(url string) (page models.Page, err error) {
+	host := limitedHost(url)
+	lr.limiter.Acquire(host)
+	defer lr.limiter.Release(host)
+
+	return lr.Reader.ReadUrl(url)
+}
+
+func (lr *limitedReader) FetchUrl(url string) (page models.Page, err error) {
+	host := limitedHost(url)
+	lr.limiter.Acquire(host)
+	defer lr.limiter.Release(host)
+
+	return lr.Reader.FetchUrl(url)
+}
+
+func (lr *limitedReader) ReadUrlTo(url string, consume BodyConsumer) (page models.Page, err error) {
+	host := limitedHost(url)
+	lr.limiter.Acquire(host)
+	defer lr.limiter.Release(host)
+
+	return lr.Reader.ReadUrlTo(url, consume)
+}
+
+func (lr *limitedReader) FetchUrlTo(url string, consume BodyConsumer) (page models.Page, err error) {
+	host := limitedHost(url)
+	lr.limiter.Acquire(host)
+	defer lr.limiter.Release(host)
+
+	return lr.Reader.FetchUrlTo(url, consume)
+}
+
+// limitedHost is the lower-cased host of URL, invalid URLs share the empty one
+func limitedHost(location string) string {
+	u, err := url.Parse(location)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Host)
+}
@@ -0,0 +1,102 @@
+package readers_test
+
+import (
+	"fmt"
+	"net/url"
+	"sitemap-generator/pkg/readers"
+	"sitemap-generator/pkg/readers/models"
+	"sitemap-generator/utils"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLimitedReader(t *testing.T) {
+	t.Run("requests are limited in total and per host", func(t *testing.T) {
+		var locker sync.Mutex
+		running, maxRunning := 0, 0
+		hosts, maxHosts := make(map[string]int), make(map[string]int)
+
+		reader := readers.NewLimitedReader(readers.NewReaderMock(readers.ReaderMockOptions{
+			CheckUrl: func(location string) (models.UrlInfo, error) {
+				u, _ := url.Parse(location)
+				locker.Lock()
+				running++
+				hosts[u.Host]++
+				if running > maxRunning {
+					maxRunning = running
+				}
+				if hosts[u.Host] > maxHosts[u.Host] {
+					maxHosts[u.Host] = hosts[u.Host]
+				}
+				locker.Unlock()
+
+				time.Sleep(5 * time.Millisecond)
+
+				locker.Lock()
+				running--
+				hosts[u.Host]--
+				locker.Unlock()
+				return models.UrlInfo{}, nil
+			},
+		}), readers.NewHostLimiter(readers.HostLimiterOptions{MaxParallel: 4, MaxHostParallel: 2}))
+
+		var wg sync.WaitGroup
+		for i := 0; i < 60; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, err := reader.CheckUrl(fmt.Sprintf("https://site%d.com/page%d", i%3, i))
+				utils.AssertNoError(t, err)
+			}(i)
+		}
+		wg.Wait()
+
+		utils.AssertEqual(t, maxRunning, 4)
+		utils.AssertEqual(t, len(maxHosts), 3)
+		for _, m := range maxHosts {
+			utils.AssertEqual(t, m, 2)
+		}
+	})
+
+	t.Run("waiting hosts get free slots in turn", func(t *testing.T) {
+		limiter := readers.NewHostLimiter(readers.HostLimiterOptions{MaxParallel: 1})
+		limiter.Acquire("big.com")
+
+		var locker sync.Mutex
+		order := make([]string, 0)
+		var wg sync.WaitGroup
+		wait := func(host string) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				limiter.Acquire(host)
+				locker.Lock()
+				order = append(order, host)
+				locker.Unlock()
+				limiter.Release(host)
+			}()
+			// let the request take its place in the queue
+			time.Sleep(10 * time.Millisecond)
+		}
+		for i := 0; i < 3; i++ {
+			wait("big.com")
+		}
+		wait("small.com")
+
+		limiter.Release("big.com")
+		wg.Wait()
+
+		utils.AssertEqual(t, fmt.Sprint(order), "[big.com small.com big.com big.com]")
+	})
+
+	t.Run("unlimited", func(t *testing.T) {
+		limiter := readers.NewHostLimiter(readers.HostLimiterOptions{})
+		for i := 0; i < 10; i++ {
+			limiter.Acquire("site.com")
+		}
+		for i := 0; i < 10; i++ {
+			limiter.Release("site.com")
+		}
+	})
+}
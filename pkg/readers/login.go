@@ -0,0 +1,224 @@
+package readers
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"golang.org/x/net/html"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Authenticator logs in to the site, the session cookies are kept in the jar of the client
+type Authenticator interface {
+	Authenticate(client *http.Client) error
+}
+
+type FormLoginOptions struct {
+	// LoginUrl is the page with the login form
+	LoginUrl string
+	// ActionUrl is where the form is posted, the action of the form on the login page (or the page itself) by default
+	ActionUrl string
+	// Fields are posted as is, e.g. username and password
+	Fields url.Values
+	// CsrfField is the name of the hidden input of the form with CSRF token, it's posted with the value from the login page
+	CsrfField string
+	// SessionExpired is the pattern of URL where the expired session is redirected to (e.g. the login page),
+	// the login fails if it leads there or its page refers there
+	SessionExpired *regexp.Regexp
+}
+
+type formLogin struct {
+	loginUrl       string
+	actionUrl      string
+	fields         url.Values
+	csrfField      string
+	sessionExpired *regexp.Regexp
+}
+
+// NewFormLogin posts the login form, the login page is requested only if the action or CSRF token should be found there
+func NewFormLogin(opts FormLoginOptions) Authenticator {
+	return &formLogin{
+		loginUrl:       opts.LoginUrl,
+		actionUrl:      opts.ActionUrl,
+		fields:         opts.Fields,
+		csrfField:      opts.CsrfField,
+		sessionExpired: opts.SessionExpired,
+	}
+}
+
+func (fl *formLogin) Authenticate(client *http.Client) error {
+	fields := make(url.Values)
+	for key, values := range fl.fields {
+		fields[key] = values
+	}
+
+	actionUrl := fl.actionUrl
+	if actionUrl == "" || fl.csrfField != "" {
+		form, err := fl.readForm(client)
+		if err != nil {
+			return err
+		}
+		if actionUrl == "" {
+			actionUrl = form.action
+		}
+		if fl.csrfField != "" {
+			if form.csrfToken == "" {
+				return fmt.Errorf("login: CSRF field %s not found at %s", fl.csrfField, fl.loginUrl)
+			}
+			fields.Set(fl.csrfField, form.csrfToken)
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, actionUrl, strings.NewReader(fields.Encode()))
+	if err != nil {
+		return fmt.Errorf("login: %s", err.Error())
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return doLogin(client, req, fl.sessionExpired)
+}
+
+// maxLoginPageSize limits how much of the page the login leads to is checked
+const maxLoginPageSize = 1024 * 1024
+
+type loginForm struct {
+	action    string
+	csrfToken string
+}
+
+// readForm finds the action of the form with the CSRF field (or the first one) on the login page
+func (fl *formLogin) readForm(client *http.Client) (form loginForm, err error) {
+	resp, err := client.Get(fl.loginUrl)
+	if err != nil {
+		return form, fmt.Errorf("login: could not read login page: %s", err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return form, fmt.Errorf("login: could not read login page: %s", resp.Status)
+	}
+
+	pageUrl := resp.Request.URL
+	form.action = pageUrl.String()
+	formFound := false
+
+	tokenizer := html.NewTokenizer(resp.Body)
+	for {
+		next := tokenizer.Next()
+		if next == html.ErrorToken {
+			return form, nil
+		}
+		if next != html.StartTagToken && next != html.SelfClosingTagToken {
+			continue
+		}
+
+		token := tokenizer.Token()
+		switch {
+		// the form which has the CSRF field is the last one before the field
+		case token.Data == "form" && (!formFound || fl.csrfField != ""):
+			formFound = true
+			form.action = pageUrl.String()
+			if action, err := url.Parse(tokenAttr(token, "action")); err == nil {
+				form.action = pageUrl.ResolveReference(action).String()
+			}
+		case token.Data == "input" && fl.csrfField != "" && tokenAttr(token, "name") == fl.csrfField:
+			form.csrfToken = tokenAttr(token, "value")
+			return form, nil
+		}
+	}
+}
+
+type replayLogin struct {
+	raw            []byte
+	baseUrl        *url.URL
+	sessionExpired *regexp.Regexp
+}
+
+// NewReplayLogin sends the recorded raw HTTP request (as browser dev tools copy it) to log in,
+// the base URL is the scheme and host of the request if it has only the path;
+// the login fails if it leads to where the expired session is redirected to (if the pattern is set)
+func NewReplayLogin(raw []byte, baseUrl string, sessionExpired *regexp.Regexp) (Authenticator, error) {
+	base, err := url.Parse(baseUrl)
+	if err != nil {
+		return nil, fmt.Errorf("login: invalid base URL: %s", err.Error())
+	}
+
+	rl := &replayLogin{raw: raw, baseUrl: base, sessionExpired: sessionExpired}
+	if _, err = rl.request(); err != nil {
+		return nil, err
+	}
+	return rl, nil
+}
+
+func (rl *replayLogin) Authenticate(client *http.Client) error {
+	req, err := rl.request()
+	if err != nil {
+		return err
+	}
+	return doLogin(client, req, rl.sessionExpired)
+}
+
+// request parses the recorded request again each time because its body can be sent only once
+func (rl *replayLogin) request() (*http.Request, error) {
+	// the copied requests often have bare line feeds and no content length,
+	// so the head is parsed separately and the body is the rest
+	raw := bytes.ReplaceAll(rl.raw, []byte("\r\n"), []byte("\n"))
+	head, body, _ := bytes.Cut(raw, []byte("\n\n"))
+	head = append(bytes.ReplaceAll(head, []byte("\n"), []byte("\r\n")), "\r\n\r\n"...)
+
+	recorded, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(head)))
+	if err != nil {
+		return nil, fmt.Errorf("login: invalid recorded request: %s", err.Error())
+	}
+
+	target := rl.baseUrl.ResolveReference(recorded.URL)
+	if recorded.Host != "" && !recorded.URL.IsAbs() {
+		target.Host = recorded.Host
+	}
+
+	req, err := http.NewRequest(recorded.Method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("login: invalid recorded request: %s", err.Error())
+	}
+	for key, values := range recorded.Header {
+		// the length is of the body as it's sent now
+		if key != "Content-Length" {
+			req.Header[key] = values
+		}
+	}
+	return req, nil
+}
+
+// doLogin sends the login request; the failed login often responds with the login page again (even with 200),
+// so it's detected by the session expired pattern in the URL or the content of the page it leads to
+func doLogin(client *http.Client, req *http.Request, sessionExpired *regexp.Regexp) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("login: %s", err.Error())
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxLoginPageSize))
+	if err != nil {
+		return fmt.Errorf("login: %s", err.Error())
+	}
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("login: %s %s responded %s", req.Method, req.URL.String(), resp.Status)
+	}
+	if sessionExpired != nil && (sessionExpired.MatchString(resp.Request.URL.String()) || sessionExpired.Match(body)) {
+		return fmt.Errorf("login: %s %s led to the login page again (%s)", req.Method, req.URL.String(), resp.Request.URL.String())
+	}
+	return nil
+}
+
+func tokenAttr(token html.Token, key string) string {
+	for _, a := range token.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
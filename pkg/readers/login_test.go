@@ -0,0 +1,185 @@
+package readers_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sitemap-generator/pkg/readers"
+	"sitemap-generator/utils"
+	"sync"
+	"testing"
+)
+
+// loginServer lets members read their page only with the session cookie got by the login form
+type loginServer struct {
+	*httptest.Server
+
+	locker   sync.Mutex
+	sessions map[string]bool
+	logins   int
+}
+
+func newLoginServer() *loginServer {
+	ls := &loginServer{sessions: make(map[string]bool)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			http.SetCookie(w, &http.Cookie{Name: "csrf", Value: "token-1", Path: "/"})
+			_, _ = w.Write([]byte(`<html><body>
+<form action="/search"><input name="q"></form>
+<form method="post" action="/login/submit">
+    <input type="hidden" name="_csrf" value="token-1">
+    <input name="username"><input name="password" type="password">
+</form>
+</body></html>`))
+			return
+		}
+	})
+	mux.HandleFunc("/login/submit", func(w http.ResponseWriter, r *http.Request) {
+		csrf, _ := r.Cookie("csrf")
+		if r.Method != http.MethodPost || r.FormValue("username") != "user" || r.FormValue("password") != "secret" ||
+			csrf == nil || r.FormValue("_csrf") != csrf.Value {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		ls.locker.Lock()
+		ls.logins++
+		session := fmt.Sprintf("session-%d", ls.logins)
+		ls.sessions[session] = true
+		ls.locker.Unlock()
+
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: session, Path: "/"})
+		http.Redirect(w, r, "/members", http.StatusFound)
+	})
+	mux.HandleFunc("/members", func(w http.ResponseWriter, r *http.Request) {
+		session, _ := r.Cookie("session")
+		ls.locker.Lock()
+		valid := session != nil && ls.sessions[session.Value]
+		ls.locker.Unlock()
+
+		if !valid {
+			http.Redirect(w, r, "/login?next=/members", http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("members only"))
+	})
+
+	ls.Server = httptest.NewServer(mux)
+	return ls
+}
+
+func (ls *loginServer) expireSessions() {
+	ls.locker.Lock()
+	defer ls.locker.Unlock()
+	ls.sessions = make(map[string]bool)
+}
+
+// nopLogin "logs in" without getting any session
+type nopLogin struct{}
+
+func (nopLogin) Authenticate(_ *http.Client) error {
+	return nil
+}
+
+func TestReader_Authenticate(t *testing.T) {
+	srv := newLoginServer()
+	defer srv.Close()
+
+	login := readers.NewFormLogin(readers.FormLoginOptions{
+		LoginUrl:  srv.URL + "/login",
+		Fields:    url.Values{"username": {"user"}, "password": {"secret"}},
+		CsrfField: "_csrf",
+	})
+	reader := readers.NewReader(readers.ReaderOptions{
+		MaxRetries:     1,
+		MaxRedirects:   3,
+		Authenticator:  login,
+		SessionExpired: regexp.MustCompile(`/login`),
+	})
+
+	t.Run("form login", func(t *testing.T) {
+		utils.AssertNoError(t, reader.Authenticate())
+		page, err := reader.ReadUrl(srv.URL + "/members")
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, string(page.Body), "members only")
+		utils.AssertEqual(t, srv.logins, 1)
+	})
+
+	t.Run("login again when session is expired", func(t *testing.T) {
+		srv.expireSessions()
+
+		info, err := reader.CheckUrl(srv.URL + "/members")
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, info.FinalUrl, srv.URL+"/members")
+		utils.AssertEqual(t, srv.logins, 2)
+
+		page, err := reader.ReadUrl(srv.URL + "/members")
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, string(page.Body), "members only")
+		utils.AssertEqual(t, srv.logins, 2)
+	})
+
+	t.Run("wrong credentials", func(t *testing.T) {
+		err := readers.NewReader(readers.ReaderOptions{
+			MaxRetries: 1,
+			Authenticator: readers.NewFormLogin(readers.FormLoginOptions{
+				LoginUrl:  srv.URL + "/login",
+				Fields:    url.Values{"username": {"user"}, "password": {"wrong"}},
+				CsrfField: "_csrf",
+			}),
+		}).Authenticate()
+		utils.AssertHasError(t, err, "403 Forbidden")
+	})
+
+	t.Run("login leading to the login page again", func(t *testing.T) {
+		// the login page is returned with 200 when the form is posted there
+		err := readers.NewReader(readers.ReaderOptions{
+			MaxRetries: 1,
+			Authenticator: readers.NewFormLogin(readers.FormLoginOptions{
+				LoginUrl:       srv.URL + "/login",
+				ActionUrl:      srv.URL + "/login",
+				Fields:         url.Values{"username": {"user"}, "password": {"secret"}},
+				SessionExpired: regexp.MustCompile(`/login`),
+			}),
+		}).Authenticate()
+		utils.AssertHasError(t, err, "led to the login page again")
+	})
+
+	t.Run("session expired after the login again", func(t *testing.T) {
+		reader := readers.NewReader(readers.ReaderOptions{
+			MaxRetries:     1,
+			MaxRedirects:   3,
+			Authenticator:  nopLogin{},
+			SessionExpired: regexp.MustCompile(`/login`),
+		})
+
+		// the login page is not returned as the requested one
+		_, err := reader.CheckUrl(srv.URL + "/members")
+		utils.AssertHasError(t, err, "session expired again after the login")
+		utils.AssertEqual(t, readers.ErrorKind(err), readers.ErrorKindAuth)
+		_, err = reader.ReadUrl(srv.URL + "/members")
+		utils.AssertHasError(t, err, "session expired again after the login")
+	})
+
+	t.Run("replay recorded request", func(t *testing.T) {
+		// the CSRF cookie is a part of the recorded request
+		raw := "POST /login/submit HTTP/1.1\n" +
+			"Content-Type: application/x-www-form-urlencoded\n" +
+			"Cookie: csrf=token-1\n" +
+			"\n" +
+			"username=user&password=secret&_csrf=token-1"
+		login, err := readers.NewReplayLogin([]byte(raw), srv.URL, regexp.MustCompile(`/login$`))
+		utils.AssertNoError(t, err)
+
+		reader := readers.NewReader(readers.ReaderOptions{MaxRetries: 1, MaxRedirects: 3, Authenticator: login})
+		utils.AssertNoError(t, reader.Authenticate())
+		page, err := reader.ReadUrl(srv.URL + "/members")
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, string(page.Body), "members only")
+	})
+}
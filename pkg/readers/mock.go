@@ -1,28 +1,70 @@
 package readers
 
-import "sitemap-generator/pkg/readers/models"
+import (
+	"bytes"
+	"sitemap-generator/pkg/readers/models"
+)
 
 type ReaderMockOptions struct {
-	CheckUrl func(url string) (info models.UrlInfo, err error)
-	ReadUrl  func(url string) (body []byte, err error)
+	// Authenticate is optional, nothing is done by default
+	Authenticate func() error
+	CheckUrl     func(url string) (info models.UrlInfo, err error)
+	ReadUrl      func(url string) (page models.Page, err error)
+	FetchUrl     func(url string) (page models.Page, err error)
 }
 
 type readerMock struct {
-	checkUrl func(url string) (info models.UrlInfo, err error)
-	readUrl  func(url string) (body []byte, err error)
+	authenticate func() error
+	checkUrl     func(url string) (info models.UrlInfo, err error)
+	readUrl      func(url string) (page models.Page, err error)
+	fetchUrl     func(url string) (page models.Page, err error)
 }
 
 func NewReaderMock(opts ReaderMockOptions) Reader {
 	return &readerMock{
-		checkUrl: opts.CheckUrl,
-		readUrl:  opts.ReadUrl,
+		authenticate: opts.Authenticate,
+		checkUrl:     opts.CheckUrl,
+		readUrl:      opts.ReadUrl,
+		fetchUrl:     opts.FetchUrl,
 	}
 }
 
+func (rm *readerMock) Authenticate() error {
+	if rm.authenticate == nil {
+		return nil
+	}
+	return rm.authenticate()
+}
+
 func (rm *readerMock) CheckUrl(url string) (info models.UrlInfo, err error) {
 	return rm.checkUrl(url)
 }
 
-func (rm *readerMock) ReadUrl(url string) (body []byte, err error) {
+func (rm *readerMock) ReadUrl(url string) (page models.Page, err error) {
 	return rm.readUrl(url)
 }
+
+func (rm *readerMock) FetchUrl(url string) (page models.Page, err error) {
+	return rm.fetchUrl(url)
+}
+
+func (rm *readerMock) ReadUrlTo(url string, consume BodyConsumer) (models.Page, error) {
+	page, err := rm.readUrl(url)
+	return consumeMockBody(page, err, consume)
+}
+
+func (rm *readerMock) FetchUrlTo(url string, consume BodyConsumer) (models.Page, error) {
+	page, err := rm.fetchUrl(url)
+	return consumeMockBody(page, err, consume)
+}
+
+// consumeMockBody passes the body of the page given by the mock to the consumer
+func consumeMockBody(page models.Page, err error, consume BodyConsumer) (models.Page, error) {
+	if err != nil || page.Body == nil {
+		return page, err
+	}
+	body := page.Body
+	page.Body = nil
+	consume(page, bytes.NewReader(body))
+	return page, nil
+}
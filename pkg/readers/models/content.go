@@ -0,0 +1,11 @@
+package models
+
+type ContentClass string
+
+const (
+	ContentClassHtml     ContentClass = "html"
+	ContentClassDocument ContentClass = "document"
+	ContentClassImage    ContentClass = "image"
+	ContentClassMedia    ContentClass = "media"
+	ContentClassOther    ContentClass = "other"
+)
@@ -0,0 +1,23 @@
+package models
+
+import "net/http"
+
+type Truncation string
+
+const (
+	// TruncatedSize means the body is longer than the allowed size
+	TruncatedSize Truncation = "size-limit"
+	// TruncatedDeadline means the body wasn't read completely in the allowed time
+	TruncatedDeadline Truncation = "read-deadline"
+)
+
+type Page struct {
+	Url        string
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	Info       UrlInfo
+	// Truncated tells why the body is only the beginning of the content, empty if it's complete
+	Truncated Truncation
+	Transfer  Transfer
+}
@@ -0,0 +1,7 @@
+package models
+
+type Redirect struct {
+	StatusCode int `json:"statusCode"`
+	// Location is where the response redirected to
+	Location string `json:"location"`
+}
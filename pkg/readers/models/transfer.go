@@ -0,0 +1,9 @@
+package models
+
+// Transfer is how many bytes of the body are transferred and what they're decoded to
+type Transfer struct {
+	// Encoding is the content encoding of the response, empty for the identity one
+	Encoding     string `json:"encoding,omitempty"`
+	WireBytes    int64  `json:"wireBytes"`
+	DecodedBytes int64  `json:"decodedBytes"`
+}
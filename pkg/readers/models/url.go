@@ -3,6 +3,13 @@ package models
 import "time"
 
 type UrlInfo struct {
+	// StatusCode is zero when it can't be determined (e.g. server doesn't support HEAD requests)
+	StatusCode   int
 	IsHtml       bool
+	ContentType  string
+	ContentClass ContentClass
 	LastModified time.Time
+	// FinalUrl is where the URL leads to after all redirects
+	FinalUrl  string
+	Redirects []Redirect
 }
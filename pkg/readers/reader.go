@@ -1,12 +1,20 @@
 package readers
 
 import (
+	"bytes"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"regexp"
+	"sitemap-generator/pkg/metrics"
 	"sitemap-generator/pkg/readers/models"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -14,103 +22,494 @@ type ReaderOptions struct {
 	Timeout      time.Duration
 	MaxRetries   int
 	MaxRedirects int
+	// SniffContent detects the content type by its first bytes when the declared one is missing or too generic
+	SniffContent bool
+	// IgnoreContentType makes the declared content type not trusted at all, so only sniffed one is used
+	IgnoreContentType bool
+	// MaxBodySize is the number of bytes above which the body is truncated, 0 for unlimited
+	MaxBodySize int64
+	// BodyTimeout is the deadline of the body reading after which the body is truncated, 0 for none
+	BodyTimeout time.Duration
+	// AcceptEncodings are content encodings asked from servers, DefaultAcceptEncodings if empty
+	AcceptEncodings []string
+	// UserAgent is DefaultUserAgent if empty
+	UserAgent string
+	// Headers are added to every request
+	Headers http.Header
+	// Auth is credentials per host
+	Auth []HostAuth
+	// CookieJar keeps cookies between requests, cookies are not used if it's nil (see NewCookieJar)
+	CookieJar http.CookieJar
+	// Proxy is HTTP, HTTPS or SOCKS5 proxy URL, the one of environment variables is used if it's nil
+	Proxy *url.URL
+	// TLSConfig is nil for the default one (see NewTLSConfig)
+	TLSConfig *tls.Config
+	// Authenticator logs in before the crawl and again when the session is expired
+	Authenticator Authenticator
+	// SessionExpired is the pattern of URL where the expired session is redirected to (e.g. the login page)
+	SessionExpired *regexp.Regexp
+	// Recorder keeps all the requests and responses (see NewHarRecorder), nothing is recorded if it's nil
+	Recorder HarRecorder
+	// Metrics count the requests, they're not exposed anywhere if it's nil
+	Metrics *metrics.Metrics
 }
 
 type Reader interface {
+	// Authenticate logs in to the site if it's needed
+	Authenticate() error
 	CheckUrl(url string) (info models.UrlInfo, err error)
-	ReadUrl(url string) (body []byte, err error)
+	ReadUrl(url string) (page models.Page, err error)
+	FetchUrl(url string) (page models.Page, err error)
+	// ReadUrlTo does the same as ReadUrl, but the body is passed to the consumer instead of the page
+	ReadUrlTo(url string, consume BodyConsumer) (page models.Page, err error)
+	// FetchUrlTo does the same as FetchUrl, but the body of HTML page is passed to the consumer instead of the page
+	FetchUrlTo(url string, consume BodyConsumer) (page models.Page, err error)
 }
 
+// BodyConsumer reads the body of the page while it's being read, so the body isn't kept in memory;
+// the body ends at the size limit or the read deadline, the returned page tells it then.
+// The page is given without the body, ReadUrlTo knows its content class only by the headers at this moment
+type BodyConsumer func(page models.Page, body io.Reader)
+
 type reader struct {
-	maxRetries int
+	maxRetries        int
+	sniffContent      bool
+	ignoreContentType bool
+	maxBodySize       int64
+	bodyTimeout       time.Duration
+
+	authenticator  Authenticator
+	sessionExpired *regexp.Regexp
+	// authLocker makes the only one of concurrent requests log in again, others wait for it;
+	// generation tells if the session is renewed since the request is sent
+	authLocker     sync.Mutex
+	authGeneration int
 
-	client http.Client
+	client  http.Client
+	metrics *metrics.Metrics
 }
 
 func NewReader(opts ReaderOptions) Reader {
+	return newReader(opts, nil)
+}
+
+// measure counts the request by its status, "error" is for the request without response
+func (r *reader) measure(method string, resp *http.Response, err error, duration time.Duration) {
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	r.metrics.Requests.Inc(method, status)
+	r.metrics.RequestDuration.Observe(duration.Seconds(), method)
+}
+
+// newReader builds the reader making requests by the transport, nil is for the network one
+func newReader(opts ReaderOptions, transport http.RoundTripper) Reader {
+	if len(opts.AcceptEncodings) == 0 {
+		opts.AcceptEncodings = DefaultAcceptEncodings
+	}
+	if opts.UserAgent == "" {
+		opts.UserAgent = DefaultUserAgent
+	}
+	if opts.Metrics == nil {
+		opts.Metrics = metrics.NewMetrics(metrics.NewRegistry())
+	}
+	if opts.Authenticator != nil && opts.CookieJar == nil {
+		// the session should be kept somewhere
+		opts.CookieJar, _ = NewCookieJar("")
+	}
+
+	if transport == nil {
+		// the content is decoded by the reader itself to count the transferred bytes
+		network := http.DefaultTransport.(*http.Transport).Clone()
+		network.DisableCompression = true
+		if opts.Proxy != nil {
+			network.Proxy = http.ProxyURL(opts.Proxy)
+		}
+		if opts.TLSConfig != nil {
+			network.TLSClientConfig = opts.TLSConfig
+		}
+		transport = newCompressionTransport(network, opts.AcceptEncodings)
+	}
+	if opts.Recorder != nil {
+		transport = newRecordingTransport(transport, opts.Recorder)
+	}
+
 	client := http.Client{
-		Timeout: opts.Timeout,
+		Transport: newHeadersTransport(transport, opts.UserAgent, opts.Headers, opts.Auth),
+		Jar:       opts.CookieJar,
+		Timeout:   opts.Timeout,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			if len(via) >= opts.MaxRedirects {
+				for _, v := range via {
+					if v.URL.String() == req.URL.String() {
+						return fmt.Errorf("too many redirects: redirect loop")
+					}
+				}
 				return fmt.Errorf("too many redirects")
 			}
 			return nil
 		},
 	}
 	return &reader{
-		maxRetries: opts.MaxRetries,
-		client:     client,
+		maxRetries:        opts.MaxRetries,
+		sniffContent:      opts.SniffContent,
+		ignoreContentType: opts.IgnoreContentType,
+		maxBodySize:       opts.MaxBodySize,
+		bodyTimeout:       opts.BodyTimeout,
+		authenticator:     opts.Authenticator,
+		sessionExpired:    opts.SessionExpired,
+		client:            client,
+		metrics:           opts.Metrics,
+	}
+}
+
+func (r *reader) Authenticate() error {
+	if r.authenticator == nil {
+		return nil
 	}
+
+	r.authLocker.Lock()
+	defer r.authLocker.Unlock()
+	if err := r.authenticator.Authenticate(&r.client); err != nil {
+		return err
+	}
+	r.authGeneration++
+	return nil
+}
+
+// reauthenticate logs in again unless somebody else already did it after the request of the given generation
+func (r *reader) reauthenticate(generation int) error {
+	r.authLocker.Lock()
+	defer r.authLocker.Unlock()
+	if generation != r.authGeneration {
+		return nil
+	}
+	if err := r.authenticator.Authenticate(&r.client); err != nil {
+		return &AuthError{Err: fmt.Errorf("session expired and could not log in again: %s", err.Error())}
+	}
+	r.authGeneration++
+	return nil
+}
+
+// isSessionExpired tells if the request is redirected to where the expired session is redirected to
+func (r *reader) isSessionExpired(resp *http.Response, requestedUrl string) bool {
+	if r.authenticator == nil || r.sessionExpired == nil {
+		return false
+	}
+	return r.sessionExpired.MatchString(resp.Request.URL.String()) && !r.sessionExpired.MatchString(requestedUrl)
+}
+
+func (r *reader) currentGeneration() int {
+	r.authLocker.Lock()
+	defer r.authLocker.Unlock()
+	return r.authGeneration
 }
 
 func (r *reader) CheckUrl(url string) (info models.UrlInfo, err error) {
 	var resp *http.Response
+	var sniffed []byte
 
-	resp, err = r.doOrRetry(http.MethodHead, url, nil)
+	resp, err = r.doOrRetry(http.MethodHead, url, nil, nil)
 	if err != nil {
 		return
 	}
+	resp.Body.Close()
 
-	// Is it HTML ?
-	contentType := strings.Split(resp.Header.Get("Content-Type"), ";")[0]
-	if contentType == "text/html" {
-		info.IsHtml = true
+	// some servers don't support HEAD requests or declared content type is not enough,
+	// so ask for the beginning of the content instead
+	headNotSupported := resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented
+	if headNotSupported || (resp.StatusCode < 300 && r.needsSniffing(resp.Header.Get("Content-Type"))) {
+		if resp, sniffed, err = r.readBeginning(url); err != nil {
+			return
+		}
 	}
 
-	// Try to get last time of resource modification
-	lastModifiedHeader := resp.Header.Get("Last-Modified")
-	if lastModifiedHeader != "" {
-		info.LastModified, _ = time.Parse(time.RFC1123, lastModifiedHeader)
+	// http error
+	if resp.StatusCode >= 400 {
+		err = newHttpError(resp)
+		return
 	}
+
+	info = r.urlInfo(resp, sniffed)
 	return
 }
 
-func (r *reader) ReadUrl(url string) (body []byte, err error) {
+func (r *reader) ReadUrl(url string) (page models.Page, err error) {
+	var body []byte
+	page, err = r.ReadUrlTo(url, keepBody(&body))
+	page.Body = body
+	return page, err
+}
+
+// ReadUrlTo does the same as ReadUrl, but passes the body to the consumer while it's being read
+func (r *reader) ReadUrlTo(url string, consume BodyConsumer) (page models.Page, err error) {
 	var resp *http.Response
-	resp, err = r.doOrRetry(http.MethodGet, url, nil)
+	resp, err = r.doOrRetry(http.MethodGet, url, nil, nil)
 
 	// connection error
 	if err != nil {
-		return nil, err
+		return page, err
 	}
+	defer resp.Body.Close()
+
 	// http error
 	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("HTTP error [%d] %s", resp.StatusCode, resp.Status)
+		return r.newPage(resp, nil), newHttpError(resp)
+	}
+
+	page = r.newPage(resp, nil)
+	var beginning []byte
+	beginning, page.Truncated, err = r.consumeBody(resp, nil, page, consume)
+	// the content is known now, so it's classified by the content too
+	page.Info = r.urlInfo(resp, beginning)
+	page.Transfer = transferOf(resp)
+	return page, err
+}
+
+// FetchUrl checks URL and reads its content by a single request, the content is read only for HTML pages
+func (r *reader) FetchUrl(url string) (page models.Page, err error) {
+	var body []byte
+	page, err = r.FetchUrlTo(url, keepBody(&body))
+	page.Body = body
+	return page, err
+}
+
+// FetchUrlTo does the same as FetchUrl, but passes the body of HTML page to the consumer while it's being read
+func (r *reader) FetchUrlTo(url string, consume BodyConsumer) (page models.Page, err error) {
+	var resp *http.Response
+	resp, err = r.doOrRetry(http.MethodGet, url, nil, nil)
+
+	// connection error
+	if err != nil {
+		return page, err
+	}
+	defer resp.Body.Close()
+
+	// http error
+	if resp.StatusCode >= 400 {
+		return r.newPage(resp, nil), newHttpError(resp)
+	}
+
+	var sniffed []byte
+	if r.needsSniffing(resp.Header.Get("Content-Type")) {
+		if sniffed, err = ioutil.ReadAll(io.LimitReader(resp.Body, sniffLength)); err != nil {
+			return r.newPage(resp, nil), err
+		}
+	}
+
+	page = r.newPage(resp, sniffed)
+	if page.Info.IsHtml {
+		_, page.Truncated, err = r.consumeBody(resp, sniffed, page, consume)
+	}
+	page.Transfer = transferOf(resp)
+	return page, err
+}
+
+// keepBody is the consumer keeping the whole body, the errors of the reading are reported by the reader
+func keepBody(body *[]byte) BodyConsumer {
+	return func(_ models.Page, src io.Reader) {
+		*body, _ = ioutil.ReadAll(src)
+	}
+}
+
+// consumeBody passes the body to the consumer, including its already read beginning; the body is truncated
+// when it's too long or reading it takes too much time. It returns the beginning of the body to sniff it
+func (r *reader) consumeBody(resp *http.Response, beginning []byte, page models.Page, consume BodyConsumer) ([]byte, models.Truncation, error) {
+	var expired int32
+	if r.bodyTimeout > 0 {
+		// closed body makes the pending read return immediately
+		timer := time.AfterFunc(r.bodyTimeout, func() {
+			atomic.StoreInt32(&expired, 1)
+			resp.Body.Close()
+		})
+		defer timer.Stop()
+	}
+
+	body := &limitedBody{
+		src:   io.MultiReader(bytes.NewReader(beginning), resp.Body),
+		limit: r.maxBodySize,
+	}
+	consume(page, body)
+
+	switch {
+	case atomic.LoadInt32(&expired) == 1:
+		return body.beginning, models.TruncatedDeadline, nil
+	case body.truncated:
+		return body.beginning, models.TruncatedSize, body.err
+	default:
+		return body.beginning, "", body.err
 	}
+}
+
+// limitedBody ends at the size limit, it keeps the error of the reading and the beginning of the body
+type limitedBody struct {
+	src io.Reader
+	// limit is the number of bytes to read, 0 for unlimited
+	limit     int64
+	read      int64
+	truncated bool
+	err       error
+	beginning []byte
+}
 
+func (lb *limitedBody) Read(p []byte) (n int, err error) {
+	if lb.limit > 0 && lb.read >= lb.limit {
+		// one more byte tells that the body is longer than allowed
+		var more [1]byte
+		if n, _ := io.ReadFull(lb.src, more[:]); n > 0 {
+			lb.truncated = true
+		}
+		return 0, io.EOF
+	}
+	if lb.limit > 0 && int64(len(p)) > lb.limit-lb.read {
+		p = p[:lb.limit-lb.read]
+	}
+
+	n, err = lb.src.Read(p)
+	lb.read += int64(n)
+	if rest := sniffLength - len(lb.beginning); rest > 0 {
+		lb.beginning = append(lb.beginning, p[:minInt(n, rest)]...)
+	}
+	if err != nil && err != io.EOF && lb.err == nil {
+		lb.err = err
+	}
+	return n, err
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// readBeginning requests only the first bytes of the content (if server supports ranges)
+func (r *reader) readBeginning(url string) (resp *http.Response, beginning []byte, err error) {
+	// the range of the compressed content can't be decoded separately
+	header := http.Header{
+		"Range":           {fmt.Sprintf("bytes=0-%d", sniffLength-1)},
+		"Accept-Encoding": {"identity"},
+	}
+	if resp, err = r.doOrRetry(http.MethodGet, url, header, nil); err != nil {
+		return
+	}
 	defer resp.Body.Close()
-	return ioutil.ReadAll(resp.Body)
+
+	if resp.StatusCode < 300 {
+		beginning, err = ioutil.ReadAll(io.LimitReader(resp.Body, sniffLength))
+	}
+	return
 }
 
-func (r *reader) doOrRetry(method string, url string, reqBody io.Reader) (resp *http.Response, err error) {
+func (r *reader) doOrRetry(method string, url string, header http.Header, reqBody io.Reader) (resp *http.Response, err error) {
 	var req *http.Request
 	req, err = http.NewRequest(method, url, reqBody)
 	if err != nil {
 		return
 	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req = withTransfer(req)
 
 	attempt := 1
+	reauthenticated := false
 	for {
-		resp, err = r.client.Do(req)
+		generation := r.currentGeneration()
+		// the client adds cookies of the jar to the request, so each attempt needs its own copy
+		started := time.Now()
+		resp, err = r.client.Do(req.Clone(req.Context()))
+		r.measure(method, resp, err, time.Since(started))
+		if err == nil && r.isSessionExpired(resp, url) {
+			resp.Body.Close()
+			// the request is made again with the new session only once, so a broken login doesn't loop;
+			// the login page is never returned instead of the requested one
+			if reauthenticated {
+				err = &AuthError{Err: fmt.Errorf("session expired again after the login, %s is redirected to %s", url, resp.Request.URL.String())}
+				return
+			}
+			if err = r.reauthenticate(generation); err != nil {
+				return
+			}
+			reauthenticated = true
+			continue
+		}
+		// 3xx responses without Location (e.g. 304 Not Modified) are not followed by the client,
+		// they're returned as they are, so the inclusion policy decides on their status
 		if err == nil {
 			return
 		}
-		if IsTimeout(err) || IsTooManyRedirects(err) {
+		if IsTimeout(err) || IsTooManyRedirects(err) || IsOffline(err) {
+			err = &TransportError{Err: err, Redirects: redirectChain(resp)}
 			return
 		}
 		if attempt < r.maxRetries {
 			attempt++
+			r.metrics.Retries.Inc()
 		} else {
-			err = fmt.Errorf("Maximum retries exceeded with error: %s", err.Error())
+			err = &TransportError{
+				Err:       fmt.Errorf("Maximum retries exceeded with error: %s", err.Error()),
+				Redirects: redirectChain(resp),
+			}
 			return
 		}
 	}
 }
 
+// newPage builds page of the response, the content (or its beginning) helps to detect its type
+func (r *reader) newPage(resp *http.Response, content []byte) models.Page {
+	if len(content) > sniffLength {
+		content = content[:sniffLength]
+	}
+	return models.Page{
+		Url:        resp.Request.URL.String(),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Info:       r.urlInfo(resp, content),
+	}
+}
+
+func (r *reader) urlInfo(resp *http.Response, sniffed []byte) (info models.UrlInfo) {
+	info.StatusCode = resp.StatusCode
+
+	// the requested part of the content tells the same as the whole one
+	if info.StatusCode == http.StatusPartialContent {
+		info.StatusCode = http.StatusOK
+	}
+
+	// Is it HTML ?
+	info.ContentType, info.ContentClass = r.classifyContent(resp.Header.Get("Content-Type"), sniffed)
+	info.IsHtml = info.ContentClass == models.ContentClassHtml
+
+	// Where it's redirected to
+	info.FinalUrl = resp.Request.URL.String()
+	info.Redirects = redirectChain(resp)
+
+	// Try to get last time of resource modification
+	lastModifiedHeader := resp.Header.Get("Last-Modified")
+	if lastModifiedHeader != "" {
+		info.LastModified, _ = time.Parse(time.RFC1123, lastModifiedHeader)
+	}
+	return
+}
+
+func newHttpError(resp *http.Response) *HttpError {
+	return &HttpError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Redirects:  redirectChain(resp),
+	}
+}
+
 func IsTimeout(err error) bool {
-	return strings.Contains(err.Error(), "context deadline exceeded")
+	return strings.Contains(err.Error(), "context deadline exceeded") || strings.Contains(err.Error(), "Client.Timeout exceeded")
 }
 
 func IsTooManyRedirects(err error) bool {
 	return strings.Contains(err.Error(), "too many redirects")
 }
+
+func IsRedirectLoop(err error) bool {
+	return strings.Contains(err.Error(), "redirect loop")
+}
@@ -1,10 +1,17 @@
 package readers_test
 
 import (
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"sitemap-generator/pkg/metrics"
 	"sitemap-generator/pkg/readers"
+	"sitemap-generator/pkg/readers/models"
 	"sitemap-generator/utils"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -21,6 +28,7 @@ func TestReader_CheckUrl(t *testing.T) {
 
 		info, err := reader.CheckUrl(srv.URL)
 		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, info.StatusCode, http.StatusOK)
 		utils.AssertEmpty(t, info.LastModified)
 		utils.AssertTrue(t, info.IsHtml)
 	})
@@ -37,6 +45,193 @@ func TestReader_CheckUrl(t *testing.T) {
 		utils.AssertEqual(t, info.LastModified, lastModified)
 		utils.AssertFalse(t, info.IsHtml)
 	})
+
+	t.Run("3xx without location is returned as it is", func(t *testing.T) {
+		var requests int64
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt64(&requests, 1)
+			w.WriteHeader(http.StatusNotModified)
+		}))
+		defer srv.Close()
+
+		info, err := readers.NewReader(readers.ReaderOptions{MaxRetries: 3}).CheckUrl(srv.URL)
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, info.StatusCode, http.StatusNotModified)
+		utils.AssertEqual(t, atomic.LoadInt64(&requests), int64(1))
+	})
+}
+
+func TestReader_CheckUrl_Redirects(t *testing.T) {
+	reader := readers.NewReader(readers.ReaderOptions{MaxRedirects: 3})
+
+	mux := http.NewServeMux()
+	mux.Handle("/old", http.RedirectHandler("/older", http.StatusMovedPermanently))
+	mux.Handle("/older", http.RedirectHandler("/new", http.StatusFound))
+	mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	info, err := reader.CheckUrl(srv.URL + "/old")
+	utils.AssertNoError(t, err)
+	utils.AssertTrue(t, info.IsHtml)
+	utils.AssertEqual(t, info.FinalUrl, srv.URL+"/new")
+	utils.AssertEqual(t, info.Redirects, []models.Redirect{
+		{StatusCode: http.StatusMovedPermanently, Location: srv.URL + "/older"},
+		{StatusCode: http.StatusFound, Location: srv.URL + "/new"},
+	})
+}
+
+func TestReader_CheckUrl_Errors(t *testing.T) {
+	reader := readers.NewReader(readers.ReaderOptions{
+		Timeout:      200 * time.Millisecond,
+		MaxRetries:   3,
+		MaxRedirects: 3,
+	})
+
+	t.Run("not found after redirect", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.Handle("/old", http.RedirectHandler("/missing", http.StatusMovedPermanently))
+		mux.Handle("/missing", http.NotFoundHandler())
+		srv := httptest.NewServer(mux)
+		defer srv.Close()
+
+		_, err := reader.CheckUrl(srv.URL + "/old")
+		utils.AssertHasError(t, err, "HTTP error [404]")
+		utils.AssertEqual(t, readers.ErrorKind(err), readers.ErrorKindHttp)
+		utils.AssertEqual(t, readers.ErrorStatusCode(err), http.StatusNotFound)
+		utils.AssertEqual(t, readers.ErrorRedirects(err), []models.Redirect{
+			{StatusCode: http.StatusMovedPermanently, Location: srv.URL + "/missing"},
+		})
+	})
+
+	t.Run("too many redirects", func(t *testing.T) {
+		step := 0
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			step++
+			http.Redirect(w, r, fmt.Sprintf("/step-%d", step), http.StatusFound)
+		}))
+		defer srv.Close()
+
+		_, err := reader.CheckUrl(srv.URL)
+		utils.AssertEqual(t, readers.ErrorKind(err), readers.ErrorKindTooManyRedirects)
+		utils.AssertEqual(t, len(readers.ErrorRedirects(err)), 3)
+	})
+
+	t.Run("redirect loop", func(t *testing.T) {
+		srv := httptest.NewServer(http.RedirectHandler("/", http.StatusFound))
+		defer srv.Close()
+
+		_, err := reader.CheckUrl(srv.URL)
+		utils.AssertTrue(t, readers.IsTooManyRedirects(err))
+		utils.AssertEqual(t, readers.ErrorKind(err), readers.ErrorKindRedirectLoop)
+	})
+
+	t.Run("method not allowed at all", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}))
+		defer srv.Close()
+
+		_, err := reader.CheckUrl(srv.URL)
+		utils.AssertEqual(t, readers.ErrorStatusCode(err), http.StatusMethodNotAllowed)
+	})
+}
+
+// closeConnection drops the connection without any response, so the client gets a connection error
+func closeConnection(w http.ResponseWriter, r *http.Request) {
+	conn, _, err := w.(http.Hijacker).Hijack()
+	if err == nil {
+		conn.Close()
+	}
+}
+
+func TestReader_Metrics(t *testing.T) {
+	registry := metrics.NewRegistry()
+	reader := readers.NewReader(readers.ReaderOptions{
+		MaxRetries: 3,
+		Metrics:    metrics.NewMetrics(registry),
+	})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+	})
+	mux.HandleFunc("/dropped", closeConnection)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	_, err := reader.CheckUrl(srv.URL + "/page")
+	utils.AssertNoError(t, err)
+	_, err = reader.CheckUrl(srv.URL + "/dropped")
+	utils.AssertHasError(t, err, "Maximum retries exceeded")
+
+	out := new(strings.Builder)
+	utils.AssertNoError(t, registry.Write(out))
+	utils.AssertTrue(t, strings.Contains(out.String(), "\nsitemap_generator_requests_total{method=\"HEAD\",status=\"200\"} 1\n"))
+	utils.AssertTrue(t, strings.Contains(out.String(), "\nsitemap_generator_requests_total{method=\"HEAD\",status=\"error\"} 3\n"))
+	utils.AssertTrue(t, strings.Contains(out.String(), "\nsitemap_generator_retries_total 2\n"))
+	utils.AssertTrue(t, strings.Contains(out.String(), "\nsitemap_generator_request_duration_seconds_count{method=\"HEAD\"} 4\n"))
+}
+
+func TestReader_CheckUrl_HeadFallback(t *testing.T) {
+	reader := readers.NewReader(readers.ReaderOptions{MaxRetries: 1})
+
+	requests := make([]string, 0)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.Header.Get("Range"))
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Content-Range", "bytes 0-0/13")
+		w.WriteHeader(http.StatusPartialContent)
+		_, _ = w.Write([]byte("<"))
+	}))
+	defer srv.Close()
+
+	info, err := reader.CheckUrl(srv.URL)
+	utils.AssertNoError(t, err)
+	utils.AssertEqual(t, info.StatusCode, http.StatusOK)
+	utils.AssertTrue(t, info.IsHtml)
+	utils.AssertEqual(t, requests, []string{"HEAD ", "GET bytes=0-511"})
+}
+
+func TestReader_FetchUrl(t *testing.T) {
+	reader := readers.NewReader(readers.ReaderOptions{MaxRetries: 1})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte("<html></html>"))
+	})
+	mux.HandleFunc("/doc.pdf", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		_, _ = w.Write([]byte("%PDF-1.4"))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	t.Run("HTML page is read", func(t *testing.T) {
+		page, err := reader.FetchUrl(srv.URL + "/page")
+		utils.AssertNoError(t, err)
+		utils.AssertTrue(t, page.Info.IsHtml)
+		utils.AssertEqual(t, page.Info.StatusCode, http.StatusOK)
+		utils.AssertEqual(t, string(page.Body), "<html></html>")
+	})
+
+	t.Run("not HTML is only checked", func(t *testing.T) {
+		page, err := reader.FetchUrl(srv.URL + "/doc.pdf")
+		utils.AssertNoError(t, err)
+		utils.AssertFalse(t, page.Info.IsHtml)
+		utils.AssertEmpty(t, page.Body)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := reader.FetchUrl(srv.URL + "/missing")
+		utils.AssertEqual(t, readers.ErrorStatusCode(err), http.StatusNotFound)
+	})
 }
 
 func TestReader_ReadUrl(t *testing.T) {
@@ -53,9 +248,10 @@ func TestReader_ReadUrl(t *testing.T) {
 		}))
 		defer srv.Close()
 
-		body, err := reader.ReadUrl(srv.URL)
+		page, err := reader.ReadUrl(srv.URL)
 		utils.AssertNoError(t, err)
-		utils.AssertEqual(t, string(body), string(expected))
+		utils.AssertEqual(t, page.StatusCode, http.StatusOK)
+		utils.AssertEqual(t, string(page.Body), string(expected))
 	})
 
 	t.Run("timeout while reading", func(t *testing.T) {
@@ -77,13 +273,71 @@ func TestReader_ReadUrl(t *testing.T) {
 	})
 
 	t.Run("too many retries", func(t *testing.T) {
-		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.WriteHeader(http.StatusMovedPermanently)
-			// no Location header will produce error in HTTP client
-		}))
+		srv := httptest.NewServer(http.HandlerFunc(closeConnection))
 		defer srv.Close()
 
 		_, err := reader.ReadUrl(srv.URL)
 		utils.AssertHasError(t, err, "Maximum retries exceeded with error")
 	})
 }
+
+func TestReader_ReadUrl_Truncated(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/long", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(strings.Repeat("a", 2000)))
+	})
+	mux.HandleFunc("/endless", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<html>"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	reader := readers.NewReader(readers.ReaderOptions{
+		Timeout:     time.Second,
+		MaxRetries:  1,
+		MaxBodySize: 1000,
+		BodyTimeout: 100 * time.Millisecond,
+	})
+
+	t.Run("size limit", func(t *testing.T) {
+		page, err := reader.ReadUrl(srv.URL + "/long")
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, len(page.Body), 1000)
+		utils.AssertEqual(t, page.Truncated, models.TruncatedSize)
+
+		page, err = reader.FetchUrl(srv.URL + "/long")
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, len(page.Body), 1000)
+		utils.AssertEqual(t, page.Truncated, models.TruncatedSize)
+	})
+
+	t.Run("read deadline", func(t *testing.T) {
+		page, err := reader.ReadUrl(srv.URL + "/endless")
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, string(page.Body), "<html>")
+		utils.AssertEqual(t, page.Truncated, models.TruncatedDeadline)
+	})
+
+	t.Run("streamed body", func(t *testing.T) {
+		var body []byte
+		page, err := reader.ReadUrlTo(srv.URL+"/long", func(page models.Page, src io.Reader) {
+			utils.AssertEqual(t, page.StatusCode, http.StatusOK)
+			body, _ = ioutil.ReadAll(src)
+		})
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, len(body), 1000)
+		utils.AssertEmpty(t, page.Body)
+		utils.AssertEqual(t, page.Truncated, models.TruncatedSize)
+	})
+
+	t.Run("complete body", func(t *testing.T) {
+		page, err := readers.NewReader(readers.ReaderOptions{MaxRetries: 1}).ReadUrl(srv.URL + "/long")
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, len(page.Body), 2000)
+		utils.AssertEmpty(t, page.Truncated)
+	})
+}
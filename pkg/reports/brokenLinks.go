@@ -0,0 +1,91 @@
+package reports
+
+import (
+	"net/url"
+	"sitemap-generator/pkg/crawlers"
+	crawlersModels "sitemap-generator/pkg/crawlers/models"
+	"sitemap-generator/pkg/readers"
+	"sitemap-generator/pkg/reports/models"
+	"sort"
+)
+
+// BrokenLinksCollector observes the crawl to gather links which could not be read
+type BrokenLinksCollector interface {
+	crawlers.Observer
+	Report() models.BrokenLinksReport
+}
+
+type brokenLinksCollector struct {
+	crawlers.NopObserver
+
+	startUrl  string
+	startHost string
+	links     map[string]*models.BrokenLink
+	// sources of each link, a page is listed once even if it's reported again (e.g. it's read twice)
+	sources map[string]map[models.LinkSource]bool
+}
+
+func NewBrokenLinksCollector(startUrl string) BrokenLinksCollector {
+	startHost := ""
+	if u, err := url.Parse(startUrl); err == nil {
+		startHost = u.Hostname()
+	}
+
+	return &brokenLinksCollector{
+		startUrl:  startUrl,
+		startHost: startHost,
+		links:     make(map[string]*models.BrokenLink),
+		sources:   make(map[string]map[models.LinkSource]bool),
+	}
+}
+
+func (bc *brokenLinksCollector) OnError(e crawlersModels.ErrorEvent) {
+	link, exists := bc.links[e.Url]
+	if !exists {
+		link = &models.BrokenLink{
+			Url:        e.Url,
+			Internal:   bc.isInternal(e.Url),
+			StatusCode: readers.ErrorStatusCode(e.Err),
+			ErrorKind:  readers.ErrorKind(e.Err),
+			Error:      e.Err.Error(),
+			Redirects:  readers.ErrorRedirects(e.Err),
+			Sources:    make([]models.LinkSource, 0),
+		}
+		bc.links[e.Url] = link
+		bc.sources[e.Url] = make(map[models.LinkSource]bool)
+	}
+
+	source := models.LinkSource{
+		Page: e.From,
+		Text: e.Text,
+	}
+	if e.From != "" && !bc.sources[e.Url][source] {
+		bc.sources[e.Url][source] = true
+		link.Sources = append(link.Sources, source)
+	}
+}
+
+// Report returns broken links sorted by URL, internal ones go first
+func (bc *brokenLinksCollector) Report() models.BrokenLinksReport {
+	report := models.BrokenLinksReport{
+		StartUrl: bc.startUrl,
+		Links:    make([]models.BrokenLink, 0, len(bc.links)),
+	}
+	for _, l := range bc.links {
+		report.Links = append(report.Links, *l)
+	}
+
+	sort.Slice(report.Links, func(i, j int) bool {
+		a, b := report.Links[i], report.Links[j]
+		if a.Internal != b.Internal {
+			return a.Internal
+		}
+		return a.Url < b.Url
+	})
+	return report
+}
+
+func (bc *brokenLinksCollector) isInternal(link string) bool {
+	u, err := url.Parse(link)
+	return err == nil && u.Hostname() == bc.startHost
+}
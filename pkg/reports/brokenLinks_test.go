@@ -0,0 +1,83 @@
+package reports_test
+
+import (
+	"fmt"
+	"net/http"
+	crawlersModels "sitemap-generator/pkg/crawlers/models"
+	"sitemap-generator/pkg/readers"
+	readersModels "sitemap-generator/pkg/readers/models"
+	"sitemap-generator/pkg/reports"
+	"sitemap-generator/pkg/reports/models"
+	"sitemap-generator/utils"
+	"testing"
+)
+
+func TestBrokenLinksCollector_Report(t *testing.T) {
+	notFound := &readers.HttpError{
+		StatusCode: http.StatusNotFound,
+		Status:     "404 Not Found",
+		Redirects: []readersModels.Redirect{
+			{StatusCode: http.StatusMovedPermanently, Location: "https://example.com/missing"},
+		},
+	}
+	timeout := &readers.TransportError{Err: fmt.Errorf("context deadline exceeded")}
+
+	collector := reports.NewBrokenLinksCollector("https://example.com/")
+	collector.OnError(crawlersModels.ErrorEvent{
+		Op:   crawlersModels.ErrorOpCheck,
+		Url:  "https://other.com/slow",
+		From: "https://example.com/",
+		Text: "Slow",
+		Err:  timeout,
+	})
+	collector.OnError(crawlersModels.ErrorEvent{
+		Op:   crawlersModels.ErrorOpCheck,
+		Url:  "https://example.com/old",
+		From: "https://example.com/",
+		Text: "Old page",
+		Err:  notFound,
+	})
+	collector.OnError(crawlersModels.ErrorEvent{
+		Op:   crawlersModels.ErrorOpCheck,
+		Url:  "https://example.com/old",
+		From: "https://example.com/about",
+		Text: "Old",
+		Err:  notFound,
+	})
+	// the same link of the same page is listed once
+	collector.OnError(crawlersModels.ErrorEvent{
+		Op:   crawlersModels.ErrorOpCheck,
+		Url:  "https://example.com/old",
+		From: "https://example.com/",
+		Text: "Old page",
+		Err:  notFound,
+	})
+
+	report := collector.Report()
+	utils.AssertTrue(t, report.HasInternal())
+	utils.AssertEqual(t, report, models.BrokenLinksReport{
+		StartUrl: "https://example.com/",
+		Links: []models.BrokenLink{
+			{
+				Url:        "https://example.com/old",
+				Internal:   true,
+				StatusCode: http.StatusNotFound,
+				ErrorKind:  readers.ErrorKindHttp,
+				Error:      "HTTP error [404] 404 Not Found",
+				Redirects:  notFound.Redirects,
+				Sources: []models.LinkSource{
+					{Page: "https://example.com/", Text: "Old page"},
+					{Page: "https://example.com/about", Text: "Old"},
+				},
+			},
+			{
+				Url:       "https://other.com/slow",
+				ErrorKind: readers.ErrorKindTimeout,
+				Error:     "context deadline exceeded",
+				Sources: []models.LinkSource{
+					{Page: "https://example.com/", Text: "Slow"},
+				},
+			},
+		},
+	})
+}
@@ -0,0 +1,71 @@
+package reports
+
+import (
+	"sitemap-generator/pkg/crawlers"
+	crawlersModels "sitemap-generator/pkg/crawlers/models"
+	"sitemap-generator/pkg/reports/models"
+	"sort"
+)
+
+// DuplicatesCollector observes the crawl to gather URLs excluded because of duplicate content
+type DuplicatesCollector interface {
+	crawlers.Observer
+	Report() models.DuplicatesReport
+}
+
+type duplicatesCollector struct {
+	crawlers.NopObserver
+
+	startUrl string
+	clusters map[string]*models.DuplicateCluster
+}
+
+func NewDuplicatesCollector(startUrl string) DuplicatesCollector {
+	return &duplicatesCollector{
+		startUrl: startUrl,
+		clusters: make(map[string]*models.DuplicateCluster),
+	}
+}
+
+func (dc *duplicatesCollector) OnUrlSkipped(e crawlersModels.UrlSkippedEvent) {
+	if e.Reason != crawlersModels.SkipReasonDuplicateContent {
+		return
+	}
+	cluster, exists := dc.clusters[e.DuplicateOf]
+	if !exists {
+		cluster = &models.DuplicateCluster{
+			Url:        e.DuplicateOf,
+			Duplicates: make([]models.Duplicate, 0),
+		}
+		dc.clusters[e.DuplicateOf] = cluster
+	}
+	cluster.Duplicates = append(cluster.Duplicates, models.Duplicate{
+		Url:     e.Url,
+		Details: e.Details,
+	})
+}
+
+// Report returns the clusters sorted by URL, the largest ones go first
+func (dc *duplicatesCollector) Report() models.DuplicatesReport {
+	report := models.DuplicatesReport{
+		StartUrl: dc.startUrl,
+		Clusters: make([]models.DuplicateCluster, 0, len(dc.clusters)),
+	}
+	for _, c := range dc.clusters {
+		cluster := *c
+		cluster.Duplicates = append([]models.Duplicate{}, c.Duplicates...)
+		sort.Slice(cluster.Duplicates, func(i, j int) bool {
+			return cluster.Duplicates[i].Url < cluster.Duplicates[j].Url
+		})
+		report.Clusters = append(report.Clusters, cluster)
+	}
+
+	sort.Slice(report.Clusters, func(i, j int) bool {
+		a, b := report.Clusters[i], report.Clusters[j]
+		if len(a.Duplicates) != len(b.Duplicates) {
+			return len(a.Duplicates) > len(b.Duplicates)
+		}
+		return a.Url < b.Url
+	})
+	return report
+}
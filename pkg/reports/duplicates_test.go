@@ -0,0 +1,49 @@
+package reports_test
+
+import (
+	crawlersModels "sitemap-generator/pkg/crawlers/models"
+	"sitemap-generator/pkg/reports"
+	"sitemap-generator/pkg/reports/models"
+	"sitemap-generator/utils"
+	"testing"
+)
+
+func TestDuplicatesCollector_Report(t *testing.T) {
+	collector := reports.NewDuplicatesCollector("https://example.com/")
+	skip := func(url, duplicateOf, details string) {
+		collector.OnUrlSkipped(crawlersModels.UrlSkippedEvent{
+			Url:         url,
+			Depth:       1,
+			Reason:      crawlersModels.SkipReasonDuplicateContent,
+			Details:     details,
+			DuplicateOf: duplicateOf,
+		})
+	}
+	skip("https://example.com/pricing?ref=ad", "https://example.com/pricing", "exact duplicate")
+	skip("https://example.com/print/guide", "https://example.com/guide", "near-duplicate (2 bits differ)")
+	skip("https://example.com/guide?ref=home", "https://example.com/guide", "exact duplicate")
+	collector.OnUrlSkipped(crawlersModels.UrlSkippedEvent{
+		Url:    "https://example.com/",
+		Depth:  2,
+		Reason: crawlersModels.SkipReasonDuplicate,
+	})
+
+	utils.AssertEqual(t, collector.Report(), models.DuplicatesReport{
+		StartUrl: "https://example.com/",
+		Clusters: []models.DuplicateCluster{
+			{
+				Url: "https://example.com/guide",
+				Duplicates: []models.Duplicate{
+					{Url: "https://example.com/guide?ref=home", Details: "exact duplicate"},
+					{Url: "https://example.com/print/guide", Details: "near-duplicate (2 bits differ)"},
+				},
+			},
+			{
+				Url: "https://example.com/pricing",
+				Duplicates: []models.Duplicate{
+					{Url: "https://example.com/pricing?ref=ad", Details: "exact duplicate"},
+				},
+			},
+		},
+	})
+}
@@ -0,0 +1,35 @@
+package models
+
+import readersModels "sitemap-generator/pkg/readers/models"
+
+type BrokenLinksReport struct {
+	StartUrl string       `json:"startUrl"`
+	Links    []BrokenLink `json:"links"`
+}
+
+type BrokenLink struct {
+	Url string `json:"url"`
+	// Internal means the link has the same host as the start URL
+	Internal   bool                     `json:"internal"`
+	StatusCode int                      `json:"statusCode,omitempty"`
+	ErrorKind  string                   `json:"errorKind"`
+	Error      string                   `json:"error"`
+	Redirects  []readersModels.Redirect `json:"redirects,omitempty"`
+	Sources    []LinkSource             `json:"sources"`
+}
+
+// LinkSource is the page where the link was found
+type LinkSource struct {
+	Page string `json:"page"`
+	Text string `json:"text"`
+}
+
+// HasInternal tells if there are broken links to the same site
+func (r *BrokenLinksReport) HasInternal() bool {
+	for _, l := range r.Links {
+		if l.Internal {
+			return true
+		}
+	}
+	return false
+}
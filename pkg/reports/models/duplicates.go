@@ -0,0 +1,17 @@
+package models
+
+type DuplicatesReport struct {
+	StartUrl string             `json:"startUrl"`
+	Clusters []DuplicateCluster `json:"clusters"`
+}
+
+// DuplicateCluster is URL collected to the sitemap and URLs with the same content excluded from it
+type DuplicateCluster struct {
+	Url        string      `json:"url"`
+	Duplicates []Duplicate `json:"duplicates"`
+}
+
+type Duplicate struct {
+	Url     string `json:"url"`
+	Details string `json:"details"`
+}
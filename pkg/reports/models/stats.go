@@ -0,0 +1,58 @@
+package models
+
+import (
+	crawlersModels "sitemap-generator/pkg/crawlers/models"
+	"time"
+)
+
+// StatsReport sums up the crawl, so the runs can be compared to notice regressions (e.g. a drop of URLs)
+type StatsReport struct {
+	StartUrl  string        `json:"startUrl"`
+	StartedAt time.Time     `json:"startedAt"`
+	Duration  time.Duration `json:"duration"`
+	// PagesFetched is the number of pages read to scan them for links
+	PagesFetched int `json:"pagesFetched"`
+	// UrlsCollected is the number of URLs sent to the sitemap
+	UrlsCollected int `json:"urlsCollected"`
+	// NotScanned is the number of collected URLs whose links are not scanned per reason
+	NotScanned map[crawlersModels.NotScannedReason]int `json:"notScanned"`
+	// Skipped is the number of URLs skipped per reason
+	Skipped map[crawlersModels.SkipReason]int `json:"skipped"`
+	Errors  ErrorStats                        `json:"errors"`
+	// Redirects is the number of links replaced with the URLs they're redirected to
+	Redirects int `json:"redirects"`
+	// Bytes is the number of bytes of the fetched pages on the wire (or read, when it's unknown)
+	Bytes    int64          `json:"bytes"`
+	Transfer TransferReport `json:"transfer"`
+	// MaxDepth is the depth of the deepest URL fetched or collected
+	MaxDepth int `json:"maxDepth"`
+	// Hosts are sorted by name
+	Hosts []HostStats `json:"hosts"`
+}
+
+type ErrorStats struct {
+	Total int `json:"total"`
+	// ByOp is the number of errors per operation (read or check)
+	ByOp map[crawlersModels.ErrorOp]int `json:"byOp"`
+	// ByKind is the number of errors per kind (http, timeout, connection etc.)
+	ByKind map[string]int `json:"byKind"`
+	// ByStatus is the number of HTTP errors per status code
+	ByStatus map[int]int `json:"byStatus"`
+}
+
+// HostStats is about the pages fetched from the host
+type HostStats struct {
+	Host    string       `json:"host"`
+	Pages   int          `json:"pages"`
+	Bytes   int64        `json:"bytes"`
+	Latency LatencyStats `json:"latency"`
+}
+
+// LatencyStats is about the time of the page fetching, percentiles are the nearest-rank ones
+type LatencyStats struct {
+	Average time.Duration `json:"average"`
+	P50     time.Duration `json:"p50"`
+	P90     time.Duration `json:"p90"`
+	P99     time.Duration `json:"p99"`
+	Max     time.Duration `json:"max"`
+}
@@ -0,0 +1,18 @@
+package models
+
+type TransferReport struct {
+	// Responses is the number of responses which bodies were read
+	Responses    int   `json:"responses"`
+	WireBytes    int64 `json:"wireBytes"`
+	DecodedBytes int64 `json:"decodedBytes"`
+	// Encodings is the number of responses per content encoding ("identity" for not encoded ones)
+	Encodings map[string]int `json:"encodings"`
+}
+
+// Ratio tells how many times the content is compressed on the wire, 1 if it's not
+func (tr TransferReport) Ratio() float64 {
+	if tr.WireBytes == 0 {
+		return 1
+	}
+	return float64(tr.DecodedBytes) / float64(tr.WireBytes)
+}
@@ -0,0 +1,18 @@
+package models
+
+import crawlersModels "sitemap-generator/pkg/crawlers/models"
+
+type TrapsReport struct {
+	StartUrl string    `json:"startUrl"`
+	Urls     []TrapUrl `json:"urls"`
+	// Counts is the number of skipped URLs per trap kind
+	Counts map[crawlersModels.TrapKind]int `json:"counts"`
+}
+
+// TrapUrl is URL skipped as a crawler trap
+type TrapUrl struct {
+	Url     string                  `json:"url"`
+	Depth   int                     `json:"depth"`
+	Trap    crawlersModels.TrapKind `json:"trap"`
+	Details string                  `json:"details"`
+}
@@ -0,0 +1,185 @@
+package reports
+
+import (
+	"net/url"
+	"sitemap-generator/pkg/crawlers"
+	crawlersModels "sitemap-generator/pkg/crawlers/models"
+	"sitemap-generator/pkg/readers"
+	"sitemap-generator/pkg/reports/models"
+	"sort"
+	"strings"
+	"time"
+)
+
+// StatsCollector observes the crawl to sum it up; the crawl is measured
+// from the creation of the collector to the report, so it's created right before the crawl
+type StatsCollector interface {
+	crawlers.Observer
+	Report() models.StatsReport
+}
+
+type hostLatencies struct {
+	pages     int
+	bytes     int64
+	latencies []time.Duration
+}
+
+type statsCollector struct {
+	crawlers.NopObserver
+
+	report   models.StatsReport
+	transfer TransferCollector
+	hosts    map[string]*hostLatencies
+	// skipped and failed URLs are counted once, even if they're linked from several pages
+	skipped map[crawlersModels.SkipReason]map[string]bool
+	failed  map[string]bool
+}
+
+func NewStatsCollector(startUrl string) StatsCollector {
+	return &statsCollector{
+		report: models.StatsReport{
+			StartUrl:   startUrl,
+			StartedAt:  time.Now(),
+			Skipped:    make(map[crawlersModels.SkipReason]int),
+			NotScanned: make(map[crawlersModels.NotScannedReason]int),
+			Errors: models.ErrorStats{
+				ByOp:     make(map[crawlersModels.ErrorOp]int),
+				ByKind:   make(map[string]int),
+				ByStatus: make(map[int]int),
+			},
+		},
+		transfer: NewTransferCollector(),
+		hosts:    make(map[string]*hostLatencies),
+		skipped:  make(map[crawlersModels.SkipReason]map[string]bool),
+		failed:   make(map[string]bool),
+	}
+}
+
+func (sc *statsCollector) OnPageFetched(e crawlersModels.PageFetchedEvent) {
+	sc.transfer.OnPageFetched(e)
+
+	bytes := e.Transfer.WireBytes
+	if bytes == 0 {
+		bytes = int64(e.BodySize)
+	}
+	sc.report.PagesFetched++
+	sc.report.Bytes += bytes
+	sc.reachDepth(e.Depth)
+
+	host := statsHost(e.Url)
+	h, ok := sc.hosts[host]
+	if !ok {
+		h = &hostLatencies{latencies: make([]time.Duration, 0)}
+		sc.hosts[host] = h
+	}
+	h.pages++
+	h.bytes += bytes
+	h.latencies = append(h.latencies, e.Duration)
+}
+
+func (sc *statsCollector) OnRedirect(crawlersModels.RedirectEvent) {
+	sc.report.Redirects++
+}
+
+func (sc *statsCollector) OnUrlCollected(e crawlersModels.UrlCollectedEvent) {
+	sc.report.UrlsCollected++
+	if e.NotScanned != "" {
+		sc.report.NotScanned[e.NotScanned]++
+	}
+	sc.reachDepth(e.Depth)
+}
+
+func (sc *statsCollector) OnUrlSkipped(e crawlersModels.UrlSkippedEvent) {
+	urls, ok := sc.skipped[e.Reason]
+	if !ok {
+		urls = make(map[string]bool)
+		sc.skipped[e.Reason] = urls
+	}
+	if urls[e.Url] {
+		return
+	}
+	urls[e.Url] = true
+	sc.report.Skipped[e.Reason]++
+}
+
+func (sc *statsCollector) OnError(e crawlersModels.ErrorEvent) {
+	key := string(e.Op) + " " + e.Url
+	if sc.failed[key] {
+		return
+	}
+	sc.failed[key] = true
+
+	sc.report.Errors.Total++
+	sc.report.Errors.ByOp[e.Op]++
+	sc.report.Errors.ByKind[readers.ErrorKind(e.Err)]++
+	if status := readers.ErrorStatusCode(e.Err); status != 0 {
+		sc.report.Errors.ByStatus[status]++
+	}
+}
+
+func (sc *statsCollector) reachDepth(depth int) {
+	if depth > sc.report.MaxDepth {
+		sc.report.MaxDepth = depth
+	}
+}
+
+// Report returns the stats of the crawl until now
+func (sc *statsCollector) Report() models.StatsReport {
+	report := sc.report
+	report.Duration = time.Since(report.StartedAt)
+	report.Transfer = sc.transfer.Report()
+
+	report.Hosts = make([]models.HostStats, 0, len(sc.hosts))
+	for host, h := range sc.hosts {
+		report.Hosts = append(report.Hosts, models.HostStats{
+			Host:    host,
+			Pages:   h.pages,
+			Bytes:   h.bytes,
+			Latency: latencyStats(h.latencies),
+		})
+	}
+	sort.Slice(report.Hosts, func(i, j int) bool {
+		return report.Hosts[i].Host < report.Hosts[j].Host
+	})
+	return report
+}
+
+func latencyStats(latencies []time.Duration) models.LatencyStats {
+	if len(latencies) == 0 {
+		return models.LatencyStats{}
+	}
+	sorted := append([]time.Duration{}, latencies...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+
+	var sum time.Duration
+	for _, l := range sorted {
+		sum += l
+	}
+	return models.LatencyStats{
+		Average: sum / time.Duration(len(sorted)),
+		P50:     percentile(sorted, 50),
+		P90:     percentile(sorted, 90),
+		P99:     percentile(sorted, 99),
+		Max:     sorted[len(sorted)-1],
+	}
+}
+
+// percentile is the nearest-rank percentile of sorted latencies
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// statsHost is the lower-cased host of URL, empty if URL is invalid
+func statsHost(location string) string {
+	u, err := url.Parse(location)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Host)
+}
@@ -0,0 +1,97 @@
+package reports_test
+
+import (
+	"errors"
+	crawlersModels "sitemap-generator/pkg/crawlers/models"
+	"sitemap-generator/pkg/readers"
+	readersModels "sitemap-generator/pkg/readers/models"
+	"sitemap-generator/pkg/reports"
+	"sitemap-generator/pkg/reports/models"
+	"sitemap-generator/utils"
+	"testing"
+	"time"
+)
+
+func TestStatsCollector_Report(t *testing.T) {
+	collector := reports.NewStatsCollector("https://example.com/")
+
+	for i := 1; i <= 10; i++ {
+		collector.OnPageFetched(crawlersModels.PageFetchedEvent{
+			Url:      "https://example.com/page",
+			Depth:    1,
+			Duration: time.Duration(i) * time.Millisecond,
+			BodySize: 400,
+			Transfer: readersModels.Transfer{Encoding: "gzip", WireBytes: 100, DecodedBytes: 400},
+		})
+	}
+	// the file reader doesn't know bytes on the wire
+	collector.OnPageFetched(crawlersModels.PageFetchedEvent{
+		Url:      "https://Blog.example.com/",
+		Duration: 5 * time.Millisecond,
+		BodySize: 50,
+	})
+
+	collector.OnUrlCollected(crawlersModels.UrlCollectedEvent{Url: crawlersModels.Url{Location: "https://example.com/page"}, Depth: 1})
+	collector.OnUrlCollected(crawlersModels.UrlCollectedEvent{Url: crawlersModels.Url{Location: "https://example.com/deep"}, Depth: 3, NotScanned: crawlersModels.NotScannedTooDeep})
+	collector.OnRedirect(crawlersModels.RedirectEvent{From: "https://example.com/old", To: "https://example.com/page"})
+
+	collector.OnUrlSkipped(crawlersModels.UrlSkippedEvent{Url: "https://example.com/calendar/1/1/1/1", Reason: crawlersModels.SkipReasonTrap})
+	// the same trap linked from another page
+	collector.OnUrlSkipped(crawlersModels.UrlSkippedEvent{Url: "https://example.com/calendar/1/1/1/1", Reason: crawlersModels.SkipReasonTrap})
+	collector.OnUrlSkipped(crawlersModels.UrlSkippedEvent{Url: "https://example.com/file.zip", Reason: crawlersModels.SkipReasonStatus})
+
+	notFound := &readers.HttpError{StatusCode: 404, Status: "404 Not Found"}
+	collector.OnError(crawlersModels.ErrorEvent{Op: crawlersModels.ErrorOpCheck, Url: "https://example.com/missing", From: "https://example.com/", Err: notFound})
+	collector.OnError(crawlersModels.ErrorEvent{Op: crawlersModels.ErrorOpCheck, Url: "https://example.com/missing", From: "https://example.com/page", Err: notFound})
+	collector.OnError(crawlersModels.ErrorEvent{Op: crawlersModels.ErrorOpRead, Url: "https://example.com/slow", Err: &readers.TransportError{Err: errors.New("connection refused")}})
+
+	report := collector.Report()
+	utils.AssertEqual(t, report.StartUrl, "https://example.com/")
+	utils.AssertTrue(t, report.Duration > 0)
+	utils.AssertEqual(t, report.PagesFetched, 11)
+	utils.AssertEqual(t, report.UrlsCollected, 2)
+	utils.AssertEqual(t, report.Redirects, 1)
+	utils.AssertEqual(t, report.MaxDepth, 3)
+	utils.AssertEqual(t, report.Bytes, int64(1050))
+	utils.AssertEqual(t, report.Transfer.Responses, 10)
+	utils.AssertEqual(t, report.Skipped, map[crawlersModels.SkipReason]int{
+		crawlersModels.SkipReasonTrap:   1,
+		crawlersModels.SkipReasonStatus: 1,
+	})
+	utils.AssertEqual(t, report.NotScanned, map[crawlersModels.NotScannedReason]int{
+		crawlersModels.NotScannedTooDeep: 1,
+	})
+	utils.AssertEqual(t, report.Errors, models.ErrorStats{
+		Total:    2,
+		ByOp:     map[crawlersModels.ErrorOp]int{crawlersModels.ErrorOpCheck: 1, crawlersModels.ErrorOpRead: 1},
+		ByKind:   map[string]int{readers.ErrorKindHttp: 1, readers.ErrorKindConnection: 1},
+		ByStatus: map[int]int{404: 1},
+	})
+
+	utils.AssertEqual(t, report.Hosts, []models.HostStats{
+		{
+			Host:  "blog.example.com",
+			Pages: 1,
+			Bytes: 50,
+			Latency: models.LatencyStats{
+				Average: 5 * time.Millisecond,
+				P50:     5 * time.Millisecond,
+				P90:     5 * time.Millisecond,
+				P99:     5 * time.Millisecond,
+				Max:     5 * time.Millisecond,
+			},
+		},
+		{
+			Host:  "example.com",
+			Pages: 10,
+			Bytes: 1000,
+			Latency: models.LatencyStats{
+				Average: 5500 * time.Microsecond,
+				P50:     5 * time.Millisecond,
+				P90:     9 * time.Millisecond,
+				P99:     10 * time.Millisecond,
+				Max:     10 * time.Millisecond,
+			},
+		},
+	})
+}
@@ -0,0 +1,47 @@
+package reports
+
+import (
+	"sitemap-generator/pkg/crawlers"
+	crawlersModels "sitemap-generator/pkg/crawlers/models"
+	"sitemap-generator/pkg/reports/models"
+)
+
+// TransferCollector observes the crawl to sum up bytes transferred for the pages
+type TransferCollector interface {
+	crawlers.Observer
+	Report() models.TransferReport
+}
+
+type transferCollector struct {
+	crawlers.NopObserver
+
+	report models.TransferReport
+}
+
+func NewTransferCollector() TransferCollector {
+	return &transferCollector{
+		report: models.TransferReport{
+			Encodings: make(map[string]int),
+		},
+	}
+}
+
+func (tc *transferCollector) OnPageFetched(e crawlersModels.PageFetchedEvent) {
+	if e.Transfer.WireBytes == 0 && e.Transfer.DecodedBytes == 0 {
+		return
+	}
+
+	encoding := e.Transfer.Encoding
+	if encoding == "" {
+		encoding = "identity"
+	}
+
+	tc.report.Responses++
+	tc.report.WireBytes += e.Transfer.WireBytes
+	tc.report.DecodedBytes += e.Transfer.DecodedBytes
+	tc.report.Encodings[encoding]++
+}
+
+func (tc *transferCollector) Report() models.TransferReport {
+	return tc.report
+}
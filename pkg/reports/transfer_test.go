@@ -0,0 +1,32 @@
+package reports_test
+
+import (
+	crawlersModels "sitemap-generator/pkg/crawlers/models"
+	readersModels "sitemap-generator/pkg/readers/models"
+	"sitemap-generator/pkg/reports"
+	"sitemap-generator/utils"
+	"testing"
+)
+
+func TestTransferCollector_Report(t *testing.T) {
+	collector := reports.NewTransferCollector()
+	collector.OnPageFetched(crawlersModels.PageFetchedEvent{
+		Url:      "https://example.com/",
+		Transfer: readersModels.Transfer{Encoding: "gzip", WireBytes: 100, DecodedBytes: 400},
+	})
+	collector.OnPageFetched(crawlersModels.PageFetchedEvent{
+		Url:      "https://example.com/about",
+		Transfer: readersModels.Transfer{WireBytes: 200, DecodedBytes: 200},
+	})
+	// checked only, nothing is read
+	collector.OnPageFetched(crawlersModels.PageFetchedEvent{
+		Url: "https://example.com/doc.pdf",
+	})
+
+	report := collector.Report()
+	utils.AssertEqual(t, report.Responses, 2)
+	utils.AssertEqual(t, report.WireBytes, int64(300))
+	utils.AssertEqual(t, report.DecodedBytes, int64(600))
+	utils.AssertEqual(t, report.Ratio(), 2.0)
+	utils.AssertEqual(t, report.Encodings, map[string]int{"gzip": 1, "identity": 1})
+}
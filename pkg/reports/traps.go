@@ -0,0 +1,65 @@
+package reports
+
+import (
+	"sitemap-generator/pkg/crawlers"
+	crawlersModels "sitemap-generator/pkg/crawlers/models"
+	"sitemap-generator/pkg/reports/models"
+	"sort"
+)
+
+// TrapsCollector observes the crawl to gather URLs skipped as crawler traps
+type TrapsCollector interface {
+	crawlers.Observer
+	Report() models.TrapsReport
+}
+
+type trapsCollector struct {
+	crawlers.NopObserver
+
+	startUrl string
+	urls     []models.TrapUrl
+	// seen URLs are reported once, even if they're linked from several pages
+	seen map[string]bool
+}
+
+func NewTrapsCollector(startUrl string) TrapsCollector {
+	return &trapsCollector{
+		startUrl: startUrl,
+		urls:     make([]models.TrapUrl, 0),
+		seen:     make(map[string]bool),
+	}
+}
+
+func (tc *trapsCollector) OnUrlSkipped(e crawlersModels.UrlSkippedEvent) {
+	if e.Reason != crawlersModels.SkipReasonTrap || tc.seen[e.Url] {
+		return
+	}
+	tc.seen[e.Url] = true
+	tc.urls = append(tc.urls, models.TrapUrl{
+		Url:     e.Url,
+		Depth:   e.Depth,
+		Trap:    e.Trap,
+		Details: e.Details,
+	})
+}
+
+// Report returns URLs sorted by trap kind and URL
+func (tc *trapsCollector) Report() models.TrapsReport {
+	report := models.TrapsReport{
+		StartUrl: tc.startUrl,
+		Urls:     append([]models.TrapUrl{}, tc.urls...),
+		Counts:   make(map[crawlersModels.TrapKind]int),
+	}
+	for _, u := range report.Urls {
+		report.Counts[u.Trap]++
+	}
+
+	sort.Slice(report.Urls, func(i, j int) bool {
+		a, b := report.Urls[i], report.Urls[j]
+		if a.Trap != b.Trap {
+			return a.Trap < b.Trap
+		}
+		return a.Url < b.Url
+	})
+	return report
+}
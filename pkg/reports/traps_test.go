@@ -0,0 +1,54 @@
+package reports_test
+
+import (
+	crawlersModels "sitemap-generator/pkg/crawlers/models"
+	"sitemap-generator/pkg/reports"
+	"sitemap-generator/pkg/reports/models"
+	"sitemap-generator/utils"
+	"testing"
+)
+
+func TestTrapsCollector_Report(t *testing.T) {
+	collector := reports.NewTrapsCollector("https://example.com/")
+	collector.OnUrlSkipped(crawlersModels.UrlSkippedEvent{
+		Url:     "https://example.com/a/a/a/a/",
+		Depth:   4,
+		Reason:  crawlersModels.SkipReasonTrap,
+		Details: "segment a repeats 4 times",
+		Trap:    crawlersModels.TrapRepeatedSegments,
+	})
+	collector.OnUrlSkipped(crawlersModels.UrlSkippedEvent{
+		Url:    "https://example.com/",
+		Depth:  2,
+		Reason: crawlersModels.SkipReasonDuplicate,
+	})
+	for i := 0; i < 2; i++ {
+		collector.OnUrlSkipped(crawlersModels.UrlSkippedEvent{
+			Url:     "https://example.com/cart;jsessionid=42",
+			Depth:   1,
+			Reason:  crawlersModels.SkipReasonTrap,
+			Details: "parameter jsessionid",
+			Trap:    crawlersModels.TrapSessionId,
+		})
+	}
+	collector.OnUrlSkipped(crawlersModels.UrlSkippedEvent{
+		Url:     "https://example.com/cart;jsessionid=43",
+		Depth:   1,
+		Reason:  crawlersModels.SkipReasonTrap,
+		Details: "parameter jsessionid",
+		Trap:    crawlersModels.TrapSessionId,
+	})
+
+	utils.AssertEqual(t, collector.Report(), models.TrapsReport{
+		StartUrl: "https://example.com/",
+		Urls: []models.TrapUrl{
+			{Url: "https://example.com/a/a/a/a/", Depth: 4, Trap: crawlersModels.TrapRepeatedSegments, Details: "segment a repeats 4 times"},
+			{Url: "https://example.com/cart;jsessionid=42", Depth: 1, Trap: crawlersModels.TrapSessionId, Details: "parameter jsessionid"},
+			{Url: "https://example.com/cart;jsessionid=43", Depth: 1, Trap: crawlersModels.TrapSessionId, Details: "parameter jsessionid"},
+		},
+		Counts: map[crawlersModels.TrapKind]int{
+			crawlersModels.TrapRepeatedSegments: 1,
+			crawlersModels.TrapSessionId:        2,
+		},
+	})
+}
@@ -0,0 +1,95 @@
+package workerPools
+
+import "sync"
+
+// Queue keeps tasks until some worker is free and decides which one is taken next;
+// calls of the pool are serialized, so implementations don't need to be safe for concurrent use because of it
+type Queue[T any] interface {
+	Push(v T)
+	// Pop takes the next item, returns *false* if the queue is empty
+	Pop() (v T, ok bool)
+}
+
+// fifoQueue gives items in the order they're added
+type fifoQueue[T any] struct {
+	items []T
+}
+
+func NewFifoQueue[T any]() Queue[T] {
+	return &fifoQueue[T]{
+		items: make([]T, 0),
+	}
+}
+
+func (fq *fifoQueue[T]) Push(v T) {
+	fq.items = append(fq.items, v)
+}
+
+func (fq *fifoQueue[T]) Pop() (v T, ok bool) {
+	if len(fq.items) == 0 {
+		return v, false
+	}
+	v = fq.items[0]
+	var zero T
+	fq.items[0] = zero
+	fq.items = fq.items[1:]
+	return v, true
+}
+
+// queue keeps items until they're taken, so adding an item never blocks;
+// the item is popped only when someone takes it, so the order of Queue applies to all the items added until then
+type queue[T any] struct {
+	locker sync.Mutex
+	items  Queue[T]
+	closed bool
+	// available wakes up the ones waiting for new items or the close
+	available *sync.Cond
+}
+
+func newQueue[T any](items Queue[T]) *queue[T] {
+	q := &queue[T]{items: items}
+	q.available = sync.NewCond(&q.locker)
+	return q
+}
+
+func (q *queue[T]) push(v T) {
+	q.locker.Lock()
+	q.items.Push(v)
+	q.locker.Unlock()
+
+	q.available.Signal()
+}
+
+// take waits for the next item, returns *false* if the queue is closed and nothing is left
+func (q *queue[T]) take() (v T, ok bool) {
+	q.locker.Lock()
+	defer q.locker.Unlock()
+
+	for {
+		if v, ok = q.items.Pop(); ok || q.closed {
+			return
+		}
+		q.available.Wait()
+	}
+}
+
+// close lets the ones waiting for items take the rest of them and stop then
+func (q *queue[T]) close() {
+	q.locker.Lock()
+	q.closed = true
+	q.locker.Unlock()
+
+	q.available.Broadcast()
+}
+
+// drain passes items to the channel until the queue is closed and nothing is left, then closes the channel
+func (q *queue[T]) drain(dest chan<- T) {
+	for {
+		v, ok := q.take()
+		if !ok {
+			close(dest)
+			return
+		}
+		dest <- v
+	}
+}
@@ -0,0 +1,157 @@
+package workerPools_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sitemap-generator/pkg/workerPools"
+	"sitemap-generator/services"
+	"sitemap-generator/utils"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFifoQueue(t *testing.T) {
+	q := workerPools.NewFifoQueue[string]()
+	_, ok := q.Pop()
+	utils.AssertFalse(t, ok)
+
+	q.Push("a")
+	q.Push("b")
+	v, ok := q.Pop()
+	utils.AssertTrue(t, ok)
+	utils.AssertEqual(t, v, "a")
+
+	q.Push("c")
+	for _, expected := range []string{"b", "c"} {
+		v, ok = q.Pop()
+		utils.AssertTrue(t, ok)
+		utils.AssertEqual(t, v, expected)
+	}
+	_, ok = q.Pop()
+	utils.AssertFalse(t, ok)
+}
+
+// maxQueue gives the greatest item first
+type maxQueue struct {
+	items []int
+}
+
+func (mq *maxQueue) Push(v int) {
+	mq.items = append(mq.items, v)
+}
+
+func (mq *maxQueue) Pop() (v int, ok bool) {
+	if len(mq.items) == 0 {
+		return 0, false
+	}
+	max := 0
+	for i, item := range mq.items {
+		if item > mq.items[max] {
+			max = i
+		}
+	}
+	v = mq.items[max]
+	mq.items = append(mq.items[:max], mq.items[max+1:]...)
+	return v, true
+}
+
+func TestWorkerPool_Queue(t *testing.T) {
+	logger, err := services.NewLogger(os.Stderr, "testing", "error")
+	utils.AssertNoError(t, err)
+
+	t.Run("tasks are taken in the order they're added", func(t *testing.T) {
+		wp := workerPools.NewTypedWorkerPool[int](logger, workerPools.WorkerPoolOptions{WorkersCount: 1})
+		results := make([]int, 0)
+		_, err := wp.Init(func(_ context.Context, task int) error {
+			results = append(results, task)
+			return nil
+		})
+		utils.AssertNoError(t, err)
+
+		for i := 1; i <= 5; i++ {
+			wp.AddTask(i)
+		}
+		wp.WaitFinalize()
+		utils.AssertEqual(t, results, []int{1, 2, 3, 4, 5})
+	})
+
+	t.Run("task is taken from the queue only when a worker is free", func(t *testing.T) {
+		wp := workerPools.NewQueuedWorkerPool[int](logger, workerPools.WorkerPoolOptions{WorkersCount: 1}, &maxQueue{})
+		started := make(chan struct{})
+		release := make(chan struct{})
+		results := make([]int, 0)
+		_, err := wp.Init(func(_ context.Context, task int) error {
+			if task == 0 {
+				close(started)
+				<-release
+			}
+			results = append(results, task)
+			return nil
+		})
+		utils.AssertNoError(t, err)
+
+		// the worker is busy, so the task added later still goes ahead of the queued ones
+		wp.AddTask(0)
+		<-started
+		wp.AddTask(1)
+		// nothing takes the first queued task meanwhile
+		time.Sleep(10 * time.Millisecond)
+		wp.AddTask(5)
+		close(release)
+		wp.WaitFinalize()
+		utils.AssertEqual(t, results, []int{0, 5, 1})
+	})
+
+	t.Run("workers add tasks without blocking when all of them are busy", func(t *testing.T) {
+		tasksCount := 1000
+		wp := workerPools.NewTypedWorkerPool[int](logger, workerPools.WorkerPoolOptions{WorkersCount: 2})
+		var processed int64
+		_, err := wp.Init(func(_ context.Context, task int) error {
+			atomic.AddInt64(&processed, 1)
+			// the first task adds all the rest at once, far more than the workers can take
+			if task == 0 {
+				for i := 1; i < tasksCount; i++ {
+					wp.AddTask(i)
+				}
+			}
+			return nil
+		})
+		utils.AssertNoError(t, err)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			wp.AddTask(0)
+			wp.WaitFinalize()
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("worker pool is blocked")
+		}
+		utils.AssertEqual(t, atomic.LoadInt64(&processed), int64(tasksCount))
+	})
+
+	t.Run("errors are kept until they're read", func(t *testing.T) {
+		tasksCount := 50
+		wp := workerPools.NewTypedWorkerPool[int](logger, workerPools.WorkerPoolOptions{WorkersCount: 4})
+		_, err := wp.Init(func(_ context.Context, task int) error {
+			return fmt.Errorf("task %d failed", task)
+		})
+		utils.AssertNoError(t, err)
+
+		for i := 0; i < tasksCount; i++ {
+			wp.AddTask(i)
+		}
+		// nobody reads the errors while the tasks are processed
+		wp.WaitFinalize()
+
+		failed := make(map[int]bool)
+		for err := range wp.Errors() {
+			failed[err.(*workerPools.TaskError[int]).Task] = true
+		}
+		utils.AssertEqual(t, len(failed), tasksCount)
+	})
+}
@@ -0,0 +1,124 @@
+package workerPools
+
+import (
+	"context"
+	"fmt"
+	"sitemap-generator/services"
+	"sync/atomic"
+)
+
+// WorkerHandler is a job, processor of the task
+//
+// Deprecated: use TypedWorkerPool[T] with TaskHandler[T] to get the task of the right type
+type WorkerHandler func(v interface{}) error
+
+// WorkerPool processes tasks of any type in parallel by limited number of the workers;
+// the handler gets no context
+//
+// Deprecated: use TypedWorkerPool[T]
+type WorkerPool interface {
+	Init(handler WorkerHandler) (startedWorkers int, err error)
+	AddTask(v interface{})
+	WaitFinalize()
+}
+
+type untypedWorkerPool struct {
+	TypedWorkerPool[interface{}]
+
+	logger services.Logger
+}
+
+// NewWorkerPool creates the pool of the former API, the errors of the handler are logged
+//
+// Deprecated: use NewTypedWorkerPool
+func NewWorkerPool(logger services.Logger, workersCount int) WorkerPool {
+	return &untypedWorkerPool{
+		TypedWorkerPool: NewTypedWorkerPool[interface{}](logger, WorkerPoolOptions{WorkersCount: workersCount}),
+		logger:          logger,
+	}
+}
+
+func (uwp *untypedWorkerPool) Init(handler WorkerHandler) (startedWorkers int, err error) {
+	startedWorkers, err = uwp.TypedWorkerPool.Init(func(_ context.Context, v interface{}) error {
+		return handler(v)
+	})
+	if err != nil {
+		return
+	}
+
+	go func() {
+		for err := range uwp.TypedWorkerPool.Errors() {
+			uwp.logger.Error("WorkerPool: could not succeed the job in the worker", err.Error())
+		}
+	}()
+	return
+}
+
+// adaptedWorkerPool processes tasks of the type T by the pool of the former API
+type adaptedWorkerPool[T any] struct {
+	// queued and busy are counted atomically, so they're first to be aligned
+	queued int64
+	busy   int64
+
+	pool         WorkerPool
+	workersCount int
+	ctx          context.Context
+	cancel       context.CancelFunc
+	errors       chan error
+}
+
+// AdaptWorkerPool lets the pool of the former API (e.g. the one of NewWorkerPool) be used
+// where TypedWorkerPool[T] is expected; errors of the handler are handled by the pool itself,
+// so Errors gives none
+func AdaptWorkerPool[T any](wp WorkerPool) TypedWorkerPool[T] {
+	return &adaptedWorkerPool[T]{pool: wp}
+}
+
+func (awp *adaptedWorkerPool[T]) Init(handler TaskHandler[T]) (startedWorkers int, err error) {
+	if handler == nil {
+		return 0, fmt.Errorf("WorkerPool: handler is not set")
+	}
+
+	awp.ctx, awp.cancel = context.WithCancel(context.Background())
+	awp.errors = make(chan error)
+	startedWorkers, err = awp.pool.Init(func(v interface{}) error {
+		atomic.AddInt64(&awp.queued, -1)
+		// the pool is stopped, so the rest of the tasks are dropped
+		if awp.ctx.Err() != nil {
+			return nil
+		}
+
+		atomic.AddInt64(&awp.busy, 1)
+		defer atomic.AddInt64(&awp.busy, -1)
+		return handler(awp.ctx, v.(T))
+	})
+	awp.workersCount = startedWorkers
+	return
+}
+
+func (awp *adaptedWorkerPool[T]) AddTask(task T) {
+	atomic.AddInt64(&awp.queued, 1)
+	awp.pool.AddTask(task)
+}
+
+func (awp *adaptedWorkerPool[T]) Errors() <-chan error {
+	return awp.errors
+}
+
+func (awp *adaptedWorkerPool[T]) WaitFinalize() {
+	awp.pool.WaitFinalize()
+	awp.cancel()
+	close(awp.errors)
+}
+
+func (awp *adaptedWorkerPool[T]) Stop() {
+	awp.cancel()
+}
+
+func (awp *adaptedWorkerPool[T]) Stats() WorkerPoolStats {
+	return WorkerPoolStats{
+		Workers: awp.workersCount,
+		Busy:    int(atomic.LoadInt64(&awp.busy)),
+		Queued:  int(atomic.LoadInt64(&awp.queued)),
+	}
+}
@@ -1,80 +1,225 @@
 package workerPools
 
 import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sitemap-generator/pkg/metrics"
 	"sitemap-generator/services"
 	"sync"
+	"sync/atomic"
 )
 
-// WorkerHandler is a job, processor of the task
-type WorkerHandler func(v interface{}) error
+type WorkerPoolOptions struct {
+	WorkersCount int
+	// PanicRetries is how many times the task is processed again after the panic in the handler, 0 for none
+	PanicRetries int
+	// Metrics count queued tasks and busy workers, they're not exposed anywhere if it's nil
+	Metrics *metrics.Metrics
+}
+
+// WorkerPoolStats is the state of the pool at the moment
+type WorkerPoolStats struct {
+	Workers int
+	// Busy is the number of workers processing tasks
+	Busy int
+	// Queued is the number of tasks waiting for a free worker
+	Queued int
+}
 
-// WorkerPool processes tasks in parallel by limited number of the workers
-type WorkerPool interface {
-	Init(handler WorkerHandler) (startedWorkers int, err error)
-	AddTask(v interface{})
+// TaskHandler is a job, processor of the task;
+// the context is canceled when the pool is stopped, so the running task can be given up then
+type TaskHandler[T any] func(ctx context.Context, task T) error
+
+// TypedWorkerPool processes tasks of the type T in parallel by limited number of the workers
+type TypedWorkerPool[T any] interface {
+	Init(handler TaskHandler[T]) (startedWorkers int, err error)
+	AddTask(task T)
+	// Errors are failures of the handler (as *TaskError), the channel is closed when the pool is finalized;
+	// it should be read until then because errors are kept until they're read
+	Errors() <-chan error
 	WaitFinalize()
+	// Stop cancels the context of the running tasks and drops the ones which are not started yet,
+	// including the tasks added after it; WaitFinalize still has to be called
+	Stop()
+	// Stats can be called at any time, even while tasks are processed
+	Stats() WorkerPoolStats
+}
+
+// TaskError is the error of the handler for the task
+type TaskError[T any] struct {
+	Task T
+	Err  error
+}
+
+func (te *TaskError[T]) Error() string {
+	return te.Err.Error()
+}
+
+func (te *TaskError[T]) Unwrap() error {
+	return te.Err
 }
 
-type workerPool struct {
+// PanicError is the panic recovered in the handler
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (pe *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", pe.Value)
+}
+
+type workerPool[T any] struct {
+	// queued and busy are counted atomically, so they're first to be aligned
+	queued int64
+	busy   int64
+
 	workersCount int
+	panicRetries int
+	metrics      *metrics.Metrics
 
-	logger    services.Logger
-	tasksChan chan interface{}
-	jobs      sync.WaitGroup
-	workers   sync.WaitGroup
+	logger  services.Logger
+	errors  chan error
+	jobs    sync.WaitGroup
+	workers sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	// tasks wait in the queue until some worker is free to take the next one,
+	// so adding a task never blocks (even if it's added by the worker itself);
+	// errors wait in their queue until they're read the same way
+	taskQueue  Queue[T]
+	tasks      *queue[T]
+	taskErrors *queue[error]
+}
+
+// NewTypedWorkerPool creates the pool which processes tasks in the order they're added
+func NewTypedWorkerPool[T any](logger services.Logger, opts WorkerPoolOptions) TypedWorkerPool[T] {
+	return NewQueuedWorkerPool[T](logger, opts, NewFifoQueue[T]())
 }
 
-func NewWorkerPool(logger services.Logger, workersCount int) WorkerPool {
-	return &workerPool{
+// NewQueuedWorkerPool creates the pool which processes tasks in the order given by the queue
+func NewQueuedWorkerPool[T any](logger services.Logger, opts WorkerPoolOptions, queue Queue[T]) TypedWorkerPool[T] {
+	if opts.Metrics == nil {
+		opts.Metrics = metrics.NewMetrics(metrics.NewRegistry())
+	}
+	return &workerPool[T]{
 		logger:       logger,
-		workersCount: workersCount,
+		workersCount: opts.WorkersCount,
+		panicRetries: opts.PanicRetries,
+		metrics:      opts.Metrics,
+		taskQueue:    queue,
 	}
 }
 
 // Init starts specified number of workers which expect new tasks from the queue
-func (wp *workerPool) Init(handler WorkerHandler) (startedWorkers int, err error) {
-	wp.tasksChan = make(chan interface{}, wp.workersCount)
+func (wp *workerPool[T]) Init(handler TaskHandler[T]) (startedWorkers int, err error) {
+	if handler == nil {
+		return 0, fmt.Errorf("WorkerPool: handler is not set")
+	}
 
-	runJob := func(task interface{}) {
+	wp.ctx, wp.cancel = context.WithCancel(context.Background())
+	wp.errors = make(chan error)
+	wp.tasks = newQueue(wp.taskQueue)
+	wp.taskErrors = newQueue(NewFifoQueue[error]())
+
+	go wp.taskErrors.drain(wp.errors)
+
+	runJob := func(task T) {
 		defer wp.jobs.Done()
-		if err := handler(task); err != nil {
-			wp.logger.Error("WorkerPool: could not succeed the job in the worker", err.Error())
+
+		// the pool is stopped, so the rest of the tasks are dropped
+		if wp.ctx.Err() != nil {
+			return
+		}
+		err := runTask(wp.ctx, handler, task)
+		for attempt := 1; attempt <= wp.panicRetries && isPanic(err); attempt++ {
+			wp.metrics.TaskPanics.Inc()
+			wp.logger.Warn(fmt.Sprintf("WorkerPool: task is processed again (attempt %d) after %s", attempt+1, err.Error()))
+			err = runTask(wp.ctx, handler, task)
+		}
+		if isPanic(err) {
+			wp.metrics.TaskPanics.Inc()
+		}
+		if err != nil {
+			wp.taskErrors.push(&TaskError[T]{Task: task, Err: err})
 		}
 	}
 
 	// start workers
+	wp.metrics.Workers.Add(float64(wp.workersCount))
 	for i := 0; i < wp.workersCount; i++ {
 		wp.workers.Add(1)
 		startedWorkers++
 
 		go func() {
-			defer func() {
-				wp.workers.Done()
+			defer wp.workers.Done()
 
-				// todo: improve to restart worker somehow when runtime error to keep processing other tasks
-				if r := recover(); r != nil {
-					wp.logger.Error("WorkerPool: error in worker", r)
+			// take tasks from the queue while it's open
+			for {
+				task, ok := wp.tasks.take()
+				if !ok {
+					return
 				}
-			}()
-
-			// read tasks from the queue while it's open
-			for task := range wp.tasksChan {
+				atomic.AddInt64(&wp.queued, -1)
+				atomic.AddInt64(&wp.busy, 1)
+				wp.metrics.QueuedTasks.Add(-1)
+				wp.metrics.BusyWorkers.Add(1)
 				runJob(task)
+				atomic.AddInt64(&wp.busy, -1)
+				wp.metrics.BusyWorkers.Add(-1)
 			}
 		}()
 	}
 	return startedWorkers, nil
 }
 
-func (wp *workerPool) AddTask(v interface{}) {
+// runTask recovers the panic of the handler, so the worker keeps processing other tasks
+func runTask[T any](ctx context.Context, handler TaskHandler[T], task T) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return handler(ctx, task)
+}
+
+func isPanic(err error) bool {
+	_, ok := err.(*PanicError)
+	return ok
+}
+
+func (wp *workerPool[T]) AddTask(task T) {
 	wp.jobs.Add(1)
-	wp.tasksChan <- v
+	atomic.AddInt64(&wp.queued, 1)
+	wp.metrics.QueuedTasks.Add(1)
+	wp.tasks.push(task)
+}
+
+func (wp *workerPool[T]) Stop() {
+	wp.cancel()
+}
+
+func (wp *workerPool[T]) Stats() WorkerPoolStats {
+	return WorkerPoolStats{
+		Workers: wp.workersCount,
+		Busy:    int(atomic.LoadInt64(&wp.busy)),
+		Queued:  int(atomic.LoadInt64(&wp.queued)),
+	}
+}
+
+func (wp *workerPool[T]) Errors() <-chan error {
+	return wp.errors
 }
 
 // WaitFinalize waits until all tasks are processed and workers stopped
 // and close the input/output channels
-func (wp *workerPool) WaitFinalize() {
+func (wp *workerPool[T]) WaitFinalize() {
 	wp.jobs.Wait()
-	close(wp.tasksChan)
+	wp.tasks.close()
 	wp.workers.Wait()
+	wp.metrics.Workers.Add(-float64(wp.workersCount))
+	wp.cancel()
+	wp.taskErrors.close()
 }
@@ -1,12 +1,18 @@
 package workerPools_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"sitemap-generator/pkg/workerPools"
 	"sitemap-generator/services"
 	"sitemap-generator/utils"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestNewWorkerPool(t *testing.T) {
@@ -48,3 +54,238 @@ func TestNewWorkerPool(t *testing.T) {
 	wp.WaitFinalize()
 	utils.AssertEqualSlices(t, results, expectedResults)
 }
+
+func TestNewTypedWorkerPool(t *testing.T) {
+	logger, err := services.NewLogger(os.Stderr, "testing", "error")
+	utils.AssertNoError(t, err)
+
+	wp := workerPools.NewTypedWorkerPool[int](logger, workerPools.WorkerPoolOptions{WorkersCount: 3})
+
+	var locker sync.Mutex
+	results := make([]int, 0)
+	_, err = wp.Init(func(ctx context.Context, task int) error {
+		utils.AssertNoError(t, ctx.Err())
+		if task%5 == 0 {
+			return fmt.Errorf("task %d failed", task)
+		}
+
+		locker.Lock()
+		results = append(results, task)
+		locker.Unlock()
+
+		// tasks are added by the workers too
+		if task < 10 {
+			wp.AddTask(task + 10)
+		}
+		return nil
+	})
+	utils.AssertNoError(t, err)
+
+	errors := make([]error, 0)
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+		for err := range wp.Errors() {
+			errors = append(errors, err)
+		}
+	}()
+
+	for i := 1; i <= 5; i++ {
+		wp.AddTask(i)
+	}
+	wp.WaitFinalize()
+	<-collected
+
+	utils.AssertEqualSlices(t, results, []int{1, 2, 3, 4, 11, 12, 13, 14})
+	utils.AssertEqual(t, len(errors), 1)
+	utils.AssertEqual(t, errors[0].(*workerPools.TaskError[int]).Task, 5)
+	utils.AssertHasError(t, errors[0], "task 5 failed")
+}
+
+func TestWorkerPool_Panic(t *testing.T) {
+	logger, err := services.NewLogger(os.Stderr, "testing", "error")
+	utils.AssertNoError(t, err)
+
+	tasksCount := 1000
+	wp := workerPools.NewTypedWorkerPool[int](logger, workerPools.WorkerPoolOptions{WorkersCount: 4})
+
+	var processed int32
+	_, err = wp.Init(func(_ context.Context, task int) error {
+		if task%7 == 0 {
+			var values []int
+			_ = values[task] // runtime error
+		}
+		if task%11 == 0 {
+			panic(fmt.Sprintf("task %d panicked", task))
+		}
+		atomic.AddInt32(&processed, 1)
+		return nil
+	})
+	utils.AssertNoError(t, err)
+
+	panics := make([]*workerPools.PanicError, 0)
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+		for err := range wp.Errors() {
+			var panicErr *workerPools.PanicError
+			utils.AssertTrue(t, errors.As(err, &panicErr))
+			panics = append(panics, panicErr)
+		}
+	}()
+
+	for i := 1; i <= tasksCount; i++ {
+		wp.AddTask(i)
+	}
+
+	finalized := make(chan struct{})
+	go func() {
+		wp.WaitFinalize()
+		close(finalized)
+	}()
+	select {
+	case <-finalized:
+	case <-time.After(time.Second):
+		t.Fatal("worker pool is not finalized after panics in the handler")
+	}
+	<-collected
+
+	expectedPanics := 0
+	for i := 1; i <= tasksCount; i++ {
+		if i%7 == 0 || i%11 == 0 {
+			expectedPanics++
+		}
+	}
+	utils.AssertEqual(t, int(atomic.LoadInt32(&processed)), tasksCount-expectedPanics)
+	utils.AssertEqual(t, len(panics), expectedPanics)
+	for _, p := range panics {
+		utils.AssertTrue(t, strings.Contains(string(p.Stack), "workerPool_test.go"))
+	}
+}
+
+func TestWorkerPool_PanicRetries(t *testing.T) {
+	logger, err := services.NewLogger(os.Stderr, "testing", "error")
+	utils.AssertNoError(t, err)
+
+	wp := workerPools.NewTypedWorkerPool[int](logger, workerPools.WorkerPoolOptions{WorkersCount: 4, PanicRetries: 2})
+
+	var locker sync.Mutex
+	attempts := make(map[int]int)
+	_, err = wp.Init(func(_ context.Context, task int) error {
+		locker.Lock()
+		attempts[task]++
+		attempt := attempts[task]
+		locker.Unlock()
+
+		// odd tasks recover at the second attempt, task 10 never does
+		if (task%2 == 1 && attempt == 1) || task == 10 {
+			panic("flaky")
+		}
+		return nil
+	})
+	utils.AssertNoError(t, err)
+
+	errs := make([]error, 0)
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+		for err := range wp.Errors() {
+			errs = append(errs, err)
+		}
+	}()
+
+	for i := 1; i <= 100; i++ {
+		wp.AddTask(i)
+	}
+	wp.WaitFinalize()
+	<-collected
+
+	utils.AssertEqual(t, len(errs), 1)
+	utils.AssertEqual(t, errs[0].(*workerPools.TaskError[int]).Task, 10)
+	utils.AssertHasError(t, errs[0], "panic: flaky")
+	utils.AssertEqual(t, attempts[10], 3)
+	utils.AssertEqual(t, attempts[3], 2)
+	utils.AssertEqual(t, attempts[4], 1)
+}
+
+func TestWorkerPool_Stats(t *testing.T) {
+	logger, err := services.NewLogger(os.Stderr, "testing", "error")
+	utils.AssertNoError(t, err)
+
+	wp := workerPools.NewTypedWorkerPool[int](logger, workerPools.WorkerPoolOptions{WorkersCount: 2})
+	utils.AssertEqual(t, wp.Stats(), workerPools.WorkerPoolStats{Workers: 2})
+
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	_, err = wp.Init(func(_ context.Context, task int) error {
+		started <- struct{}{}
+		<-release
+		return nil
+	})
+	utils.AssertNoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		wp.AddTask(i)
+	}
+	<-started
+	<-started
+	utils.AssertEqual(t, wp.Stats(), workerPools.WorkerPoolStats{Workers: 2, Busy: 2, Queued: 3})
+
+	close(release)
+	wp.WaitFinalize()
+	utils.AssertEqual(t, wp.Stats(), workerPools.WorkerPoolStats{Workers: 2})
+}
+
+func TestWorkerPool_Stop(t *testing.T) {
+	logger, err := services.NewLogger(os.Stderr, "testing", "error")
+	utils.AssertNoError(t, err)
+
+	pools := map[string]func() workerPools.TypedWorkerPool[int]{
+		"typed pool": func() workerPools.TypedWorkerPool[int] {
+			return workerPools.NewTypedWorkerPool[int](logger, workerPools.WorkerPoolOptions{WorkersCount: 2})
+		},
+		"adapted pool of the former API": func() workerPools.TypedWorkerPool[int] {
+			return workerPools.AdaptWorkerPool[int](workerPools.NewWorkerPool(logger, 2))
+		},
+	}
+	for name, newPool := range pools {
+		t.Run(name, func(t *testing.T) {
+			wp := newPool()
+			started := make(chan int, 10)
+			var canceled int32
+			_, err := wp.Init(func(ctx context.Context, task int) error {
+				started <- task
+				// the running tasks are given up once the pool is stopped
+				<-ctx.Done()
+				atomic.AddInt32(&canceled, 1)
+				return nil
+			})
+			utils.AssertNoError(t, err)
+
+			for i := 0; i < 10; i++ {
+				wp.AddTask(i)
+			}
+			<-started
+			<-started
+			utils.AssertEqual(t, wp.Stats(), workerPools.WorkerPoolStats{Workers: 2, Busy: 2, Queued: 8})
+
+			wp.Stop()
+			wp.AddTask(10)
+			finalized := make(chan struct{})
+			go func() {
+				wp.WaitFinalize()
+				close(finalized)
+			}()
+			select {
+			case <-finalized:
+			case <-time.After(time.Second):
+				t.Fatal("worker pool is not finalized after it's stopped")
+			}
+
+			// the tasks which are not started are dropped
+			utils.AssertEqual(t, atomic.LoadInt32(&canceled), int32(2))
+			utils.AssertEqual(t, len(started), 0)
+			utils.AssertEqual(t, wp.Stats(), workerPools.WorkerPoolStats{Workers: 2})
+		})
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"time"
 )
 
+const SitemapNamespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
 type Sitemap struct {
 	XMLName xml.Name  `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
 	Urls    []SiteUrl `xml:"url"`
@@ -27,3 +29,13 @@ func BuildSitemapUrl(loc string, lastMod time.Time) SiteUrl {
 		LastModified: lastModified,
 	}
 }
+
+type SitemapIndex struct {
+	XMLName  xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 sitemapindex"`
+	Sitemaps []SitemapRef `xml:"sitemap"`
+}
+
+type SitemapRef struct {
+	Location     string `xml:"loc"`
+	LastModified string `xml:"lastmod,omitempty"`
+}
@@ -0,0 +1,309 @@
+package writers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"sitemap-generator/pkg/reports/models"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	ReportFormatJson = "json"
+	ReportFormatCsv  = "csv"
+	ReportFormatHtml = "html"
+	// ReportFormatText is the summary to be read by people, it's supported by the stats only
+	ReportFormatText = "text"
+)
+
+type ReportWriter interface {
+	WriteBrokenLinks(report models.BrokenLinksReport, format string) error
+	WriteTraps(report models.TrapsReport, format string) error
+	WriteDuplicates(report models.DuplicatesReport, format string) error
+	// WriteStats supports json and text formats
+	WriteStats(report models.StatsReport, format string) error
+}
+
+type reportWriter struct {
+	dest io.Writer
+}
+
+func NewReportWriter(dest io.Writer) ReportWriter {
+	return &reportWriter{
+		dest: dest,
+	}
+}
+
+// ValidateReportFormat checks if report can be written in the format
+func ValidateReportFormat(format string) error {
+	switch format {
+	case ReportFormatJson, ReportFormatCsv, ReportFormatHtml:
+		return nil
+	default:
+		return fmt.Errorf("unknown report format: %s", format)
+	}
+}
+
+// writeReport writes the report in the format, the csv and html ones are specific to the report
+func (rw *reportWriter) writeReport(report interface{}, format string, writeCsv func() error, html *template.Template) error {
+	switch format {
+	case ReportFormatJson:
+		return rw.writeJson(report)
+	case ReportFormatCsv:
+		return writeCsv()
+	case ReportFormatHtml:
+		return html.Execute(rw.dest, report)
+	default:
+		return ValidateReportFormat(format)
+	}
+}
+
+func (rw *reportWriter) writeJson(report interface{}) error {
+	encoder := json.NewEncoder(rw.dest)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+func (rw *reportWriter) WriteBrokenLinks(report models.BrokenLinksReport, format string) error {
+	return rw.writeReport(report, format, func() error { return rw.writeBrokenLinksCsv(report) }, brokenLinksTemplate)
+}
+
+// writeBrokenLinksCsv writes a row per each source page of the broken link
+func (rw *reportWriter) writeBrokenLinksCsv(report models.BrokenLinksReport) error {
+	w := csv.NewWriter(rw.dest)
+	if err := w.Write([]string{"url", "internal", "status_code", "error_kind", "error", "redirects", "source_page", "source_text"}); err != nil {
+		return err
+	}
+
+	for _, l := range report.Links {
+		redirects := make([]string, len(l.Redirects))
+		for i, r := range l.Redirects {
+			redirects[i] = fmt.Sprintf("%d %s", r.StatusCode, r.Location)
+		}
+
+		row := []string{
+			l.Url,
+			strconv.FormatBool(l.Internal),
+			strconv.Itoa(l.StatusCode),
+			l.ErrorKind,
+			l.Error,
+			strings.Join(redirects, " -> "),
+		}
+		if len(l.Sources) == 0 {
+			if err := w.Write(append(row, "", "")); err != nil {
+				return err
+			}
+		}
+		for _, s := range l.Sources {
+			if err := w.Write(append(row, s.Page, s.Text)); err != nil {
+				return err
+			}
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+func (rw *reportWriter) WriteTraps(report models.TrapsReport, format string) error {
+	return rw.writeReport(report, format, func() error { return rw.writeTrapsCsv(report) }, trapsTemplate)
+}
+
+func (rw *reportWriter) writeTrapsCsv(report models.TrapsReport) error {
+	w := csv.NewWriter(rw.dest)
+	if err := w.Write([]string{"url", "depth", "trap", "details"}); err != nil {
+		return err
+	}
+	for _, u := range report.Urls {
+		if err := w.Write([]string{u.Url, strconv.Itoa(u.Depth), string(u.Trap), u.Details}); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+func (rw *reportWriter) WriteDuplicates(report models.DuplicatesReport, format string) error {
+	return rw.writeReport(report, format, func() error { return rw.writeDuplicatesCsv(report) }, duplicatesTemplate)
+}
+
+// writeDuplicatesCsv writes a row per each duplicate of the collected URL
+func (rw *reportWriter) writeDuplicatesCsv(report models.DuplicatesReport) error {
+	w := csv.NewWriter(rw.dest)
+	if err := w.Write([]string{"url", "duplicate_url", "details"}); err != nil {
+		return err
+	}
+	for _, c := range report.Clusters {
+		for _, d := range c.Duplicates {
+			if err := w.Write([]string{c.Url, d.Url, d.Details}); err != nil {
+				return err
+			}
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+func (rw *reportWriter) WriteStats(report models.StatsReport, format string) error {
+	switch format {
+	case ReportFormatJson:
+		return rw.writeJson(report)
+	case ReportFormatText:
+		return rw.writeStatsText(report)
+	default:
+		return fmt.Errorf("unknown stats format: %s", format)
+	}
+}
+
+// writeStatsText writes the summary of the crawl with the table of the hosts
+func (rw *reportWriter) writeStatsText(report models.StatsReport) error {
+	w := tabwriter.NewWriter(rw.dest, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Crawl of %s took %s\n", report.StartUrl, report.Duration.Round(time.Millisecond))
+	if report.Transfer.DecodedBytes > 0 {
+		fmt.Fprintf(w, "Pages fetched:\t%d (%d bytes, %d bytes decoded)\n", report.PagesFetched, report.Bytes, report.Transfer.DecodedBytes)
+	} else {
+		fmt.Fprintf(w, "Pages fetched:\t%d (%d bytes)\n", report.PagesFetched, report.Bytes)
+	}
+	notScanned := make([]string, 0, len(report.NotScanned))
+	for reason, count := range report.NotScanned {
+		notScanned = append(notScanned, fmt.Sprintf("%s %d", reason, count))
+	}
+	sort.Strings(notScanned)
+	if len(notScanned) > 0 {
+		fmt.Fprintf(w, "URLs collected:\t%d (not scanned: %s)\n", report.UrlsCollected, strings.Join(notScanned, ", "))
+	} else {
+		fmt.Fprintf(w, "URLs collected:\t%d\n", report.UrlsCollected)
+	}
+	fmt.Fprintf(w, "Max depth:\t%d\n", report.MaxDepth)
+	fmt.Fprintf(w, "Redirects:\t%d\n", report.Redirects)
+
+	skipped := make([]string, 0, len(report.Skipped))
+	total := 0
+	for reason, count := range report.Skipped {
+		skipped = append(skipped, fmt.Sprintf("%s %d", reason, count))
+		total += count
+	}
+	sort.Strings(skipped)
+	if len(skipped) > 0 {
+		fmt.Fprintf(w, "Skipped:\t%d (%s)\n", total, strings.Join(skipped, ", "))
+	} else {
+		fmt.Fprintf(w, "Skipped:\t0\n")
+	}
+
+	errorCounts := make([]string, 0)
+	for op, count := range report.Errors.ByOp {
+		errorCounts = append(errorCounts, fmt.Sprintf("%s %d", op, count))
+	}
+	for kind, count := range report.Errors.ByKind {
+		errorCounts = append(errorCounts, fmt.Sprintf("%s %d", kind, count))
+	}
+	for status, count := range report.Errors.ByStatus {
+		errorCounts = append(errorCounts, fmt.Sprintf("status %d: %d", status, count))
+	}
+	sort.Strings(errorCounts)
+	if len(errorCounts) > 0 {
+		fmt.Fprintf(w, "Errors:\t%d (%s)\n", report.Errors.Total, strings.Join(errorCounts, ", "))
+	} else {
+		fmt.Fprintf(w, "Errors:\t0\n")
+	}
+
+	if len(report.Hosts) > 0 {
+		fmt.Fprintln(w, "\nHOST\tPAGES\tBYTES\tAVERAGE\tP50\tP90\tP99\tMAX")
+		for _, h := range report.Hosts {
+			l := h.Latency
+			fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\t%s\t%s\t%s\n", h.Host, h.Pages, h.Bytes,
+				l.Average.Round(time.Millisecond), l.P50.Round(time.Millisecond), l.P90.Round(time.Millisecond),
+				l.P99.Round(time.Millisecond), l.Max.Round(time.Millisecond))
+		}
+	}
+	return w.Flush()
+}
+
+// reportLayout is the page shared by the html reports,
+// each of them defines its "title" and the "content" under the heading
+var reportLayout = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="utf-8">
+    <title>{{template "title"}} of {{.StartUrl}}</title>
+    <style>
+        body { font-family: sans-serif; }
+        table { border-collapse: collapse; width: 100%; }
+        th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+        .internal { background: #fee; }
+    </style>
+</head>
+<body>
+<h1>{{template "title"}} of <a href="{{.StartUrl}}">{{.StartUrl}}</a></h1>
+{{template "content" .}}
+</body>
+</html>
+`))
+
+func reportTemplate(definitions string) *template.Template {
+	return template.Must(template.Must(reportLayout.Clone()).Parse(definitions))
+}
+
+var brokenLinksTemplate = reportTemplate(`{{define "title"}}Broken links{{end}}
+{{define "content"}}
+{{if .Links}}
+<table>
+    <tr><th>URL</th><th>Error</th><th>Redirects</th><th>Found on</th></tr>
+    {{range .Links}}
+    <tr{{if .Internal}} class="internal"{{end}}>
+        <td><a href="{{.Url}}">{{.Url}}</a></td>
+        <td>{{if .StatusCode}}{{.StatusCode}}{{else}}{{.ErrorKind}}{{end}}: {{.Error}}</td>
+        <td>{{range .Redirects}}{{.StatusCode}} &rarr; {{.Location}}<br>{{end}}</td>
+        <td>{{range .Sources}}<a href="{{.Page}}">{{.Page}}</a>{{if .Text}} ({{.Text}}){{end}}<br>{{end}}</td>
+    </tr>
+    {{end}}
+</table>
+{{else}}
+<p>No broken links found.</p>
+{{end}}
+{{end}}`)
+
+var trapsTemplate = reportTemplate(`{{define "title"}}Crawler traps{{end}}
+{{define "content"}}
+{{if .Urls}}
+<p>{{range $trap, $count := .Counts}}{{$trap}}: {{$count}}<br>{{end}}</p>
+<table>
+    <tr><th>URL</th><th>Depth</th><th>Trap</th><th>Details</th></tr>
+    {{range .Urls}}
+    <tr>
+        <td>{{.Url}}</td>
+        <td>{{.Depth}}</td>
+        <td>{{.Trap}}</td>
+        <td>{{.Details}}</td>
+    </tr>
+    {{end}}
+</table>
+{{else}}
+<p>No crawler traps found.</p>
+{{end}}
+{{end}}`)
+
+var duplicatesTemplate = reportTemplate(`{{define "title"}}Duplicate pages{{end}}
+{{define "content"}}
+{{if .Clusters}}
+<table>
+    <tr><th>URL in the sitemap</th><th>Duplicates</th></tr>
+    {{range .Clusters}}
+    <tr>
+        <td><a href="{{.Url}}">{{.Url}}</a></td>
+        <td>{{range .Duplicates}}<a href="{{.Url}}">{{.Url}}</a> ({{.Details}})<br>{{end}}</td>
+    </tr>
+    {{end}}
+</table>
+{{else}}
+<p>No duplicate pages found.</p>
+{{end}}
+{{end}}`)
@@ -0,0 +1,170 @@
+package writers_test
+
+import (
+	"bytes"
+	crawlersModels "sitemap-generator/pkg/crawlers/models"
+	readersModels "sitemap-generator/pkg/readers/models"
+	"sitemap-generator/pkg/reports/models"
+	"sitemap-generator/pkg/writers"
+	"sitemap-generator/utils"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReportWriter_WriteBrokenLinks(t *testing.T) {
+	report := models.BrokenLinksReport{
+		StartUrl: "https://example.com/",
+		Links: []models.BrokenLink{
+			{
+				Url:        "https://example.com/old",
+				Internal:   true,
+				StatusCode: 404,
+				ErrorKind:  "http",
+				Error:      "HTTP error [404] 404 Not Found",
+				Redirects: []readersModels.Redirect{
+					{StatusCode: 301, Location: "https://example.com/missing"},
+				},
+				Sources: []models.LinkSource{
+					{Page: "https://example.com/", Text: "Old page"},
+					{Page: "https://example.com/about", Text: "Old"},
+				},
+			},
+		},
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		expected := `url,internal,status_code,error_kind,error,redirects,source_page,source_text
+https://example.com/old,true,404,http,HTTP error [404] 404 Not Found,301 https://example.com/missing,https://example.com/,Old page
+https://example.com/old,true,404,http,HTTP error [404] 404 Not Found,301 https://example.com/missing,https://example.com/about,Old
+`
+		buffer := new(bytes.Buffer)
+		err := writers.NewReportWriter(buffer).WriteBrokenLinks(report, writers.ReportFormatCsv)
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, buffer.String(), expected)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		buffer := new(bytes.Buffer)
+		err := writers.NewReportWriter(buffer).WriteBrokenLinks(report, writers.ReportFormatJson)
+		utils.AssertNoError(t, err)
+		utils.AssertTrue(t, strings.Contains(buffer.String(), `"url": "https://example.com/old"`))
+		utils.AssertTrue(t, strings.Contains(buffer.String(), `"text": "Old page"`))
+	})
+
+	t.Run("html", func(t *testing.T) {
+		buffer := new(bytes.Buffer)
+		err := writers.NewReportWriter(buffer).WriteBrokenLinks(report, writers.ReportFormatHtml)
+		utils.AssertNoError(t, err)
+		utils.AssertTrue(t, strings.Contains(buffer.String(), `<tr class="internal">`))
+		utils.AssertTrue(t, strings.Contains(buffer.String(), `https://example.com/about</a> (Old)`))
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		err := writers.NewReportWriter(new(bytes.Buffer)).WriteBrokenLinks(report, "xml")
+		utils.AssertHasError(t, err, "unknown report format")
+	})
+}
+
+func TestReportWriter_WriteTraps(t *testing.T) {
+	report := models.TrapsReport{
+		StartUrl: "https://example.com/",
+		Urls: []models.TrapUrl{
+			{Url: "https://example.com/a/a/a/a/", Depth: 4, Trap: crawlersModels.TrapRepeatedSegments, Details: "segment a repeats 4 times"},
+		},
+		Counts: map[crawlersModels.TrapKind]int{crawlersModels.TrapRepeatedSegments: 1},
+	}
+
+	buffer := new(bytes.Buffer)
+	err := writers.NewReportWriter(buffer).WriteTraps(report, writers.ReportFormatCsv)
+	utils.AssertNoError(t, err)
+	utils.AssertEqual(t, buffer.String(), `url,depth,trap,details
+https://example.com/a/a/a/a/,4,repeated-segments,segment a repeats 4 times
+`)
+
+	buffer.Reset()
+	err = writers.NewReportWriter(buffer).WriteTraps(report, writers.ReportFormatHtml)
+	utils.AssertNoError(t, err)
+	utils.AssertTrue(t, strings.Contains(buffer.String(), "repeated-segments: 1<br>"))
+}
+
+func TestReportWriter_WriteDuplicates(t *testing.T) {
+	report := models.DuplicatesReport{
+		StartUrl: "https://example.com/",
+		Clusters: []models.DuplicateCluster{
+			{
+				Url: "https://example.com/guide",
+				Duplicates: []models.Duplicate{
+					{Url: "https://example.com/guide?ref=home", Details: "exact duplicate"},
+					{Url: "https://example.com/print/guide", Details: "near-duplicate (2 bits differ)"},
+				},
+			},
+		},
+	}
+
+	buffer := new(bytes.Buffer)
+	err := writers.NewReportWriter(buffer).WriteDuplicates(report, writers.ReportFormatCsv)
+	utils.AssertNoError(t, err)
+	utils.AssertEqual(t, buffer.String(), `url,duplicate_url,details
+https://example.com/guide,https://example.com/guide?ref=home,exact duplicate
+https://example.com/guide,https://example.com/print/guide,near-duplicate (2 bits differ)
+`)
+
+	buffer.Reset()
+	err = writers.NewReportWriter(buffer).WriteDuplicates(report, writers.ReportFormatHtml)
+	utils.AssertNoError(t, err)
+	utils.AssertTrue(t, strings.Contains(buffer.String(), "<title>Duplicate pages of https://example.com/</title>"))
+	utils.AssertTrue(t, strings.Contains(buffer.String(), `print/guide</a> (near-duplicate (2 bits differ))<br>`))
+}
+
+func TestReportWriter_WriteStats(t *testing.T) {
+	report := models.StatsReport{
+		StartUrl:      "https://example.com/",
+		Duration:      1500 * time.Millisecond,
+		PagesFetched:  3,
+		UrlsCollected: 2,
+		NotScanned:    map[crawlersModels.NotScannedReason]int{crawlersModels.NotScannedNotHtml: 1},
+		Skipped:       map[crawlersModels.SkipReason]int{crawlersModels.SkipReasonTrap: 1},
+		Errors: models.ErrorStats{
+			Total:    1,
+			ByOp:     map[crawlersModels.ErrorOp]int{crawlersModels.ErrorOpCheck: 1},
+			ByKind:   map[string]int{"http": 1},
+			ByStatus: map[int]int{404: 1},
+		},
+		Redirects: 1,
+		Bytes:     300,
+		Transfer:  models.TransferReport{DecodedBytes: 900},
+		MaxDepth:  2,
+		Hosts: []models.HostStats{
+			{
+				Host:    "example.com",
+				Pages:   3,
+				Bytes:   300,
+				Latency: models.LatencyStats{Average: 20 * time.Millisecond, P50: 20 * time.Millisecond, P90: 30 * time.Millisecond, P99: 30 * time.Millisecond, Max: 30 * time.Millisecond},
+			},
+		},
+	}
+
+	buffer := new(bytes.Buffer)
+	err := writers.NewReportWriter(buffer).WriteStats(report, writers.ReportFormatText)
+	utils.AssertNoError(t, err)
+	utils.AssertEqual(t, buffer.String(), `Crawl of https://example.com/ took 1.5s
+Pages fetched:   3 (300 bytes, 900 bytes decoded)
+URLs collected:  2 (not scanned: not-html 1)
+Max depth:       2
+Redirects:       1
+Skipped:         1 (trap 1)
+Errors:          1 (check 1, http 1, status 404: 1)
+
+HOST         PAGES  BYTES  AVERAGE  P50   P90   P99   MAX
+example.com  3      300    20ms     20ms  30ms  30ms  30ms
+`)
+
+	buffer.Reset()
+	err = writers.NewReportWriter(buffer).WriteStats(report, writers.ReportFormatJson)
+	utils.AssertNoError(t, err)
+	utils.AssertTrue(t, strings.Contains(buffer.String(), `"urlsCollected": 2`))
+
+	err = writers.NewReportWriter(buffer).WriteStats(report, writers.ReportFormatCsv)
+	utils.AssertHasError(t, err, "unknown stats format: csv")
+}
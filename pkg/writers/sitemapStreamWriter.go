@@ -0,0 +1,172 @@
+package writers
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sitemap-generator/pkg/writers/models"
+)
+
+// Limits of a single sitemap file defined by the protocol (https://www.sitemaps.org/protocol.html)
+const (
+	MaxSitemapUrls  = 50000
+	MaxSitemapBytes = 50 * 1024 * 1024
+)
+
+type SitemapStreamWriterOptions struct {
+	// Open returns destination for the sitemap part with the given number (starting from 1)
+	Open     func(part int) (io.WriteCloser, error)
+	MaxUrls  int
+	MaxBytes int
+}
+
+// SitemapStreamWriter encodes URLs one by one as they come
+// and rolls to a new sitemap part when the current one reaches the limits
+type SitemapStreamWriter interface {
+	WriteUrl(u models.SiteUrl) error
+	Close() error
+	Parts() int
+}
+
+type sitemapStreamWriter struct {
+	open     func(part int) (io.WriteCloser, error)
+	maxUrls  int
+	maxBytes int
+
+	parts   int
+	dest    io.WriteCloser
+	buffer  *bufio.Writer
+	counter *countingWriter
+	encoder *xml.Encoder
+	urls    int
+}
+
+var (
+	urlsetStart = xml.StartElement{
+		Name: xml.Name{Local: "urlset"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: models.SitemapNamespace}},
+	}
+	urlStart = xml.StartElement{Name: xml.Name{Local: "url"}}
+
+	// how the end of the urlset is encoded with indentation
+	urlsetEndSize = len("\n</urlset>")
+)
+
+func NewSitemapStreamWriter(opts SitemapStreamWriterOptions) SitemapStreamWriter {
+	if opts.MaxUrls <= 0 || opts.MaxUrls > MaxSitemapUrls {
+		opts.MaxUrls = MaxSitemapUrls
+	}
+	if opts.MaxBytes <= 0 || opts.MaxBytes > MaxSitemapBytes {
+		opts.MaxBytes = MaxSitemapBytes
+	}
+	return &sitemapStreamWriter{
+		open:     opts.Open,
+		maxUrls:  opts.MaxUrls,
+		maxBytes: opts.MaxBytes,
+	}
+}
+
+// WriteUrl encodes the URL to the current sitemap part, starting a new one if the URL doesn't fit into it
+func (sw *sitemapStreamWriter) WriteUrl(u models.SiteUrl) error {
+	// the same indentation as encoder does inside the urlset, plus a line break before the element
+	encoded, err := xml.MarshalIndent(struct {
+		models.SiteUrl
+		XMLName xml.Name `xml:"url"`
+	}{SiteUrl: u}, "  ", "  ")
+	if err != nil {
+		return err
+	}
+	size := len(encoded) + 1
+
+	if sw.dest != nil && (sw.urls >= sw.maxUrls || sw.counter.written+size+urlsetEndSize > sw.maxBytes) {
+		if err = sw.finishPart(); err != nil {
+			return err
+		}
+	}
+	if sw.dest == nil {
+		if err = sw.startPart(); err != nil {
+			return err
+		}
+	}
+
+	if err = sw.encoder.EncodeElement(u, urlStart); err != nil {
+		return err
+	}
+	if err = sw.encoder.Flush(); err != nil {
+		return err
+	}
+	sw.urls++
+	return nil
+}
+
+// Close finishes the current sitemap part; an empty sitemap is written if there were no URLs at all
+func (sw *sitemapStreamWriter) Close() error {
+	if sw.dest == nil && sw.parts == 0 {
+		if err := sw.startPart(); err != nil {
+			return err
+		}
+	}
+	if sw.dest == nil {
+		return nil
+	}
+	return sw.finishPart()
+}
+
+// Parts returns number of sitemap parts started so far
+func (sw *sitemapStreamWriter) Parts() int {
+	return sw.parts
+}
+
+func (sw *sitemapStreamWriter) startPart() (err error) {
+	sw.parts++
+	if sw.dest, err = sw.open(sw.parts); err != nil {
+		sw.dest = nil
+		return fmt.Errorf("could not open sitemap part %d: %s", sw.parts, err.Error())
+	}
+
+	sw.buffer = bufio.NewWriter(sw.dest)
+	sw.counter = &countingWriter{dest: sw.buffer}
+	sw.encoder = xml.NewEncoder(sw.counter)
+	sw.encoder.Indent("", "  ")
+	sw.urls = 0
+
+	if _, err = io.WriteString(sw.counter, xml.Header); err != nil {
+		return err
+	}
+	if err = sw.encoder.EncodeToken(urlsetStart); err != nil {
+		return err
+	}
+	return sw.encoder.Flush()
+}
+
+func (sw *sitemapStreamWriter) finishPart() error {
+	dest := sw.dest
+	sw.dest = nil
+
+	if err := sw.encoder.EncodeToken(urlsetStart.End()); err != nil {
+		_ = dest.Close()
+		return err
+	}
+	if err := sw.encoder.Flush(); err != nil {
+		_ = dest.Close()
+		return err
+	}
+	if err := sw.buffer.Flush(); err != nil {
+		_ = dest.Close()
+		return err
+	}
+	return dest.Close()
+}
+
+// countingWriter counts bytes passed through it
+type countingWriter struct {
+	dest    io.Writer
+	written int
+}
+
+func (cw *countingWriter) Write(p []byte) (n int, err error) {
+	n, err = cw.dest.Write(p)
+	cw.written += n
+	return
+}
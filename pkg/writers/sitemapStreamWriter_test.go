@@ -0,0 +1,118 @@
+package writers_test
+
+import (
+	"bytes"
+	"io"
+	"sitemap-generator/pkg/writers"
+	"sitemap-generator/pkg/writers/models"
+	"sitemap-generator/utils"
+	"testing"
+)
+
+type bufferCloser struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (bc *bufferCloser) Close() error {
+	bc.closed = true
+	return nil
+}
+
+func TestSitemapStreamWriter_WriteUrl(t *testing.T) {
+	urls := []models.SiteUrl{
+		{
+			Location: "https://creativecommons.org/about/contact",
+		},
+		{
+			Location:     "https://wiki.creativecommons.org/Intergovernmental_Organizations",
+			LastModified: "2022-05-11T12:48:18Z",
+		},
+		{
+			Location: "https://creativecommons.org/about/team",
+		},
+	}
+
+	t.Run("single part has the same format as sitemap writer", func(t *testing.T) {
+		parts := make([]*bufferCloser, 0)
+		sw := writers.NewSitemapStreamWriter(writers.SitemapStreamWriterOptions{
+			Open: func(part int) (io.WriteCloser, error) {
+				parts = append(parts, &bufferCloser{})
+				return parts[part-1], nil
+			},
+		})
+		for _, u := range urls {
+			utils.AssertNoError(t, sw.WriteUrl(u))
+		}
+		utils.AssertNoError(t, sw.Close())
+
+		expected := new(bytes.Buffer)
+		utils.AssertNoError(t, writers.NewSitemapWriter(expected).Write(models.Sitemap{Urls: urls}))
+
+		utils.AssertEqual(t, sw.Parts(), 1)
+		utils.AssertEqual(t, parts[0].String(), expected.String())
+		utils.AssertTrue(t, parts[0].closed)
+	})
+
+	t.Run("rolls to new part when URL limit is hit", func(t *testing.T) {
+		parts := make([]*bufferCloser, 0)
+		sw := writers.NewSitemapStreamWriter(writers.SitemapStreamWriterOptions{
+			Open: func(part int) (io.WriteCloser, error) {
+				parts = append(parts, &bufferCloser{})
+				return parts[part-1], nil
+			},
+			MaxUrls: 2,
+		})
+		for _, u := range urls {
+			utils.AssertNoError(t, sw.WriteUrl(u))
+		}
+		utils.AssertNoError(t, sw.Close())
+
+		first, second := new(bytes.Buffer), new(bytes.Buffer)
+		utils.AssertNoError(t, writers.NewSitemapWriter(first).Write(models.Sitemap{Urls: urls[:2]}))
+		utils.AssertNoError(t, writers.NewSitemapWriter(second).Write(models.Sitemap{Urls: urls[2:]}))
+
+		utils.AssertEqual(t, sw.Parts(), 2)
+		utils.AssertEqual(t, parts[0].String(), first.String())
+		utils.AssertEqual(t, parts[1].String(), second.String())
+	})
+
+	t.Run("rolls to new part when size limit is hit", func(t *testing.T) {
+		maxBytes := 300
+		parts := make([]*bufferCloser, 0)
+		sw := writers.NewSitemapStreamWriter(writers.SitemapStreamWriterOptions{
+			Open: func(part int) (io.WriteCloser, error) {
+				parts = append(parts, &bufferCloser{})
+				return parts[part-1], nil
+			},
+			MaxBytes: maxBytes,
+		})
+		for _, u := range urls {
+			utils.AssertNoError(t, sw.WriteUrl(u))
+		}
+		utils.AssertNoError(t, sw.Close())
+
+		utils.AssertEqual(t, sw.Parts(), len(parts))
+		utils.AssertTrue(t, len(parts) > 1)
+		for _, p := range parts {
+			utils.AssertTrue(t, p.Len() <= maxBytes)
+			utils.AssertTrue(t, bytes.HasSuffix(p.Bytes(), []byte("</urlset>")))
+			utils.AssertTrue(t, p.closed)
+		}
+	})
+
+	t.Run("empty sitemap when no URLs", func(t *testing.T) {
+		buffer := &bufferCloser{}
+		sw := writers.NewSitemapStreamWriter(writers.SitemapStreamWriterOptions{
+			Open: func(part int) (io.WriteCloser, error) {
+				return buffer, nil
+			},
+		})
+		utils.AssertNoError(t, sw.Close())
+
+		expected := `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"></urlset>`
+		utils.AssertEqual(t, sw.Parts(), 1)
+		utils.AssertEqual(t, buffer.String(), expected)
+	})
+}
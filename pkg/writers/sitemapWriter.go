@@ -8,6 +8,7 @@ import (
 
 type SitemapWriter interface {
 	Write(data models.Sitemap) error
+	WriteIndex(data models.SitemapIndex) error
 }
 
 type sitemapWriter struct {
@@ -21,6 +22,15 @@ func NewSitemapWriter(dest io.Writer) SitemapWriter {
 }
 
 func (sw *sitemapWriter) Write(data models.Sitemap) error {
+	return sw.write(data)
+}
+
+// WriteIndex writes the index of sitemap files when the site doesn't fit into a single sitemap
+func (sw *sitemapWriter) WriteIndex(data models.SitemapIndex) error {
+	return sw.write(data)
+}
+
+func (sw *sitemapWriter) write(data interface{}) error {
 	bytes, err := xml.MarshalIndent(data, "", "  ")
 	if err != nil {
 		return err
@@ -6,6 +6,7 @@ import (
 	"sitemap-generator/pkg/writers/models"
 	"sitemap-generator/utils"
 	"testing"
+	"time"
 )
 
 func TestSitemapWriter_Write(t *testing.T) {
@@ -39,3 +40,41 @@ func TestSitemapWriter_Write(t *testing.T) {
 	utils.AssertNoError(t, err)
 	utils.AssertEqual(t, buffer.String(), expected)
 }
+
+func TestSitemapWriter_WriteIndex(t *testing.T) {
+	data := models.SitemapIndex{
+		Sitemaps: []models.SitemapRef{
+			{Location: "https://creativecommons.org/sitemap-1.xml"},
+			{Location: "https://creativecommons.org/sitemap-2.xml"},
+		},
+	}
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap>
+    <loc>https://creativecommons.org/sitemap-1.xml</loc>
+  </sitemap>
+  <sitemap>
+    <loc>https://creativecommons.org/sitemap-2.xml</loc>
+  </sitemap>
+</sitemapindex>`
+
+	buffer := new(bytes.Buffer)
+	sw := writers.NewSitemapWriter(buffer)
+
+	err := sw.WriteIndex(data)
+	utils.AssertNoError(t, err)
+	utils.AssertEqual(t, buffer.String(), expected)
+}
+
+func TestBuildSitemapUrl(t *testing.T) {
+	tests := map[string]string{
+		"https://example.com/about/contact":      "https://example.com/about/contact",
+		"https://example.com/новости?q=тест&p=1": "https://example.com/%D0%BD%D0%BE%D0%B2%D0%BE%D1%81%D1%82%D0%B8?q=%D1%82%D0%B5%D1%81%D1%82&p=1",
+		"https://example.com/a%20b?q=a%20b":      "https://example.com/a%20b?q=a%20b",
+		"https://пример.рф:8080/":                "https://xn--e1afmkfd.xn--p1ai:8080/",
+	}
+	for loc, expected := range tests {
+		utils.AssertEqual(t, models.BuildSitemapUrl(loc, time.Time{}).Location, expected)
+	}
+}
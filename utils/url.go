@@ -1,10 +1,15 @@
 package utils
 
 import (
+	"fmt"
+	"golang.org/x/net/idna"
+	"net"
 	"net/url"
+	"strings"
 )
 
 // UrlPercentEncode encodes any non-ASCII character to percent-encoded (%C3%BC)
+// in the path and the query, and international host names to punycode (xn--...)
 // Use it instead of standard url.QueryEscape() if you don't need
 // to encode URL reserved characters (/, :, ?, & etc)
 func UrlPercentEncode(v string) string {
@@ -12,5 +17,60 @@ func UrlPercentEncode(v string) string {
 	if err != nil {
 		return v
 	}
+
+	if host := u.Hostname(); !isAscii(host) {
+		if ascii, err := idna.Lookup.ToASCII(host); err == nil {
+			if port := u.Port(); port != "" {
+				ascii = net.JoinHostPort(ascii, port)
+			}
+			u.Host = ascii
+		}
+	}
+
+	// the query is kept as is by url.URL.String()
+	u.RawQuery = percentEncodeNonAscii(u.RawQuery)
+	return u.String()
+}
+
+// percentEncodeNonAscii encodes non-ASCII, control characters and spaces only, so already encoded ones stay the same
+func percentEncodeNonAscii(v string) string {
+	var b strings.Builder
+	for i := 0; i < len(v); i++ {
+		if c := v[i]; c <= ' ' || c >= 0x7f {
+			b.WriteString(fmt.Sprintf("%%%02X", c))
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func isAscii(v string) bool {
+	for i := 0; i < len(v); i++ {
+		if v[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// NormalizeUrl brings URL to the form to compare it with others:
+// lower case scheme and host, no default port, no fragment and "/" for the empty path
+func NormalizeUrl(v string) string {
+	u, err := url.Parse(v)
+	if err != nil {
+		return v
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if (u.Scheme == "http" && strings.HasSuffix(u.Host, ":80")) || (u.Scheme == "https" && strings.HasSuffix(u.Host, ":443")) {
+		u.Host = u.Host[:strings.LastIndex(u.Host, ":")]
+	}
+	if u.Path == "" && u.Opaque == "" {
+		u.Path = "/"
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
 	return u.String()
 }